@@ -5,13 +5,26 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/FearLessSaad/SNFOK/agent/controllers/kubernetes/features"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8scache"
 	"github.com/FearLessSaad/SNFOK/agent/tooling/k8sclient"
 	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
 	"github.com/gofiber/fiber/v2"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
 )
 
+// policyCRDs lists the policy kinds whose CRDs are reported by the health check
+var policyCRDs = []agent_dto.Health_CRD{
+	{Kind: "TracingPolicy", Group: "cilium.io", Version: "v1alpha1"},
+	{Kind: "TracingPolicyNamespaced", Group: "cilium.io", Version: "v1alpha1"},
+	{Kind: "KubeArmorPolicy", Group: "security.kubearmor.com", Version: "v1"},
+	{Kind: "KubeArmorClusterPolicy", Group: "security.kubearmor.com", Version: "v1"},
+	{Kind: "CiliumNetworkPolicy", Group: "cilium.io", Version: "v2"},
+}
+
 func HealthController(router fiber.Router) {
 	router.Get("/beat", func(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{})
@@ -63,6 +76,22 @@ func HealthController(router fiber.Router) {
 			c_name = "empty"
 		}
 
+		// Check 3: Verify which policy CRDs are installed
+		crds, err := installedCRDs(clientset.Discovery())
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
+		}
+
+		// Check 4: Verify the rollout of the security engine DaemonSets
+		resources, err := k8scache.GetCache()
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
+		}
+		engines, err := features.GetSecurityEngineDaemonSets(resources)
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
+		}
+
 		return c.JSON(agent_dto.HealthResponse{
 			K8sInfo: agent_dto.Health_K8s{
 				ClusterName:      c_name,
@@ -73,6 +102,36 @@ func HealthController(router fiber.Router) {
 				Status:  true,
 				Message: "SNFOK Agent is running correctly.",
 			},
+			CRDs:    crds,
+			Engines: engines,
 		})
 	})
 }
+
+// installedCRDs reports for every policy kind whether the API server serves it
+func installedCRDs(client discovery.DiscoveryInterface) ([]agent_dto.Health_CRD, error) {
+	served := make(map[string]map[string]bool)
+
+	crds := []agent_dto.Health_CRD{}
+	for _, crd := range policyCRDs {
+		groupVersion := crd.Group + "/" + crd.Version
+		kinds, checked := served[groupVersion]
+		if !checked {
+			kinds = make(map[string]bool)
+			resources, err := client.ServerResourcesForGroupVersion(groupVersion)
+			if err != nil && !apierrors.IsNotFound(err) {
+				return nil, fmt.Errorf("failed to discover %s resources: %v", groupVersion, err)
+			}
+			if resources != nil {
+				for _, resource := range resources.APIResources {
+					kinds[resource.Kind] = true
+				}
+			}
+			served[groupVersion] = kinds
+		}
+
+		crd.Installed = kinds[crd.Kind]
+		crds = append(crds, crd)
+	}
+	return crds, nil
+}
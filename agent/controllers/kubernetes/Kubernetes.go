@@ -7,4 +7,7 @@ import (
 
 func KubernetesController(router fiber.Router) {
 	routes.GetAllWorkerNodes(router)
+	routes.WatchResources(router)
+	routes.CollectForensics(router)
+	routes.GetPodLogs(router)
 }
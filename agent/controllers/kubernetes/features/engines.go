@@ -0,0 +1,39 @@
+package features
+
+import (
+	"fmt"
+
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8scache"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+)
+
+// GetSecurityEngineDaemonSets reports the DaemonSet of every runtime security engine, its rollout readiness and version
+func GetSecurityEngineDaemonSets(resources *k8scache.Cache) ([]agent_dto.Health_Engine, error) {
+	result := []agent_dto.Health_Engine{}
+	for _, engine := range securityEngines {
+		daemonsets, err := resources.DaemonSets.List(engine.selector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s daemonsets: %v", engine.name, err)
+		}
+
+		status := agent_dto.Health_Engine{Engine: engine.name}
+		if len(daemonsets) != 0 {
+			ds := daemonsets[0]
+			status.Installed = true
+			status.Namespace = ds.Namespace
+			status.DaemonSet = ds.Name
+			status.Desired = ds.Status.DesiredNumberScheduled
+			status.Ready = ds.Status.NumberReady
+			status.Available = ds.Status.NumberAvailable
+			status.Healthy = status.Desired > 0 && status.Ready == status.Desired && ds.Status.UpdatedNumberScheduled == status.Desired
+			for _, c := range ds.Spec.Template.Spec.Containers {
+				if c.Name == engine.container || status.Image == "" {
+					status.Image = c.Image
+				}
+			}
+			status.Version = imageTag(status.Image)
+		}
+		result = append(result, status)
+	}
+	return result, nil
+}
@@ -0,0 +1,386 @@
+package features
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8scache"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8sclient"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/manifests"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// maxLogBytes caps every collected container log so a noisy container cannot exhaust the agent's memory
+const maxLogBytes = 10 * 1024 * 1024
+
+// OwnerReference is a single link of a pod's owner chain, e.g. ReplicaSet -> Deployment
+type OwnerReference struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	UID    string `json:"uid"`
+	Object any    `json:"object,omitempty"` // Full object, when the kind is known and still exists
+}
+
+// RBACBindings holds the bindings granting permissions to a ServiceAccount and the roles they refer to
+type RBACBindings struct {
+	RoleBindings        []rbacv1.RoleBinding        `json:"role_bindings"`
+	ClusterRoleBindings []rbacv1.ClusterRoleBinding `json:"cluster_role_bindings"`
+	Roles               []rbacv1.Role               `json:"roles"`
+	ClusterRoles        []rbacv1.ClusterRole        `json:"cluster_roles"`
+}
+
+// forensicBundle accumulates the files of a bundle in memory before they are archived
+type forensicBundle struct {
+	names    []string
+	contents map[string][]byte
+	manifest agent_dto.ForensicManifest
+}
+
+func (b *forensicBundle) addFile(name string, content []byte) {
+	sum := sha256.Sum256(content)
+	b.names = append(b.names, name)
+	b.contents[name] = content
+	b.manifest.Files = append(b.manifest.Files, agent_dto.ForensicFile{
+		Name:   name,
+		Size:   int64(len(content)),
+		SHA256: hex.EncodeToString(sum[:]),
+	})
+}
+
+func (b *forensicBundle) addJSON(name string, value any) {
+	content, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		b.fail("%s: %v", name, err)
+		return
+	}
+	b.addFile(name, content)
+}
+
+func (b *forensicBundle) fail(format string, args ...any) {
+	b.manifest.Errors = append(b.manifest.Errors, fmt.Sprintf(format, args...))
+}
+
+// archive writes every file followed by the manifest into a tar.gz
+func (b *forensicBundle) archive() ([]byte, error) {
+	manifest, err := json.MarshalIndent(b.manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	gz := gzip.NewWriter(&buffer)
+	tw := tar.NewWriter(gz)
+
+	write := func(name string, content []byte) error {
+		err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(content)),
+			ModTime: b.manifest.CollectedAt,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = tw.Write(content)
+		return err
+	}
+
+	for _, name := range b.names {
+		if err := write(name, b.contents[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := write(agent_dto.FORENSIC_MANIFEST_FILE, manifest); err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// CollectForensicBundle gathers the evidence of a pod into a tar.gz: its spec and status, owner chain,
+// current and previous container logs, related events, ServiceAccount and RBAC bindings, node and the
+// SNFOK policies targeting it. Only a missing pod fails the collection; every other item that cannot be
+// read is recorded in the manifest errors so that as much evidence as possible is preserved.
+func CollectForensicBundle(clientset *kubernetes.Clientset, client dynamic.Interface, resources *k8scache.Cache, namespace, podName string) ([]byte, agent_dto.ForensicManifest, error) {
+	ctx := context.TODO()
+	collectedAt := time.Now().UTC()
+
+	// The pod is read from the API server, the cache may lag behind a pod that is about to disappear
+	pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return nil, agent_dto.ForensicManifest{}, fmt.Errorf("failed to get pod %s in namespace %s: %v", podName, namespace, err)
+	}
+	pod.APIVersion, pod.Kind = "v1", "Pod"
+
+	bundle := &forensicBundle{
+		contents: make(map[string][]byte),
+		manifest: agent_dto.ForensicManifest{
+			Namespace:   pod.Namespace,
+			Pod:         pod.Name,
+			PodUID:      string(pod.UID),
+			NodeName:    pod.Spec.NodeName,
+			CollectedAt: collectedAt,
+		},
+	}
+	if cluster, err := k8sclient.GetClusterName(""); err == nil {
+		bundle.manifest.Cluster = cluster
+	}
+
+	bundle.addJSON("pod.json", pod)
+
+	if description, err := DescribePod(clientset, resources, namespace, podName); err == nil {
+		bundle.addJSON("description.json", description)
+	} else {
+		bundle.fail("description: %v", err)
+	}
+
+	owners := collectOwnerChain(ctx, clientset, bundle, pod)
+	bundle.addJSON("owners.json", owners)
+
+	collectLogs(ctx, clientset, bundle, pod)
+
+	// Events of the pod and of every owner in its chain
+	var events []corev1.Event
+	involved := []types.UID{pod.UID}
+	for _, owner := range owners {
+		involved = append(involved, types.UID(owner.UID))
+	}
+	for _, uid := range involved {
+		list, err := clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+			FieldSelector: fmt.Sprintf("involvedObject.uid=%s", uid),
+		})
+		if err != nil {
+			bundle.fail("events of %s: %v", uid, err)
+			continue
+		}
+		events = append(events, list.Items...)
+	}
+	bundle.addJSON("events.json", events)
+
+	serviceAccount := pod.Spec.ServiceAccountName
+	if serviceAccount == "" {
+		serviceAccount = "default"
+	}
+	if sa, err := clientset.CoreV1().ServiceAccounts(namespace).Get(ctx, serviceAccount, metav1.GetOptions{}); err == nil {
+		sa.APIVersion, sa.Kind = "v1", "ServiceAccount"
+		bundle.addJSON("serviceaccount.json", sa)
+	} else {
+		bundle.fail("serviceaccount %s: %v", serviceAccount, err)
+	}
+	bundle.addJSON("rbac.json", collectRBACBindings(ctx, clientset, bundle, namespace, serviceAccount))
+
+	if pod.Spec.NodeName != "" {
+		if node, err := clientset.CoreV1().Nodes().Get(ctx, pod.Spec.NodeName, metav1.GetOptions{}); err == nil {
+			node.APIVersion, node.Kind = "v1", "Node"
+			bundle.addJSON("node.json", node)
+		} else {
+			bundle.fail("node %s: %v", pod.Spec.NodeName, err)
+		}
+	}
+
+	var policies []any
+	managed, err := manifests.ListManaged(ctx, client, namespace)
+	if err != nil {
+		bundle.fail("policies: %v", err)
+	}
+	for i := range managed {
+		selected, err := manifests.SelectsPod(&managed[i], namespace, pod.Labels)
+		if err != nil {
+			bundle.fail("policies: %v", err)
+			continue
+		}
+		if selected {
+			policies = append(policies, managed[i].Object)
+		}
+	}
+	bundle.addJSON("policies.json", policies)
+
+	archive, err := bundle.archive()
+	if err != nil {
+		return nil, agent_dto.ForensicManifest{}, fmt.Errorf("failed to archive forensic bundle: %v", err)
+	}
+	return archive, bundle.manifest, nil
+}
+
+// collectOwnerChain follows the controller references of the pod up to the top-level workload
+func collectOwnerChain(ctx context.Context, clientset *kubernetes.Clientset, bundle *forensicBundle, pod *corev1.Pod) []OwnerReference {
+	owners := []OwnerReference{}
+
+	ref := metav1.GetControllerOf(pod)
+	for ref != nil {
+		owner := OwnerReference{Kind: ref.Kind, Name: ref.Name, UID: string(ref.UID)}
+
+		var object metav1.Object
+		var err error
+		switch ref.Kind {
+		case "ReplicaSet":
+			object, err = clientset.AppsV1().ReplicaSets(pod.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		case "Deployment":
+			object, err = clientset.AppsV1().Deployments(pod.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		case "StatefulSet":
+			object, err = clientset.AppsV1().StatefulSets(pod.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		case "DaemonSet":
+			object, err = clientset.AppsV1().DaemonSets(pod.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		case "Job":
+			object, err = clientset.BatchV1().Jobs(pod.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		case "CronJob":
+			object, err = clientset.BatchV1().CronJobs(pod.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		}
+
+		ref = nil
+		if err != nil {
+			bundle.fail("owner %s %s: %v", owner.Kind, owner.Name, err)
+		} else if object != nil {
+			owner.Object = object
+			ref = metav1.GetControllerOf(object)
+		}
+		owners = append(owners, owner)
+	}
+
+	return owners
+}
+
+// collectLogs adds the current log of every container and the previous log of restarted containers
+func collectLogs(ctx context.Context, clientset *kubernetes.Clientset, bundle *forensicBundle, pod *corev1.Pod) {
+	restarts := make(map[string]int32)
+	for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, status := range statuses {
+			restarts[status.Name] = status.RestartCount
+		}
+	}
+
+	var containers []string
+	for _, container := range pod.Spec.InitContainers {
+		containers = append(containers, container.Name)
+	}
+	for _, container := range pod.Spec.Containers {
+		containers = append(containers, container.Name)
+	}
+	for _, container := range pod.Spec.EphemeralContainers {
+		containers = append(containers, container.Name)
+	}
+
+	limit := int64(maxLogBytes)
+	for _, container := range containers {
+		logs, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+			Container:  container,
+			Timestamps: true,
+			LimitBytes: &limit,
+		}).DoRaw(ctx)
+		if err == nil {
+			bundle.addFile("logs/"+container+".log", logs)
+		} else {
+			bundle.fail("logs of %s: %v", container, err)
+		}
+
+		if restarts[container] == 0 {
+			continue
+		}
+		previous, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+			Container:  container,
+			Previous:   true,
+			Timestamps: true,
+			LimitBytes: &limit,
+		}).DoRaw(ctx)
+		if err == nil {
+			bundle.addFile("logs/"+container+".previous.log", previous)
+		} else {
+			bundle.fail("previous logs of %s: %v", container, err)
+		}
+	}
+}
+
+// collectRBACBindings returns the bindings whose subjects include the ServiceAccount, directly or through
+// the system:serviceaccounts groups, together with the roles they grant
+func collectRBACBindings(ctx context.Context, clientset *kubernetes.Clientset, bundle *forensicBundle, namespace, serviceAccount string) RBACBindings {
+	bindings := RBACBindings{
+		RoleBindings:        []rbacv1.RoleBinding{},
+		ClusterRoleBindings: []rbacv1.ClusterRoleBinding{},
+		Roles:               []rbacv1.Role{},
+		ClusterRoles:        []rbacv1.ClusterRole{},
+	}
+
+	matches := func(subjects []rbacv1.Subject) bool {
+		for _, subject := range subjects {
+			switch subject.Kind {
+			case rbacv1.ServiceAccountKind:
+				if subject.Name == serviceAccount && subject.Namespace == namespace {
+					return true
+				}
+			case rbacv1.GroupKind:
+				if subject.Name == "system:serviceaccounts" || subject.Name == "system:serviceaccounts:"+namespace {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	roles := make(map[string]bool)
+	clusterRoles := make(map[string]bool)
+	addRole := func(ref rbacv1.RoleRef) {
+		if ref.Kind == "ClusterRole" {
+			clusterRoles[ref.Name] = true
+		} else {
+			roles[ref.Name] = true
+		}
+	}
+
+	if list, err := clientset.RbacV1().RoleBindings(namespace).List(ctx, metav1.ListOptions{}); err == nil {
+		for _, binding := range list.Items {
+			if matches(binding.Subjects) {
+				bindings.RoleBindings = append(bindings.RoleBindings, binding)
+				addRole(binding.RoleRef)
+			}
+		}
+	} else {
+		bundle.fail("rolebindings: %v", err)
+	}
+
+	if list, err := clientset.RbacV1().ClusterRoleBindings().List(ctx, metav1.ListOptions{}); err == nil {
+		for _, binding := range list.Items {
+			if matches(binding.Subjects) {
+				bindings.ClusterRoleBindings = append(bindings.ClusterRoleBindings, binding)
+				addRole(binding.RoleRef)
+			}
+		}
+	} else {
+		bundle.fail("clusterrolebindings: %v", err)
+	}
+
+	for name := range roles {
+		if role, err := clientset.RbacV1().Roles(namespace).Get(ctx, name, metav1.GetOptions{}); err == nil {
+			bindings.Roles = append(bindings.Roles, *role)
+		} else {
+			bundle.fail("role %s: %v", name, err)
+		}
+	}
+	for name := range clusterRoles {
+		if role, err := clientset.RbacV1().ClusterRoles().Get(ctx, name, metav1.GetOptions{}); err == nil {
+			bindings.ClusterRoles = append(bindings.ClusterRoles, *role)
+		} else {
+			bundle.fail("clusterrole %s: %v", name, err)
+		}
+	}
+
+	return bindings
+}
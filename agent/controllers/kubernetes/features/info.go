@@ -3,23 +3,29 @@ package features
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8scache"
 	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
 )
 
 // GetWorkerNodes retrieves all worker nodes with their IP addresses and hostnames
-func GetWorkerNodes(clientset *kubernetes.Clientset) ([]agent_dto.WorkerNodeInfo, error) {
+func GetWorkerNodes(resources *k8scache.Cache) ([]agent_dto.WorkerNodeInfo, error) {
 	// List all nodes
-	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	nodes, err := resources.Nodes.List(labels.Everything())
 	if err != nil {
 		return nil, fmt.Errorf("failed to list nodes: %v", err)
 	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
 
 	var workerNodes []agent_dto.WorkerNodeInfo
-	for _, node := range nodes.Items {
+	for _, node := range nodes {
 		// Skip control plane nodes
 		if isControlPlaneNode(node.Labels) {
 			continue
@@ -54,81 +60,54 @@ func GetWorkerNodes(clientset *kubernetes.Clientset) ([]agent_dto.WorkerNodeInfo
 }
 
 // GetAllNamespaces retrieves the names of all namespaces in the Kubernetes cluster.
-func GetAllNamespaces(clientset *kubernetes.Clientset) ([]string, error) {
+func GetAllNamespaces(resources *k8scache.Cache) ([]string, error) {
 	// List all namespaces
-	namespaces, err := clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	namespaces, err := resources.Namespaces.List(labels.Everything())
 	if err != nil {
 		return nil, fmt.Errorf("failed to list namespaces: %v", err)
 	}
 
 	// Extract namespace names
 	var namespaceNames []string
-	for _, ns := range namespaces.Items {
+	for _, ns := range namespaces {
 		namespaceNames = append(namespaceNames, ns.Name)
 	}
+	sort.Strings(namespaceNames)
 
 	return namespaceNames, nil
 }
 
 // GetNamespaceResources retrieves all running resources in the specified namespace
-func GetNamespaceResources(clientset *kubernetes.Clientset, namespace string) (agent_dto.NamespaceResources, error) {
+func GetNamespaceResources(cache *k8scache.Cache, namespace string) (agent_dto.NamespaceResources, error) {
 	var resources agent_dto.NamespaceResources
 
 	// List Pods
-	pods, err := clientset.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
+	pods, err := cache.Pods.Pods(namespace).List(labels.Everything())
 	if err != nil {
 		return agent_dto.NamespaceResources{}, fmt.Errorf("failed to list pods in namespace %s: %v", namespace, err)
 	}
-	for _, pod := range pods.Items {
-		// Collect container details
-		var containers []agent_dto.ContainerInfo
-		for i, container := range pod.Spec.Containers {
-			// Default status
-			status := "Unknown"
-			// Check status from ContainerStatuses if available
-			if i < len(pod.Status.ContainerStatuses) {
-				containerStatus := pod.Status.ContainerStatuses[i]
-				if containerStatus.State.Running != nil {
-					status = "Running"
-				} else if containerStatus.State.Waiting != nil {
-					status = fmt.Sprintf("Waiting (%s)", containerStatus.State.Waiting.Reason)
-				} else if containerStatus.State.Terminated != nil {
-					status = fmt.Sprintf("Terminated (%s)", containerStatus.State.Terminated.Reason)
-				}
-			}
-			containers = append(containers, agent_dto.ContainerInfo{
-				Name:   container.Name,
-				Image:  container.Image,
-				Status: status,
-			})
-		}
-
-		resources.Pods = append(resources.Pods, agent_dto.PodInfo{
-			Name:       pod.Name,
-			Phase:      string(pod.Status.Phase),
-			Containers: containers,
-		})
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	for _, pod := range pods {
+		resources.Pods = append(resources.Pods, toPodInfo(pod))
 	}
 
 	// List Deployments
-	deployments, err := clientset.AppsV1().Deployments(namespace).List(context.TODO(), metav1.ListOptions{})
+	deployments, err := cache.Deployments.Deployments(namespace).List(labels.Everything())
 	if err != nil {
 		return agent_dto.NamespaceResources{}, fmt.Errorf("failed to list deployments in namespace %s: %v", namespace, err)
 	}
-	for _, dep := range deployments.Items {
-		resources.Deployments = append(resources.Deployments, agent_dto.DeploymentInfo{
-			Name:              dep.Name,
-			Replicas:          *dep.Spec.Replicas,
-			AvailableReplicas: dep.Status.AvailableReplicas,
-		})
+	sort.Slice(deployments, func(i, j int) bool { return deployments[i].Name < deployments[j].Name })
+	for _, dep := range deployments {
+		resources.Deployments = append(resources.Deployments, toDeploymentInfo(dep))
 	}
 
 	// List Services
-	services, err := clientset.CoreV1().Services(namespace).List(context.TODO(), metav1.ListOptions{})
+	services, err := cache.Services.Services(namespace).List(labels.Everything())
 	if err != nil {
 		return agent_dto.NamespaceResources{}, fmt.Errorf("failed to list services in namespace %s: %v", namespace, err)
 	}
-	for _, svc := range services.Items {
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	for _, svc := range services {
 		resources.Services = append(resources.Services, agent_dto.ServiceInfo{
 			Name:      svc.Name,
 			Type:      string(svc.Spec.Type),
@@ -137,34 +116,95 @@ func GetNamespaceResources(clientset *kubernetes.Clientset, namespace string) (a
 	}
 
 	// List StatefulSets
-	statefulSets, err := clientset.AppsV1().StatefulSets(namespace).List(context.TODO(), metav1.ListOptions{})
+	statefulSets, err := cache.StatefulSets.StatefulSets(namespace).List(labels.Everything())
 	if err != nil {
 		return agent_dto.NamespaceResources{}, fmt.Errorf("failed to list statefulsets in namespace %s: %v", namespace, err)
 	}
-	for _, sts := range statefulSets.Items {
-		resources.StatefulSets = append(resources.StatefulSets, agent_dto.StatefulSetInfo{
-			Name:              sts.Name,
-			Replicas:          *sts.Spec.Replicas,
-			AvailableReplicas: sts.Status.AvailableReplicas,
-		})
+	sort.Slice(statefulSets, func(i, j int) bool { return statefulSets[i].Name < statefulSets[j].Name })
+	for _, sts := range statefulSets {
+		resources.StatefulSets = append(resources.StatefulSets, toStatefulSetInfo(sts))
 	}
 
 	// List DaemonSets
-	daemonSets, err := clientset.AppsV1().DaemonSets(namespace).List(context.TODO(), metav1.ListOptions{})
+	daemonSets, err := cache.DaemonSets.DaemonSets(namespace).List(labels.Everything())
 	if err != nil {
 		return agent_dto.NamespaceResources{}, fmt.Errorf("failed to list daemonsets in namespace %s: %v", namespace, err)
 	}
-	for _, ds := range daemonSets.Items {
-		resources.DaemonSets = append(resources.DaemonSets, agent_dto.DaemonSetInfo{
-			Name:                   ds.Name,
-			DesiredNumberScheduled: ds.Status.DesiredNumberScheduled,
-			NumberReady:            ds.Status.NumberReady,
-		})
+	sort.Slice(daemonSets, func(i, j int) bool { return daemonSets[i].Name < daemonSets[j].Name })
+	for _, ds := range daemonSets {
+		resources.DaemonSets = append(resources.DaemonSets, toDaemonSetInfo(ds))
 	}
 
 	return resources, nil
 }
 
+// toPodInfo converts a pod into its agent_dto shape
+func toPodInfo(pod *corev1.Pod) agent_dto.PodInfo {
+	// Collect container details
+	var containers []agent_dto.ContainerInfo
+	for i, container := range pod.Spec.Containers {
+		// Default status
+		status := "Unknown"
+		// Check status from ContainerStatuses if available
+		if i < len(pod.Status.ContainerStatuses) {
+			containerStatus := pod.Status.ContainerStatuses[i]
+			if containerStatus.State.Running != nil {
+				status = "Running"
+			} else if containerStatus.State.Waiting != nil {
+				status = fmt.Sprintf("Waiting (%s)", containerStatus.State.Waiting.Reason)
+			} else if containerStatus.State.Terminated != nil {
+				status = fmt.Sprintf("Terminated (%s)", containerStatus.State.Terminated.Reason)
+			}
+		}
+		containers = append(containers, agent_dto.ContainerInfo{
+			Name:   container.Name,
+			Image:  container.Image,
+			Status: status,
+		})
+	}
+
+	return agent_dto.PodInfo{
+		Name:       pod.Name,
+		Phase:      string(pod.Status.Phase),
+		Containers: containers,
+	}
+}
+
+// toDeploymentInfo converts a deployment into its agent_dto shape
+func toDeploymentInfo(dep *appsv1.Deployment) agent_dto.DeploymentInfo {
+	return agent_dto.DeploymentInfo{
+		Name:              dep.Name,
+		Replicas:          replicas(dep.Spec.Replicas),
+		AvailableReplicas: dep.Status.AvailableReplicas,
+	}
+}
+
+// toStatefulSetInfo converts a statefulset into its agent_dto shape
+func toStatefulSetInfo(sts *appsv1.StatefulSet) agent_dto.StatefulSetInfo {
+	return agent_dto.StatefulSetInfo{
+		Name:              sts.Name,
+		Replicas:          replicas(sts.Spec.Replicas),
+		AvailableReplicas: sts.Status.AvailableReplicas,
+	}
+}
+
+// toDaemonSetInfo converts a daemonset into its agent_dto shape
+func toDaemonSetInfo(ds *appsv1.DaemonSet) agent_dto.DaemonSetInfo {
+	return agent_dto.DaemonSetInfo{
+		Name:                   ds.Name,
+		DesiredNumberScheduled: ds.Status.DesiredNumberScheduled,
+		NumberReady:            ds.Status.NumberReady,
+	}
+}
+
+// replicas dereferences spec.replicas, which defaults to 1 when unset
+func replicas(count *int32) int32 {
+	if count == nil {
+		return 1
+	}
+	return *count
+}
+
 // isControlPlaneNode checks if a node is a control plane node based on labels
 func isControlPlaneNode(labels map[string]string) bool {
 	// Check common control plane labels
@@ -178,39 +218,29 @@ func isControlPlaneNode(labels map[string]string) bool {
 }
 
 // CountAllRunningPods counts all pods in the "Running" phase across all namespaces
-func CountAllRunningPods(clientset *kubernetes.Clientset) (int, error) {
-	// Get all namespaces
-	namespaces, err := GetAllNamespaces(clientset)
+func CountAllRunningPods(resources *k8scache.Cache) (int, error) {
+	// List pods of all namespaces
+	pods, err := resources.Pods.List(labels.Everything())
 	if err != nil {
-		return 0, fmt.Errorf("failed to get namespaces: %v", err)
+		return 0, fmt.Errorf("failed to list pods: %v", err)
 	}
 
-	// Initialize counter
+	// Count pods in "Running" phase
 	totalRunningPods := 0
-
-	// Iterate through each namespace
-	for _, namespace := range namespaces {
-		// List pods in the namespace
-		pods, err := clientset.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
-		if err != nil {
-			return 0, fmt.Errorf("failed to list pods in namespace %s: %v", namespace, err)
-		}
-
-		// Count pods in "Running" phase
-		for _, pod := range pods.Items {
-			if pod.Status.Phase == "Running" {
-				totalRunningPods++
-			}
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodRunning {
+			totalRunningPods++
 		}
 	}
 
 	return totalRunningPods, nil
 }
 
-// DescribePod retrieves detailed information about a specific pod in a namespace
-func DescribePod(clientset *kubernetes.Clientset, namespace, podName string) (*agent_dto.PodDescription, error) {
+// DescribePod retrieves detailed information about a specific pod in a namespace.
+// The pod is read from the cache, its events are still read from the API server.
+func DescribePod(clientset *kubernetes.Clientset, resources *k8scache.Cache, namespace, podName string) (*agent_dto.PodDescription, error) {
 	// Get the pod
-	pod, err := clientset.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	pod, err := resources.Pods.Pods(namespace).Get(podName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pod %s in namespace %s: %v", podName, namespace, err)
 	}
@@ -315,42 +345,42 @@ func DescribePod(clientset *kubernetes.Clientset, namespace, podName string) (*a
 }
 
 // GetAllAppLabels retrieves all unique app labels for pods in each namespace
-func GetAllAppLabels(clientset *kubernetes.Clientset) ([]agent_dto.NamespaceLabels, error) {
+func GetAllAppLabels(resources *k8scache.Cache) ([]agent_dto.NamespaceLabels, error) {
 	// Get all namespaces
-	namespaces, err := clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	namespaces, err := GetAllNamespaces(resources)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list namespaces: %v", err)
+		return nil, err
 	}
 
 	var namespaceLabels []agent_dto.NamespaceLabels
 
 	// Iterate through each namespace
-	for _, ns := range namespaces.Items {
+	for _, ns := range namespaces {
 		// List all pods in the namespace
-		pods, err := clientset.CoreV1().Pods(ns.Name).List(context.TODO(), metav1.ListOptions{})
+		pods, err := resources.Pods.Pods(ns).List(labels.Everything())
 		if err != nil {
-			return nil, fmt.Errorf("failed to list pods in namespace %s: %v", ns.Name, err)
+			return nil, fmt.Errorf("failed to list pods in namespace %s: %v", ns, err)
 		}
 
 		// Collect unique app labels
 		labelSet := make(map[string]struct{})
-		for _, pod := range pods.Items {
+		for _, pod := range pods {
 			if appLabel, exists := pod.Labels["app"]; exists && appLabel != "" {
 				labelSet[appLabel] = struct{}{}
 			}
 		}
 
 		// Convert label set to slice
-		var labels []string
+		var appLabels []string
 		for label := range labelSet {
-			labels = append(labels, label)
-			fmt.Println(">> ", label)
+			appLabels = append(appLabels, label)
 		}
+		sort.Strings(appLabels)
 
 		// Append namespace with its labels (even if empty)
 		namespaceLabels = append(namespaceLabels, agent_dto.NamespaceLabels{
-			Namespace: ns.Name,
-			Labels:    labels,
+			Namespace: ns,
+			Labels:    appLabels,
 		})
 	}
 
@@ -0,0 +1,63 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// LogOptions builds the log options of a request. since is either a duration such as "15m"
+// or an RFC3339 timestamp; tailLines is the number of lines to return from the end of the log.
+func LogOptions(container, since, tailLines string, previous, follow bool) (*corev1.PodLogOptions, error) {
+	opts := &corev1.PodLogOptions{
+		Container:  container,
+		Previous:   previous,
+		Follow:     follow,
+		Timestamps: true,
+	}
+
+	if since != "" {
+		if duration, err := time.ParseDuration(since); err == nil {
+			seconds := int64(duration.Seconds())
+			if seconds <= 0 {
+				return nil, fmt.Errorf("since must be a positive duration")
+			}
+			opts.SinceSeconds = &seconds
+		} else if timestamp, err := time.Parse(time.RFC3339, since); err == nil {
+			sinceTime := metav1.NewTime(timestamp)
+			opts.SinceTime = &sinceTime
+		} else {
+			return nil, fmt.Errorf("since must be a duration or an RFC3339 timestamp")
+		}
+	}
+
+	if tailLines != "" {
+		lines, err := strconv.ParseInt(tailLines, 10, 64)
+		if err != nil || lines < 0 {
+			return nil, fmt.Errorf("tailLines must be a non-negative number")
+		}
+		opts.TailLines = &lines
+	}
+
+	if previous && follow {
+		return nil, fmt.Errorf("the previous log of a container cannot be followed")
+	}
+
+	return opts, nil
+}
+
+// StreamPodLogs opens the log stream of a pod container. A pod with a single container
+// does not need the container name. The caller must close the returned stream.
+func StreamPodLogs(ctx context.Context, clientset *kubernetes.Clientset, namespace, podName string, opts *corev1.PodLogOptions) (io.ReadCloser, error) {
+	stream, err := clientset.CoreV1().Pods(namespace).GetLogs(podName, opts).Stream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get logs of pod %s in namespace %s: %v", podName, namespace, err)
+	}
+	return stream, nil
+}
@@ -0,0 +1,216 @@
+package features
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8scache"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// securityEngine identifies the agent pods of a runtime security engine
+type securityEngine struct {
+	name      string
+	selector  labels.Selector
+	container string // Container whose image carries the engine version
+}
+
+// securityEngines lists the engines SNFOK deploys policies to, matched by the labels of their official charts
+var securityEngines = []securityEngine{
+	{
+		name:      agent_dto.SECURITY_ENGINE_TETRAGON,
+		selector:  labels.SelectorFromSet(labels.Set{"app.kubernetes.io/name": "tetragon"}),
+		container: "tetragon",
+	},
+	{
+		name:      agent_dto.SECURITY_ENGINE_KUBEARMOR,
+		selector:  labels.SelectorFromSet(labels.Set{"kubearmor-app": "kubearmor"}),
+		container: "kubearmor",
+	},
+}
+
+// GetNodeDetails returns the inventory of every node, including control-plane nodes,
+// together with the status of the security engine pods running on it
+func GetNodeDetails(resources *k8scache.Cache) ([]agent_dto.NodeDetails, error) {
+	nodes, err := resources.Nodes.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %v", err)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+
+	engines, err := securityEnginePods(resources)
+	if err != nil {
+		return nil, err
+	}
+
+	details := []agent_dto.NodeDetails{}
+	for _, node := range nodes {
+		details = append(details, toNodeDetails(node, engines))
+	}
+	return details, nil
+}
+
+// GetNodeDetail returns the inventory of a single node
+func GetNodeDetail(resources *k8scache.Cache, name string) (agent_dto.NodeDetails, error) {
+	node, err := resources.Nodes.Get(name)
+	if err != nil {
+		return agent_dto.NodeDetails{}, fmt.Errorf("failed to get node %s: %v", name, err)
+	}
+
+	engines, err := securityEnginePods(resources)
+	if err != nil {
+		return agent_dto.NodeDetails{}, err
+	}
+	return toNodeDetails(node, engines), nil
+}
+
+// securityEnginePods indexes the engine pods by engine name and node name
+func securityEnginePods(resources *k8scache.Cache) (map[string]map[string]*corev1.Pod, error) {
+	engines := make(map[string]map[string]*corev1.Pod)
+	for _, engine := range securityEngines {
+		pods, err := resources.Pods.List(engine.selector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s pods: %v", engine.name, err)
+		}
+
+		byNode := make(map[string]*corev1.Pod)
+		for _, pod := range pods {
+			if pod.Spec.NodeName == "" {
+				continue
+			}
+			// Prefer a running pod when a replaced one is still terminating on the same node
+			if current, exists := byNode[pod.Spec.NodeName]; exists && current.Status.Phase == corev1.PodRunning {
+				continue
+			}
+			byNode[pod.Spec.NodeName] = pod
+		}
+		engines[engine.name] = byNode
+	}
+	return engines, nil
+}
+
+// toNodeDetails converts a node and the engine pods scheduled on it into its agent_dto shape
+func toNodeDetails(node *corev1.Node, engines map[string]map[string]*corev1.Pod) agent_dto.NodeDetails {
+	info := node.Status.NodeInfo
+	details := agent_dto.NodeDetails{
+		Name:             node.Name,
+		Hostname:         node.Name,
+		ControlPlane:     isControlPlaneNode(node.Labels),
+		Unschedulable:    node.Spec.Unschedulable,
+		Created:          node.CreationTimestamp.Time,
+		Capacity:         resourceList(node.Status.Capacity),
+		Allocatable:      resourceList(node.Status.Allocatable),
+		KernelVersion:    info.KernelVersion,
+		OSImage:          info.OSImage,
+		OperatingSystem:  info.OperatingSystem,
+		Architecture:     info.Architecture,
+		ContainerRuntime: info.ContainerRuntimeVersion,
+		KubeletVersion:   info.KubeletVersion,
+		Roles:            []string{},
+		Conditions:       []agent_dto.NodeCondition{},
+		Taints:           []agent_dto.NodeTaint{},
+		Labels:           node.Labels,
+		SecurityEngines:  []agent_dto.SecurityEngineStatus{},
+	}
+
+	for _, addr := range node.Status.Addresses {
+		switch addr.Type {
+		case corev1.NodeInternalIP:
+			details.InternalIP = addr.Address
+		case corev1.NodeExternalIP:
+			details.ExternalIP = addr.Address
+		case corev1.NodeHostName:
+			details.Hostname = addr.Address
+		}
+	}
+
+	for key := range node.Labels {
+		if role, found := strings.CutPrefix(key, "node-role.kubernetes.io/"); found && role != "" {
+			details.Roles = append(details.Roles, role)
+		}
+	}
+	sort.Strings(details.Roles)
+
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			details.Ready = condition.Status == corev1.ConditionTrue
+		}
+		details.Conditions = append(details.Conditions, agent_dto.NodeCondition{
+			Type:               string(condition.Type),
+			Status:             string(condition.Status),
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+			LastTransitionTime: condition.LastTransitionTime.Time,
+		})
+	}
+
+	for _, taint := range node.Spec.Taints {
+		details.Taints = append(details.Taints, agent_dto.NodeTaint{
+			Key:    taint.Key,
+			Value:  taint.Value,
+			Effect: string(taint.Effect),
+		})
+	}
+
+	for _, engine := range securityEngines {
+		status := agent_dto.SecurityEngineStatus{Engine: engine.name}
+		if pod, exists := engines[engine.name][node.Name]; exists {
+			status.Installed = true
+			status.Namespace = pod.Namespace
+			status.Pod = pod.Name
+			status.Phase = string(pod.Status.Phase)
+			status.Running = pod.Status.Phase == corev1.PodRunning
+			status.Ready = isPodReady(pod)
+			status.Image = engineImage(pod, engine.container)
+			status.Version = imageTag(status.Image)
+		}
+		details.SecurityEngines = append(details.SecurityEngines, status)
+	}
+
+	return details
+}
+
+// resourceList converts resource quantities into their string form, e.g. "cpu": "4"
+func resourceList(list corev1.ResourceList) map[string]string {
+	result := make(map[string]string, len(list))
+	for name, quantity := range list {
+		result[string(name)] = quantity.String()
+	}
+	return result
+}
+
+// isPodReady reports whether the pod's Ready condition is true
+func isPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// engineImage returns the image of the named container, or of the first container if it does not exist
+func engineImage(pod *corev1.Pod, container string) string {
+	for _, c := range pod.Spec.Containers {
+		if c.Name == container {
+			return c.Image
+		}
+	}
+	if len(pod.Spec.Containers) > 0 {
+		return pod.Spec.Containers[0].Image
+	}
+	return ""
+}
+
+// imageTag returns the tag of an image reference, ignoring any digest and registry port
+func imageTag(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	slash := strings.LastIndex(image, "/")
+	if colon := strings.LastIndex(image, ":"); colon > slash {
+		return image[colon+1:]
+	}
+	return ""
+}
@@ -0,0 +1,287 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+// WatchKinds lists the resource kinds that can be streamed, in the order they are started
+var WatchKinds = []string{"pods", "deployments", "statefulsets", "daemonsets", "namespaces"}
+
+// watchKindOf maps the kind of a resource event to the watch kind it is streamed by
+var watchKindOf = map[string]string{
+	"Pod":         "pods",
+	"Deployment":  "deployments",
+	"StatefulSet": "statefulsets",
+	"DaemonSet":   "daemonsets",
+	"Namespace":   "namespaces",
+}
+
+// ResumeVersions holds the resource version a stream resumes after for every watch kind. Every kind has its own
+// watch, so one version cannot resume all of them.
+type ResumeVersions map[string]string
+
+// ParseResumeVersions reads resume versions written by String
+func ParseResumeVersions(id string) (ResumeVersions, error) {
+	versions := make(ResumeVersions)
+	if id == "" {
+		return versions, nil
+	}
+	for _, pair := range strings.Split(id, ",") {
+		kind, version, found := strings.Cut(pair, "=")
+		if !found || version == "" {
+			return nil, fmt.Errorf("invalid resume version %q, expected kind=resourceVersion", pair)
+		}
+		versions[kind] = version
+	}
+	return versions, nil
+}
+
+// String writes the versions as kind=resourceVersion pairs separated by commas, in the order of WatchKinds.
+// It is sent as the id of every event, so a reconnecting EventSource resumes every kind where it left off.
+func (v ResumeVersions) String() string {
+	var pairs []string
+	for _, kind := range WatchKinds {
+		if version, exists := v[kind]; exists {
+			pairs = append(pairs, kind+"="+version)
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Advance records the resource version of an event for its kind
+func (v ResumeVersions) Advance(event agent_dto.ResourceEvent) {
+	if kind, exists := watchKindOf[event.Kind]; exists && event.ResourceVersion != "" {
+		v[kind] = event.ResourceVersion
+	}
+}
+
+// resourceWatcher lists and watches one kind of resource
+type resourceWatcher struct {
+	list  func(ctx context.Context, opts metav1.ListOptions) (string, error)
+	watch func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+}
+
+// resourceWatchers returns the watchers for every supported kind, scoped to a namespace if one is given
+func resourceWatchers(clientset *kubernetes.Clientset, namespace string) map[string]resourceWatcher {
+	// Namespaces are cluster scoped, so narrow them down to the requested one by name
+	namespaceOptions := func(opts metav1.ListOptions) metav1.ListOptions {
+		if namespace != "" {
+			opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", namespace).String()
+		}
+		return opts
+	}
+
+	return map[string]resourceWatcher{
+		"pods": {
+			list: func(ctx context.Context, opts metav1.ListOptions) (string, error) {
+				list, err := clientset.CoreV1().Pods(namespace).List(ctx, opts)
+				if err != nil {
+					return "", err
+				}
+				return list.ResourceVersion, nil
+			},
+			watch: func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+				return clientset.CoreV1().Pods(namespace).Watch(ctx, opts)
+			},
+		},
+		"deployments": {
+			list: func(ctx context.Context, opts metav1.ListOptions) (string, error) {
+				list, err := clientset.AppsV1().Deployments(namespace).List(ctx, opts)
+				if err != nil {
+					return "", err
+				}
+				return list.ResourceVersion, nil
+			},
+			watch: func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+				return clientset.AppsV1().Deployments(namespace).Watch(ctx, opts)
+			},
+		},
+		"statefulsets": {
+			list: func(ctx context.Context, opts metav1.ListOptions) (string, error) {
+				list, err := clientset.AppsV1().StatefulSets(namespace).List(ctx, opts)
+				if err != nil {
+					return "", err
+				}
+				return list.ResourceVersion, nil
+			},
+			watch: func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+				return clientset.AppsV1().StatefulSets(namespace).Watch(ctx, opts)
+			},
+		},
+		"daemonsets": {
+			list: func(ctx context.Context, opts metav1.ListOptions) (string, error) {
+				list, err := clientset.AppsV1().DaemonSets(namespace).List(ctx, opts)
+				if err != nil {
+					return "", err
+				}
+				return list.ResourceVersion, nil
+			},
+			watch: func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+				return clientset.AppsV1().DaemonSets(namespace).Watch(ctx, opts)
+			},
+		},
+		"namespaces": {
+			list: func(ctx context.Context, opts metav1.ListOptions) (string, error) {
+				list, err := clientset.CoreV1().Namespaces().List(ctx, namespaceOptions(opts))
+				if err != nil {
+					return "", err
+				}
+				return list.ResourceVersion, nil
+			},
+			watch: func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+				return clientset.CoreV1().Namespaces().Watch(ctx, namespaceOptions(opts))
+			},
+		},
+	}
+}
+
+// WatchResources streams add/update/delete events of the requested kinds until ctx is cancelled.
+// Every kind resumes after its version in resume; kinds without one start from the current state.
+// It returns the versions every kind starts from, to be advanced with the events that are sent.
+func WatchResources(ctx context.Context, clientset *kubernetes.Clientset, namespace string, kinds []string, resume ResumeVersions) (<-chan agent_dto.ResourceEvent, ResumeVersions, error) {
+	watchers := resourceWatchers(clientset, namespace)
+	if len(kinds) == 0 {
+		kinds = WatchKinds
+	}
+
+	// Resolve the starting resource version of every kind before streaming anything
+	versions := make(ResumeVersions)
+	for _, kind := range kinds {
+		watcher, exists := watchers[kind]
+		if !exists {
+			return nil, nil, fmt.Errorf("unsupported watch kind %q", kind)
+		}
+		if resume[kind] != "" {
+			versions[kind] = resume[kind]
+			continue
+		}
+
+		current, err := watcher.list(ctx, metav1.ListOptions{Limit: 1})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list %s: %v", kind, err)
+		}
+		versions[kind] = current
+	}
+
+	events := make(chan agent_dto.ResourceEvent, 64)
+	done := make(chan struct{})
+	for _, kind := range kinds {
+		go func(kind string, version string) {
+			defer func() { done <- struct{}{} }()
+			runWatch(ctx, kind, watchers[kind], version, events)
+		}(kind, versions[kind])
+	}
+
+	go func() {
+		for range kinds {
+			<-done
+		}
+		close(events)
+	}()
+
+	return events, versions, nil
+}
+
+// runWatch forwards the events of one kind and re-opens the watch from the last seen
+// resource version whenever the API server closes it
+func runWatch(ctx context.Context, kind string, watcher resourceWatcher, resourceVersion string, events chan<- agent_dto.ResourceEvent) {
+	for ctx.Err() == nil {
+		w, err := watcher.watch(ctx, metav1.ListOptions{
+			ResourceVersion:     resourceVersion,
+			AllowWatchBookmarks: true,
+		})
+		if err != nil {
+			sendEvent(ctx, events, watchErrorEvent(kind, err))
+			return
+		}
+
+		for event := range w.ResultChan() {
+			switch event.Type {
+			case watch.Error:
+				w.Stop()
+				sendEvent(ctx, events, watchErrorEvent(kind, apierrors.FromObject(event.Object)))
+				return
+			case watch.Bookmark:
+				if accessor, err := meta.Accessor(event.Object); err == nil {
+					resourceVersion = accessor.GetResourceVersion()
+				}
+			default:
+				resourceEvent := toResourceEvent(event)
+				resourceVersion = resourceEvent.ResourceVersion
+				if !sendEvent(ctx, events, resourceEvent) {
+					w.Stop()
+					return
+				}
+			}
+		}
+	}
+}
+
+// sendEvent delivers an event unless the stream has been cancelled
+func sendEvent(ctx context.Context, events chan<- agent_dto.ResourceEvent, event agent_dto.ResourceEvent) bool {
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// watchErrorEvent reports a failed watch, marking expired resource versions separately
+func watchErrorEvent(kind string, err error) agent_dto.ResourceEvent {
+	eventType := agent_dto.RESOURCE_EVENT_ERROR
+	if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+		eventType = agent_dto.RESOURCE_EVENT_EXPIRED
+	}
+	return agent_dto.ResourceEvent{
+		Type:    eventType,
+		Kind:    kind,
+		Message: err.Error(),
+	}
+}
+
+// toResourceEvent converts a watch event into its agent_dto shape
+func toResourceEvent(event watch.Event) agent_dto.ResourceEvent {
+	resourceEvent := agent_dto.ResourceEvent{Type: string(event.Type)}
+
+	switch obj := event.Object.(type) {
+	case *corev1.Pod:
+		info := toPodInfo(obj)
+		resourceEvent.Kind = "Pod"
+		resourceEvent.Pod = &info
+	case *appsv1.Deployment:
+		info := toDeploymentInfo(obj)
+		resourceEvent.Kind = "Deployment"
+		resourceEvent.Deployment = &info
+	case *appsv1.StatefulSet:
+		info := toStatefulSetInfo(obj)
+		resourceEvent.Kind = "StatefulSet"
+		resourceEvent.StatefulSet = &info
+	case *appsv1.DaemonSet:
+		info := toDaemonSetInfo(obj)
+		resourceEvent.Kind = "DaemonSet"
+		resourceEvent.DaemonSet = &info
+	case *corev1.Namespace:
+		resourceEvent.Kind = "Namespace"
+	}
+
+	if accessor, err := meta.Accessor(event.Object); err == nil {
+		resourceEvent.Namespace = accessor.GetNamespace()
+		resourceEvent.Name = accessor.GetName()
+		resourceEvent.ResourceVersion = accessor.GetResourceVersion()
+	}
+
+	return resourceEvent
+}
@@ -0,0 +1,91 @@
+package features
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+)
+
+func TestParseResumeVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		want    ResumeVersions
+		wantErr bool
+	}{
+		{name: "empty", id: "", want: ResumeVersions{}},
+		{name: "one kind", id: "pods=12", want: ResumeVersions{"pods": "12"}},
+		{name: "every kind", id: "pods=1,deployments=2,statefulsets=3,daemonsets=4,namespaces=5", want: ResumeVersions{
+			"pods": "1", "deployments": "2", "statefulsets": "3", "daemonsets": "4", "namespaces": "5",
+		}},
+		{name: "any order", id: "namespaces=5,pods=1", want: ResumeVersions{"pods": "1", "namespaces": "5"}},
+		{name: "last pair wins", id: "pods=1,pods=7", want: ResumeVersions{"pods": "7"}},
+		{name: "bare version", id: "12", wantErr: true},
+		{name: "missing version", id: "pods=", wantErr: true},
+		{name: "trailing comma", id: "pods=1,", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseResumeVersions(tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseResumeVersions(%q) error = %v, wantErr %v", tt.id, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseResumeVersions(%q) = %v, want %v", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResumeVersionsString(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions ResumeVersions
+		want     string
+	}{
+		{name: "empty", versions: ResumeVersions{}, want: ""},
+		{name: "watch kind order", versions: ResumeVersions{"namespaces": "5", "pods": "1", "daemonsets": "4"}, want: "pods=1,daemonsets=4,namespaces=5"},
+		{name: "unknown kind left out", versions: ResumeVersions{"pods": "1", "services": "9"}, want: "pods=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.versions.String()
+			if got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+			parsed, err := ParseResumeVersions(got)
+			if err != nil {
+				t.Fatalf("ParseResumeVersions(%q): %v", got, err)
+			}
+			if parsed.String() != got {
+				t.Errorf("round trip = %q, want %q", parsed.String(), got)
+			}
+		})
+	}
+}
+
+func TestResumeVersionsAdvance(t *testing.T) {
+	tests := []struct {
+		name  string
+		event agent_dto.ResourceEvent
+		want  ResumeVersions
+	}{
+		{name: "known kind", event: agent_dto.ResourceEvent{Kind: "Deployment", ResourceVersion: "8"}, want: ResumeVersions{"pods": "1", "deployments": "8"}},
+		{name: "newer version", event: agent_dto.ResourceEvent{Kind: "Pod", ResourceVersion: "3"}, want: ResumeVersions{"pods": "3"}},
+		{name: "unknown kind", event: agent_dto.ResourceEvent{Kind: "Service", ResourceVersion: "9"}, want: ResumeVersions{"pods": "1"}},
+		{name: "no version", event: agent_dto.ResourceEvent{Kind: "Pod"}, want: ResumeVersions{"pods": "1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			versions := ResumeVersions{"pods": "1"}
+			versions.Advance(tt.event)
+			if !reflect.DeepEqual(versions, tt.want) {
+				t.Errorf("Advance(%+v) = %v, want %v", tt.event, versions, tt.want)
+			}
+		})
+	}
+}
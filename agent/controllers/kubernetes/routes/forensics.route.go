@@ -0,0 +1,46 @@
+package routes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/FearLessSaad/SNFOK/agent/controllers/kubernetes/features"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8scache"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8sclient"
+	"github.com/gofiber/fiber/v2"
+)
+
+func CollectForensics(router fiber.Router) {
+
+	// Returns the forensic bundle of a pod as a tar.gz
+	router.Get("/pod/forensics/:namespace/:pod", func(c *fiber.Ctx) error {
+
+		// Get the singleton Kubernetes clientset
+		clientset, err := k8sclient.GetClientset()
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
+		}
+
+		client, err := k8sclient.GetDynamicClient()
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
+		}
+
+		// Get the shared resource cache
+		resources, err := k8scache.GetCache()
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
+		}
+
+		bundle, manifest, err := features.CollectForensicBundle(clientset, client, resources, c.AllParams()["namespace"], c.AllParams()["pod"])
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(err.Error())
+		}
+
+		name := fmt.Sprintf("%s_%s_%s.tar.gz", manifest.Namespace, manifest.Pod, manifest.CollectedAt.Format("20060102T150405Z"))
+		c.Set("Content-Type", "application/gzip")
+		c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+		c.Set("X-Collected-At", manifest.CollectedAt.Format(time.RFC3339))
+		return c.Status(fiber.StatusOK).Send(bundle)
+	})
+}
@@ -1,11 +1,8 @@
 package routes
 
 import (
-	"fmt"
-	"log"
-	"os"
-
 	"github.com/FearLessSaad/SNFOK/agent/controllers/kubernetes/features"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8scache"
 	"github.com/FearLessSaad/SNFOK/agent/tooling/k8sclient"
 	"github.com/gofiber/fiber/v2"
 )
@@ -14,91 +11,116 @@ func GetAllWorkerNodes(router fiber.Router) {
 
 	router.Get("/get/all/labels", func(c *fiber.Ctx) error {
 
-		// Get the singleton Kubernetes clientset
-		clientset, err := k8sclient.GetClientset()
+		// Get the shared resource cache
+		resources, err := k8scache.GetCache()
 		if err != nil {
-			log.Fatalf("Failed to get Kubernetes clientset: %v", err)
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
 		}
 
-		nodes, err := features.GetAllAppLabels(clientset)
+		labels, err := features.GetAllAppLabels(resources)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error getting worker nodes: %v\n", err)
-			os.Exit(1)
+			return c.Status(fiber.StatusInternalServerError).JSON(err.Error())
 		}
 
-		return c.Status(fiber.StatusOK).JSON(nodes)
+		return c.Status(fiber.StatusOK).JSON(labels)
 	})
 
 	router.Get("/workers/nodes/all", func(c *fiber.Ctx) error {
 
-		// Get the singleton Kubernetes clientset
-		clientset, err := k8sclient.GetClientset()
+		// Get the shared resource cache
+		resources, err := k8scache.GetCache()
 		if err != nil {
-			log.Fatalf("Failed to get Kubernetes clientset: %v", err)
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
 		}
 
-		nodes, err := features.GetWorkerNodes(clientset)
+		nodes, err := features.GetWorkerNodes(resources)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error getting worker nodes: %v\n", err)
-			os.Exit(1)
+			return c.Status(fiber.StatusInternalServerError).JSON(err.Error())
 		}
 
 		return c.Status(fiber.StatusOK).JSON(nodes)
 	})
 
-	router.Get("/namespaces/all", func(c *fiber.Ctx) error {
+	router.Get("/nodes/all", func(c *fiber.Ctx) error {
 
-		fmt.Println("Get All NameSpaces")
-		// Get the singleton Kubernetes clientset
-		clientset, err := k8sclient.GetClientset()
+		// Get the shared resource cache
+		resources, err := k8scache.GetCache()
 		if err != nil {
-			log.Fatalf("Failed to get Kubernetes clientset: %v", err)
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
 		}
 
-		nodes, err := features.GetAllNamespaces(clientset)
+		nodes, err := features.GetNodeDetails(resources)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error getting worker nodes: %v\n", err)
-			os.Exit(1)
+			return c.Status(fiber.StatusInternalServerError).JSON(err.Error())
 		}
 
 		return c.Status(fiber.StatusOK).JSON(nodes)
 	})
 
+	router.Get("/nodes/:name", func(c *fiber.Ctx) error {
+
+		// Get the shared resource cache
+		resources, err := k8scache.GetCache()
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
+		}
+
+		node, err := features.GetNodeDetail(resources, c.AllParams()["name"])
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(node)
+	})
+
+	router.Get("/namespaces/all", func(c *fiber.Ctx) error {
+
+		// Get the shared resource cache
+		resources, err := k8scache.GetCache()
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
+		}
+
+		namespaces, err := features.GetAllNamespaces(resources)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(namespaces)
+	})
+
 	router.Get("/count/pods", func(c *fiber.Ctx) error {
 
-		fmt.Println("Get All Running Pods")
-		// Get the singleton Kubernetes clientset
-		clientset, err := k8sclient.GetClientset()
+		// Get the shared resource cache
+		resources, err := k8scache.GetCache()
 		if err != nil {
-			log.Fatalf("Failed to get Kubernetes clientset: %v", err)
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
 		}
 
-		nodes, err := features.CountAllRunningPods(clientset)
+		count, err := features.CountAllRunningPods(resources)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error getting all running pods: %v\n", err)
-			os.Exit(1)
+			return c.Status(fiber.StatusInternalServerError).JSON(err.Error())
 		}
 
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"running_pods": nodes,
+			"running_pods": count,
 		})
 	})
 
 	router.Get("/namespaces/:namespace/resources", func(c *fiber.Ctx) error {
 
-		// Get the singleton Kubernetes clientset
-		clientset, err := k8sclient.GetClientset()
+		// Get the shared resource cache
+		resources, err := k8scache.GetCache()
 		if err != nil {
-			log.Fatalf("Failed to get Kubernetes clientset: %v", err)
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
 		}
 
-		nodes, err := features.GetNamespaceResources(clientset, c.AllParams()["namespace"])
+		namespaceResources, err := features.GetNamespaceResources(resources, c.AllParams()["namespace"])
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error getting worker nodes: %v\n", err)
-			os.Exit(1)
+			return c.Status(fiber.StatusInternalServerError).JSON(err.Error())
 		}
 
-		return c.Status(fiber.StatusOK).JSON(nodes)
+		return c.Status(fiber.StatusOK).JSON(namespaceResources)
 	})
 
 	router.Get("/pod/describe/:namespace/:pod", func(c *fiber.Ctx) error {
@@ -106,13 +128,18 @@ func GetAllWorkerNodes(router fiber.Router) {
 		// Get the singleton Kubernetes clientset
 		clientset, err := k8sclient.GetClientset()
 		if err != nil {
-			log.Fatalf("Failed to get Kubernetes clientset: %v", err)
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
+		}
+
+		// Get the shared resource cache
+		resources, err := k8scache.GetCache()
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
 		}
 
-		description, err := features.DescribePod(clientset, c.AllParams()["namespace"], c.AllParams()["pod"])
+		description, err := features.DescribePod(clientset, resources, c.AllParams()["namespace"], c.AllParams()["pod"])
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error getting worker nodes: %v\n", err)
-			os.Exit(1)
+			return c.Status(fiber.StatusNotFound).JSON(err.Error())
 		}
 
 		return c.Status(fiber.StatusOK).JSON(description)
@@ -0,0 +1,112 @@
+package routes
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"time"
+
+	"github.com/FearLessSaad/SNFOK/agent/controllers/kubernetes/features"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8sclient"
+	"github.com/gofiber/fiber/v2"
+)
+
+func GetPodLogs(router fiber.Router) {
+
+	// Streams the log of a pod container as plain text.
+	// Query: container, since (duration or RFC3339), tailLines, previous and follow.
+	// A followed log that stays quiet gets an empty line every heartbeatInterval, since a write is the only way
+	// to notice that the client has disconnected.
+	router.Get("/pod/logs/:namespace/:pod", func(c *fiber.Ctx) error {
+
+		// Get the singleton Kubernetes clientset
+		clientset, err := k8sclient.GetClientset()
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
+		}
+
+		opts, err := features.LogOptions(c.Query("container"), c.Query("since"), c.Query("tailLines"), c.QueryBool("previous"), c.QueryBool("follow"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(err.Error())
+		}
+
+		// The stream outlives the handler, so it cannot use the request context
+		ctx, cancel := context.WithCancel(context.Background())
+		stream, err := features.StreamPodLogs(ctx, clientset, c.AllParams()["namespace"], c.AllParams()["pod"], opts)
+		if err != nil {
+			cancel()
+			return c.Status(fiber.StatusNotFound).JSON(err.Error())
+		}
+
+		c.Set("Content-Type", "text/plain; charset=utf-8")
+		c.Set("Cache-Control", "no-cache")
+		c.Set("X-Accel-Buffering", "no")
+
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			defer cancel()
+			defer stream.Close()
+
+			// Reading blocks until the container logs something, so it runs apart from the writes. The read fails
+			// once the stream is closed when this writer returns.
+			chunks := make(chan []byte)
+			failed := make(chan error, 1)
+			go func() {
+				reader := bufio.NewReader(stream)
+				var chunk []byte
+				for {
+					line, err := reader.ReadBytes('\n')
+					chunk = append(chunk, line...)
+					// Send what is buffered at once, so it is flushed once
+					if err == nil && reader.Buffered() > 0 {
+						continue
+					}
+					if len(chunk) > 0 {
+						select {
+						case chunks <- chunk:
+						case <-ctx.Done():
+							return
+						}
+						chunk = nil
+					}
+					if err != nil {
+						failed <- err
+						return
+					}
+				}
+			}()
+
+			heartbeat := time.NewTicker(heartbeatInterval)
+			defer heartbeat.Stop()
+
+			quiet := true
+			for {
+				select {
+				case chunk := <-chunks:
+					if _, err := w.Write(chunk); err != nil {
+						return
+					}
+					quiet = false
+				case err := <-failed:
+					if err != io.EOF {
+						w.WriteString(err.Error() + "\n")
+						w.Flush()
+					}
+					return
+				case <-heartbeat.C:
+					if !quiet {
+						quiet = true
+						continue
+					}
+					w.WriteString("\n")
+				}
+
+				// A failed flush means the client has disconnected
+				if err := w.Flush(); err != nil {
+					return
+				}
+			}
+		})
+
+		return nil
+	})
+}
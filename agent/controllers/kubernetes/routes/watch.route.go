@@ -0,0 +1,98 @@
+package routes
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/FearLessSaad/SNFOK/agent/controllers/kubernetes/features"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8sclient"
+	"github.com/gofiber/fiber/v2"
+)
+
+// heartbeatInterval keeps idle event streams open through proxies
+const heartbeatInterval = 15 * time.Second
+
+func WatchResources(router fiber.Router) {
+
+	// Server-Sent Events stream of pod, workload and namespace changes.
+	// Query: namespace, kinds (comma separated) and rv[<kind>] with the resource version every kind resumes after,
+	// e.g. rv[pods]=1234. The id of every event holds the versions of all kinds, so the Last-Event-ID header sent
+	// by reconnecting EventSource clients resumes the stream when no rv[<kind>] is given.
+	router.Get("/watch", func(c *fiber.Ctx) error {
+
+		// Get the singleton Kubernetes clientset
+		clientset, err := k8sclient.GetClientset()
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
+		}
+
+		var kinds []string
+		if c.Query("kinds") != "" {
+			kinds = strings.Split(c.Query("kinds"), ",")
+		}
+
+		resume := make(features.ResumeVersions)
+		for _, kind := range features.WatchKinds {
+			if version := c.Query("rv[" + kind + "]"); version != "" {
+				resume[kind] = version
+			}
+		}
+		if len(resume) == 0 {
+			resume, err = features.ParseResumeVersions(c.Get("Last-Event-ID"))
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(err.Error())
+			}
+		}
+
+		// The stream outlives the handler, so it cannot use the request context
+		ctx, cancel := context.WithCancel(context.Background())
+		events, versions, err := features.WatchResources(ctx, clientset, c.Query("namespace"), kinds, resume)
+		if err != nil {
+			cancel()
+			return c.Status(fiber.StatusBadRequest).JSON(err.Error())
+		}
+
+		c.Set("Content-Type", "text/event-stream")
+		c.Set("Cache-Control", "no-cache")
+		c.Set("Connection", "keep-alive")
+		c.Set("X-Accel-Buffering", "no")
+
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			defer cancel()
+
+			heartbeat := time.NewTicker(heartbeatInterval)
+			defer heartbeat.Stop()
+
+			for {
+				select {
+				case event, open := <-events:
+					if !open {
+						return
+					}
+					data, err := json.Marshal(event)
+					if err != nil {
+						continue
+					}
+					if event.ResourceVersion != "" {
+						versions.Advance(event)
+						fmt.Fprintf(w, "id: %s\n", versions)
+					}
+					fmt.Fprintf(w, "event: %s\ndata: %s\n\n", strings.ToLower(event.Type), data)
+				case <-heartbeat.C:
+					fmt.Fprint(w, ": heartbeat\n\n")
+				}
+
+				// A failed flush means the client has disconnected
+				if err := w.Flush(); err != nil {
+					return
+				}
+			}
+		})
+
+		return nil
+	})
+}
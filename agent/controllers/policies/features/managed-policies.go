@@ -0,0 +1,104 @@
+package features
+
+import (
+	"context"
+	"sort"
+
+	"github.com/FearLessSaad/SNFOK/agent/tooling/manifests"
+	"github.com/FearLessSaad/SNFOK/constants/agent_consts"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// Status of a managed object when its engine does not report one
+const (
+	MANAGED_STATUS_APPLIED     = "Applied"
+	MANAGED_STATUS_TERMINATING = "Terminating"
+)
+
+// ListManagedPolicies returns every policy object in the cluster that is managed by SNFOK.
+// The result is read from the cluster itself, so it stays complete when the server database
+// or the applied policies directory of the agent is lost.
+func ListManagedPolicies(client dynamic.Interface, namespace string) ([]agent_dto.ManagedObject, error) {
+	objects, err := manifests.ListManaged(context.TODO(), client, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	managed := []agent_dto.ManagedObject{}
+	for i := range objects {
+		obj := &objects[i]
+		object_labels := obj.GetLabels()
+		managed = append(managed, agent_dto.ManagedObject{
+			Kind:          obj.GetKind(),
+			APIVersion:    obj.GetAPIVersion(),
+			Name:          obj.GetName(),
+			Namespace:     obj.GetNamespace(),
+			UID:           string(obj.GetUID()),
+			PolicyID:      object_labels[agent_consts.POLICY_ID_LABEL],
+			ImplementedID: object_labels[agent_consts.IMPLEMENTED_ID_LABEL],
+			Status:        managedStatus(obj),
+			Labels:        object_labels,
+			Created:       obj.GetCreationTimestamp().Time,
+		})
+	}
+
+	sort.Slice(managed, func(i, j int) bool {
+		if managed[i].Kind != managed[j].Kind {
+			return managed[i].Kind < managed[j].Kind
+		}
+		if managed[i].Namespace != managed[j].Namespace {
+			return managed[i].Namespace < managed[j].Namespace
+		}
+		return managed[i].Name < managed[j].Name
+	})
+	return managed, nil
+}
+
+// DeleteManagedPolicy removes every managed object labelled with the implemented policy id and returns how many were removed
+func DeleteManagedPolicy(client dynamic.Interface, implemented_id string) (int, error) {
+	ctx := context.TODO()
+	objects, err := manifests.ListImplemented(ctx, client, implemented_id)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for i := range objects {
+		if err := manifests.Delete(ctx, client, &objects[i]); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// managedStatus returns the status reported by the engine of the object, if any.
+// KubeArmor reports status.PolicyStatus; other kinds may report a Ready condition.
+func managedStatus(obj *unstructured.Unstructured) string {
+	if obj.GetDeletionTimestamp() != nil {
+		return MANAGED_STATUS_TERMINATING
+	}
+
+	if status, found, _ := unstructured.NestedString(obj.Object, "status", "PolicyStatus"); found && status != "" {
+		return status
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, raw := range conditions {
+		condition, ok := raw.(map[string]interface{})
+		if !ok || condition["type"] != "Ready" {
+			continue
+		}
+		if condition["status"] == "True" {
+			return "Ready"
+		}
+		if reason, ok := condition["reason"].(string); ok && reason != "" {
+			return reason
+		}
+		return "NotReady"
+	}
+
+	return MANAGED_STATUS_APPLIED
+}
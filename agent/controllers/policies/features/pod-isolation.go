@@ -0,0 +1,173 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8sclient"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/manifests"
+	"github.com/FearLessSaad/SNFOK/constants/agent_consts"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"github.com/google/uuid"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// IsolatePod labels a single pod with a quarantine label and applies a deny-all NetworkPolicy
+// that selects only that label, so the other replicas of the workload keep serving.
+// NetworkPolicies are additive: traffic that another policy explicitly allows to the pod is still allowed.
+func IsolatePod(clientset *kubernetes.Clientset, namespace string, pod_name string, ttl time.Duration) (agent_dto.IsolatedPod, error) {
+	ctx := context.TODO()
+
+	pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, pod_name, metav1.GetOptions{})
+	if err != nil {
+		return agent_dto.IsolatedPod{}, err
+	}
+	if id, exists := pod.Labels[agent_consts.ISOLATION_LABEL]; exists {
+		return agent_dto.IsolatedPod{}, fmt.Errorf("pod %s/%s is already isolated (%s)", namespace, pod_name, id)
+	}
+
+	id := strings.Split(uuid.NewString(), "-")[4]
+	now := time.Now().UTC()
+	annotations := map[string]string{
+		agent_consts.ISOLATED_POD_ANNOTATION: pod_name,
+		agent_consts.ISOLATED_AT_ANNOTATION:  now.Format(time.RFC3339),
+	}
+	if ttl > 0 {
+		annotations[agent_consts.ISOLATION_EXPIRY_ANNOTATION] = now.Add(ttl).Format(time.RFC3339)
+	}
+
+	// The policy is created first; it selects nothing until the pod carries the label
+	policy, err := clientset.NetworkingV1().NetworkPolicies(namespace).Create(ctx, &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        agent_consts.ISOLATION_POLICY_PREFIX + id,
+			Namespace:   namespace,
+			Labels:      map[string]string{agent_consts.ISOLATION_LABEL: id},
+			Annotations: annotations,
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{agent_consts.ISOLATION_LABEL: id},
+			},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+			Ingress:     []networkingv1.NetworkPolicyIngressRule{},
+			Egress:      []networkingv1.NetworkPolicyEgressRule{},
+		},
+	}, metav1.CreateOptions{FieldManager: manifests.FieldManager})
+	if err != nil {
+		return agent_dto.IsolatedPod{}, err
+	}
+
+	patch := fmt.Sprintf(`{"metadata":{"labels":{%q:%q}}}`, agent_consts.ISOLATION_LABEL, id)
+	_, err = clientset.CoreV1().Pods(namespace).Patch(ctx, pod_name, types.MergePatchType, []byte(patch), metav1.PatchOptions{FieldManager: manifests.FieldManager})
+	if err != nil {
+		clientset.NetworkingV1().NetworkPolicies(namespace).Delete(ctx, policy.Name, metav1.DeleteOptions{})
+		return agent_dto.IsolatedPod{}, err
+	}
+
+	return toIsolatedPod(policy), nil
+}
+
+// ListIsolatedPods returns every pod isolated by the agent, across all namespaces
+func ListIsolatedPods(clientset *kubernetes.Clientset) ([]agent_dto.IsolatedPod, error) {
+	policies, err := clientset.NetworkingV1().NetworkPolicies("").List(context.TODO(), metav1.ListOptions{
+		LabelSelector: agent_consts.ISOLATION_LABEL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list isolation policies: %v", err)
+	}
+
+	isolated := []agent_dto.IsolatedPod{}
+	for i := range policies.Items {
+		isolated = append(isolated, toIsolatedPod(&policies.Items[i]))
+	}
+	return isolated, nil
+}
+
+// ReleasePod removes the quarantine label from the pod and deletes its deny-all policy.
+// A pod that no longer exists is not treated as an error.
+func ReleasePod(clientset *kubernetes.Clientset, namespace string, id string) error {
+	ctx := context.TODO()
+
+	policy, err := clientset.NetworkingV1().NetworkPolicies(namespace).Get(ctx, agent_consts.ISOLATION_POLICY_PREFIX+id, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	// The label is removed before the policy, so a failure never leaves the pod labelled but unprotected
+	patch := fmt.Sprintf(`{"metadata":{"labels":{%q:null}}}`, agent_consts.ISOLATION_LABEL)
+	pod_name := policy.Annotations[agent_consts.ISOLATED_POD_ANNOTATION]
+	_, err = clientset.CoreV1().Pods(namespace).Patch(ctx, pod_name, types.MergePatchType, []byte(patch), metav1.PatchOptions{FieldManager: manifests.FieldManager})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	err = clientset.NetworkingV1().NetworkPolicies(namespace).Delete(ctx, policy.Name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// ReleaseExpiredPods releases every isolation whose TTL has passed
+func ReleaseExpiredPods(clientset *kubernetes.Clientset) error {
+	isolated, err := ListIsolatedPods(clientset)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, pod := range isolated {
+		if pod.ExpiresAt == nil || pod.ExpiresAt.After(now) {
+			continue
+		}
+		if err := ReleasePod(clientset, pod.Namespace, pod.ID); err != nil {
+			log.Printf("Failed to release isolated pod %s/%s: %v", pod.Namespace, pod.Pod, err)
+			continue
+		}
+		log.Printf("Released isolated pod %s/%s after its TTL expired", pod.Namespace, pod.Pod)
+	}
+	return nil
+}
+
+// RunIsolationReaper periodically releases expired isolations. The expiry is stored on the
+// policy itself, so isolations survive agent restarts.
+func RunIsolationReaper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		clientset, err := k8sclient.GetClientset()
+		if err != nil {
+			continue
+		}
+		if err := ReleaseExpiredPods(clientset); err != nil {
+			log.Printf("Failed to release expired isolations: %v", err)
+		}
+	}
+}
+
+// toIsolatedPod reads the isolation details stored on the deny-all policy
+func toIsolatedPod(policy *networkingv1.NetworkPolicy) agent_dto.IsolatedPod {
+	isolated := agent_dto.IsolatedPod{
+		ID:         policy.Labels[agent_consts.ISOLATION_LABEL],
+		Namespace:  policy.Namespace,
+		Pod:        policy.Annotations[agent_consts.ISOLATED_POD_ANNOTATION],
+		PolicyName: policy.Name,
+		IsolatedAt: policy.CreationTimestamp.Time,
+	}
+
+	if isolatedAt, err := time.Parse(time.RFC3339, policy.Annotations[agent_consts.ISOLATED_AT_ANNOTATION]); err == nil {
+		isolated.IsolatedAt = isolatedAt
+	}
+	if expiresAt, err := time.Parse(time.RFC3339, policy.Annotations[agent_consts.ISOLATION_EXPIRY_ANNOTATION]); err == nil {
+		isolated.ExpiresAt = &expiresAt
+	}
+	return isolated
+}
@@ -0,0 +1,101 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/FearLessSaad/SNFOK/agent/tooling/templates"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// DeployBundle applies the members of a bundle in order, as one unit. Every member is rendered before anything is
+// applied; if a member fails to render or apply, the members applied before it are removed again and the rest is
+// skipped. The outcome of every member is reported, also when the bundle is not deployed.
+func DeployBundle(client dynamic.Interface, bundle agent_dto.DeployBundle) (agent_dto.DeployBundleResponse, error) {
+	if len(bundle.Members) == 0 {
+		return agent_dto.DeployBundleResponse{}, fmt.Errorf("bundle has no members")
+	}
+
+	result := agent_dto.DeployBundleResponse{Members: make([]agent_dto.BundleMemberResult, len(bundle.Members))}
+	for i, member := range bundle.Members {
+		result.Members[i] = agent_dto.BundleMemberResult{
+			PolicyID:      member.PolicyID,
+			ImplementedID: member.ImplementedID,
+			Status:        agent_dto.BUNDLE_MEMBER_SKIPPED,
+		}
+	}
+
+	objects := make([][]*unstructured.Unstructured, len(bundle.Members))
+	rendered := make([][]byte, len(bundle.Members))
+	for i, member := range bundle.Members {
+		var err error
+		objects[i], rendered[i], err = renderPolicy(member)
+		if err != nil {
+			result.Members[i].Status = agent_dto.BUNDLE_MEMBER_FAILED
+			result.Members[i].Error = err.Error()
+			return result, nil
+		}
+	}
+
+	for i := range bundle.Members {
+		policy_path, err := templates.SavePolicy(rendered[i])
+		if err != nil {
+			result.Members[i].Status = agent_dto.BUNDLE_MEMBER_FAILED
+			result.Members[i].Error = fmt.Sprintf("%s %v", policy_path, err)
+			removeBundleFiles(result.Members[:i])
+			return result, nil
+		}
+		result.Members[i].PolicyPath = policy_path
+	}
+
+	ctx := context.TODO()
+	previous := make([][]*unstructured.Unstructured, len(bundle.Members))
+	for i := range bundle.Members {
+		applied, states, err := applyObjects(ctx, client, objects[i])
+		if err != nil {
+			result.Members[i].Status = agent_dto.BUNDLE_MEMBER_FAILED
+			result.Members[i].Error = err.Error()
+			rollbackBundle(ctx, client, objects[:i], previous[:i], result.Members[:i])
+			removeBundleFiles(result.Members)
+			return result, nil
+		}
+		previous[i] = states
+		result.Members[i].Status = agent_dto.BUNDLE_MEMBER_APPLIED
+		result.Members[i].Objects = applied
+	}
+
+	result.Deployed = true
+	return result, nil
+}
+
+// removeBundleFiles deletes the rendered files of members that were not deployed
+func removeBundleFiles(members []agent_dto.BundleMemberResult) {
+	for i := range members {
+		if members[i].PolicyPath == "" || members[i].Status == agent_dto.BUNDLE_MEMBER_APPLIED {
+			continue
+		}
+		os.Remove(members[i].PolicyPath)
+		members[i].PolicyPath = ""
+	}
+}
+
+// rollbackBundle puts the objects of the members that were applied back into the state they had before, so objects
+// the bundle created are removed and objects that existed already are restored. A member whose objects cannot be
+// rolled back keeps the applied status and reports the error, so it can be cleaned up by hand.
+func rollbackBundle(ctx context.Context, client dynamic.Interface, objects [][]*unstructured.Unstructured, previous [][]*unstructured.Unstructured, members []agent_dto.BundleMemberResult) {
+	for i := len(members) - 1; i >= 0; i-- {
+		if members[i].Status != agent_dto.BUNDLE_MEMBER_APPLIED {
+			continue
+		}
+
+		if failed := rollbackObjects(ctx, client, objects[i], previous[i]); failed != nil {
+			members[i].Error = fmt.Sprintf("rollback failed: %v", failed)
+			continue
+		}
+		members[i].Status = agent_dto.BUNDLE_MEMBER_ROLLED_BACK
+		members[i].Objects = nil
+	}
+}
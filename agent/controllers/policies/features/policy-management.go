@@ -1,40 +1,223 @@
 package features
 
 import (
-	"os/exec"
+	"context"
+	"errors"
+	"fmt"
+	"os"
 
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8sclient"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/manifests"
 	"github.com/FearLessSaad/SNFOK/agent/tooling/templates"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
 )
 
-func DeployPolicy(policy_file string, namespace string, app_label string) (string, error) {
+// DeployPolicy renders the policy template with its parameters and applies every object in it with server-side apply.
+// Every object is labelled with the ids of its catalog policy and implemented policy.
+// If any object fails to apply, the objects applied before it are restored to their previous state, or deleted
+// when they did not exist before.
+func DeployPolicy(details agent_dto.DeployPolicy) (agent_dto.DeployPolicyResponse, error) {
 
-	policy_path, _ := templates.GeneratePolicy(policy_file, namespace, app_label)
+	objects, rendered, err := renderPolicy(details)
+	if err != nil {
+		return agent_dto.DeployPolicyResponse{}, err
+	}
+
+	policy_path, err := templates.SavePolicy(rendered)
+	if err != nil {
+		return agent_dto.DeployPolicyResponse{}, fmt.Errorf("%s %v", policy_path, err)
+	}
+
+	client, err := k8sclient.GetDynamicClient()
+	if err != nil {
+		os.Remove(policy_path)
+		return agent_dto.DeployPolicyResponse{}, err
+	}
+
+	result, _, err := applyObjects(context.TODO(), client, objects)
+	if err != nil {
+		os.Remove(policy_path)
+		return agent_dto.DeployPolicyResponse{}, err
+	}
+
+	return agent_dto.DeployPolicyResponse{
+		PolicyPath: policy_path,
+		Objects:    result,
+	}, nil
+}
+
+// DeletePolicy removes every object of an applied policy from the cluster and deletes its rendered file.
+// When the implemented policy id is known, objects labelled with it are removed as well, so a policy
+// can still be deleted after its rendered file is lost.
+func DeletePolicy(policy_path string, implemented_id string) (string, error) {
 
-	cmd := exec.Command("kubectl", "apply", "-f", policy_path)
+	objects, err := readPolicyFile(policy_path)
+	if err != nil && (implemented_id == "" || !errors.Is(err, os.ErrNotExist)) {
+		return "", err
+	}
 
-	output, err := cmd.CombinedOutput()
+	client, err := k8sclient.GetDynamicClient()
 	if err != nil {
-		return string(output), err
+		return "", err
+	}
+
+	for _, obj := range objects {
+		if err := manifests.Delete(context.TODO(), client, obj); err != nil {
+			return "", err
+		}
+	}
+
+	if implemented_id != "" {
+		if _, err := DeleteManagedPolicy(client, implemented_id); err != nil {
+			return "", err
+		}
+	}
+
+	if err := os.Remove(policy_path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to remove policy file %s: %v", policy_path, err)
 	}
 
 	return policy_path, nil
 }
 
-func DeletePolicy(policy_path string) (string, error) {
+// applyObjects applies the objects in order and returns the state every object had before, nil for objects it
+// created. If one fails to apply, the objects applied before it are put back into that state: objects it created
+// are removed and objects that existed already, e.g. with the same name from another deployment, are restored.
+func applyObjects(ctx context.Context, client dynamic.Interface, objects []*unstructured.Unstructured) ([]agent_dto.AppliedObject, []*unstructured.Unstructured, error) {
+	var previous []*unstructured.Unstructured
+	var result []agent_dto.AppliedObject
+	for _, obj := range objects {
+		existing, err := manifests.Get(ctx, client, obj)
+		if err != nil {
+			rollbackObjects(ctx, client, objects[:len(previous)], previous)
+			return nil, nil, err
+		}
+
+		created, err := manifests.Apply(ctx, client, obj)
+		if err != nil {
+			rollbackObjects(ctx, client, objects[:len(previous)], previous)
+			return nil, nil, err
+		}
+
+		previous = append(previous, existing)
+		result = append(result, agent_dto.AppliedObject{
+			Kind:            created.GetKind(),
+			Name:            created.GetName(),
+			Namespace:       created.GetNamespace(),
+			UID:             string(created.GetUID()),
+			ResourceVersion: created.GetResourceVersion(),
+		})
+	}
+	return result, previous, nil
+}
+
+// rollbackObjects puts applied objects back into the state they had before, in reverse order, see applyObjects.
+// It continues after a failure and returns the last error.
+func rollbackObjects(ctx context.Context, client dynamic.Interface, objects []*unstructured.Unstructured, previous []*unstructured.Unstructured) error {
+	var failed error
+	for i := len(objects) - 1; i >= 0; i-- {
+		if err := manifests.Restore(ctx, client, objects[i], previous[i]); err != nil {
+			failed = err
+		}
+	}
+	return failed
+}
+
+// renderPolicy renders the policy template, pushed by the server or read from the templates directory, and decodes it,
+// then sets the scope or pod selector, the mode and the SNFOK labels on every object. It returns the objects and the manifest
+// that is applied.
+func renderPolicy(details agent_dto.DeployPolicy) ([]*unstructured.Unstructured, []byte, error) {
+	var rendered string
+	var err error
+	if details.Template != "" {
+		rendered, err = templates.RenderContent("policy-"+details.PolicyID, details.Template, details.Namespace, details.AppLabel, details.Params)
+	} else {
+		rendered, err = templates.RenderPolicy(details.FilePath, details.Namespace, details.AppLabel, details.Params)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s %v", rendered, err)
+	}
+
+	objects, err := manifests.Decode([]byte(rendered))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scope := details.Scope
+	if scope == "" {
+		scope = agent_dto.POLICY_SCOPE_WORKLOAD
+	}
+
+	var selector *metav1.LabelSelector
+	if details.Selector != nil {
+		if scope != agent_dto.POLICY_SCOPE_WORKLOAD {
+			return nil, nil, fmt.Errorf("a selector can only be used with %s scope", agent_dto.POLICY_SCOPE_WORKLOAD)
+		}
+		selector, err = toLabelSelector(details.Selector)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
 
-	cmd := exec.Command("kubectl", "delete", "-f", policy_path)
+	for _, obj := range objects {
+		switch scope {
+		case agent_dto.POLICY_SCOPE_WORKLOAD:
+			if selector != nil {
+				err = manifests.SetSelector(obj, selector)
+			}
+		case agent_dto.POLICY_SCOPE_NAMESPACE:
+			err = manifests.SetNamespaceScope(obj, details.Namespace)
+		case agent_dto.POLICY_SCOPE_CLUSTER:
+			err = manifests.SetClusterScope(obj, details.Namespaces, details.ExcludedNamespaces, details.IncludeHost)
+		default:
+			err = fmt.Errorf("unknown policy scope %q", scope)
+		}
+		if err == nil && details.Mode != "" {
+			err = manifests.SetMode(obj, details.Mode)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		manifests.Label(obj, details.PolicyID, details.ImplementedID, scope)
+	}
 
-	output, err := cmd.CombinedOutput()
+	content, err := manifests.Encode(objects)
 	if err != nil {
-		return string(output), err
+		return nil, nil, err
+	}
+	return objects, content, nil
+}
+
+// toLabelSelector converts and validates a selector. An empty selector is rejected, since it would select every pod.
+func toLabelSelector(selector *agent_dto.LabelSelector) (*metav1.LabelSelector, error) {
+	if len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0 {
+		return nil, fmt.Errorf("selector has no matchLabels or matchExpressions")
+	}
+
+	result := &metav1.LabelSelector{MatchLabels: selector.MatchLabels}
+	for _, expression := range selector.MatchExpressions {
+		result.MatchExpressions = append(result.MatchExpressions, metav1.LabelSelectorRequirement{
+			Key:      expression.Key,
+			Operator: metav1.LabelSelectorOperator(expression.Operator),
+			Values:   expression.Values,
+		})
 	}
 
-	cmd = exec.Command("rm", policy_path)
+	if _, err := metav1.LabelSelectorAsSelector(result); err != nil {
+		return nil, fmt.Errorf("invalid selector: %v", err)
+	}
+	return result, nil
+}
 
-	output, err = cmd.CombinedOutput()
+// readPolicyFile reads a rendered policy and decodes all of its objects
+func readPolicyFile(policy_path string) ([]*unstructured.Unstructured, error) {
+	content, err := os.ReadFile(policy_path)
 	if err != nil {
-		return string(output), err
+		return nil, fmt.Errorf("failed to read policy file %s: %w", policy_path, err)
 	}
 
-	return policy_path, nil
+	return manifests.Decode(content)
 }
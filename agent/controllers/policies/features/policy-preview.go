@@ -0,0 +1,86 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8scache"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/manifests"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
+)
+
+// PreviewPolicy renders the policy exactly as DeployPolicy would and applies every object in it in dry-run mode,
+// without writing the rendered file. For every object it reports the schema or admission error, if any, and the
+// running pods its selector matches. Errors in the template, its parameters or the selector are reported in the preview.
+func PreviewPolicy(client dynamic.Interface, resources *k8scache.Cache, details agent_dto.DeployPolicy) (agent_dto.PolicyPreview, error) {
+
+	objects, rendered, err := renderPolicy(details)
+	if err != nil {
+		return agent_dto.PolicyPreview{
+			Valid:   false,
+			Error:   err.Error(),
+			Objects: []agent_dto.PreviewObject{},
+		}, nil
+	}
+
+	preview := agent_dto.PolicyPreview{
+		RenderedYAML: string(rendered),
+		Valid:        true,
+		Objects:      []agent_dto.PreviewObject{},
+	}
+
+	pods, err := resources.Pods.List(labels.Everything())
+	if err != nil {
+		return agent_dto.PolicyPreview{}, fmt.Errorf("failed to list pods: %v", err)
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		if pods[i].Namespace != pods[j].Namespace {
+			return pods[i].Namespace < pods[j].Namespace
+		}
+		return pods[i].Name < pods[j].Name
+	})
+
+	for _, obj := range objects {
+		result := agent_dto.PreviewObject{
+			Kind:        obj.GetKind(),
+			Name:        obj.GetName(),
+			Namespace:   obj.GetNamespace(),
+			Valid:       true,
+			MatchedPods: []agent_dto.PreviewPod{},
+		}
+
+		if _, err := manifests.DryRun(context.TODO(), client, obj); err != nil {
+			result.Valid = false
+			result.Error = err.Error()
+			preview.Valid = false
+		}
+
+		for _, pod := range pods {
+			if pod.Status.Phase != corev1.PodRunning {
+				continue
+			}
+			matches, err := manifests.SelectsPod(obj, pod.Namespace, pod.Labels)
+			if err != nil {
+				result.Valid = false
+				result.Error = err.Error()
+				preview.Valid = false
+				break
+			}
+			if matches {
+				result.MatchedPods = append(result.MatchedPods, agent_dto.PreviewPod{
+					Namespace: pod.Namespace,
+					Name:      pod.Name,
+					NodeName:  pod.Spec.NodeName,
+				})
+			}
+		}
+
+		preview.Objects = append(preview.Objects, result)
+	}
+
+	return preview, nil
+}
@@ -0,0 +1,115 @@
+package features
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/FearLessSaad/SNFOK/agent/tooling/manifests"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/templates"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// UpgradePolicy applies another version of an applied policy and removes the objects of the previous version
+// that the new version does not replace in place. If the new version fails to apply, its objects are put back into
+// the state they had; if an object of the previous version cannot be removed, the previous version is restored
+// from its rendered file as well. When the rendered file is lost, the objects labelled with the implemented policy
+// id are the previous version.
+func UpgradePolicy(client dynamic.Interface, details agent_dto.UpgradePolicy) (agent_dto.DeployPolicyResponse, error) {
+	ctx := context.TODO()
+
+	previous, err := readPolicyFile(details.PreviousPath)
+	if errors.Is(err, os.ErrNotExist) && details.ImplementedID != "" {
+		previous, err = implementedObjects(ctx, client, details.ImplementedID)
+	}
+	if err != nil {
+		return agent_dto.DeployPolicyResponse{}, err
+	}
+
+	objects, rendered, err := renderPolicy(details.DeployPolicy)
+	if err != nil {
+		return agent_dto.DeployPolicyResponse{}, err
+	}
+
+	policy_path, err := templates.SavePolicy(rendered)
+	if err != nil {
+		return agent_dto.DeployPolicyResponse{}, fmt.Errorf("%s %v", policy_path, err)
+	}
+
+	// A failed apply puts the objects it applied back into their previous state itself
+	result, states, err := applyObjects(ctx, client, objects)
+	if err != nil {
+		os.Remove(policy_path)
+		return agent_dto.DeployPolicyResponse{}, err
+	}
+
+	replaced := objectKeys(objects)
+	for _, obj := range previous {
+		if replaced[objectKey(obj)] {
+			continue
+		}
+		if err := manifests.Delete(ctx, client, obj); err != nil {
+			err = fmt.Errorf("failed to remove %s %q of the previous version: %v", obj.GetKind(), obj.GetName(), err)
+
+			// Go back to the previous version: undo the apply, then restore the objects removed so far
+			restore_err := rollbackObjects(ctx, client, objects, states)
+			if failed := restoreObjects(ctx, client, previous); failed != nil {
+				restore_err = failed
+			}
+			os.Remove(policy_path)
+			if restore_err != nil {
+				return agent_dto.DeployPolicyResponse{}, fmt.Errorf("%v; restoring the previous version failed: %v", err, restore_err)
+			}
+			return agent_dto.DeployPolicyResponse{}, err
+		}
+	}
+
+	os.Remove(details.PreviousPath)
+
+	return agent_dto.DeployPolicyResponse{
+		PolicyPath: policy_path,
+		Objects:    result,
+	}, nil
+}
+
+// restoreObjects applies the objects of the previous version again, continuing after a failure
+func restoreObjects(ctx context.Context, client dynamic.Interface, objects []*unstructured.Unstructured) error {
+	var failed error
+	for _, obj := range objects {
+		if err := manifests.Restore(ctx, client, obj, obj); err != nil {
+			failed = err
+		}
+	}
+	return failed
+}
+
+// implementedObjects returns the objects in the cluster that are labelled with the implemented policy id
+func implementedObjects(ctx context.Context, client dynamic.Interface, implemented_id string) ([]*unstructured.Unstructured, error) {
+	listed, err := manifests.ListImplemented(ctx, client, implemented_id)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]*unstructured.Unstructured, len(listed))
+	for i := range listed {
+		objects[i] = &listed[i]
+	}
+	return objects, nil
+}
+
+// objectKeys returns the keys of the objects, see objectKey
+func objectKeys(objects []*unstructured.Unstructured) map[string]bool {
+	keys := make(map[string]bool)
+	for _, obj := range objects {
+		keys[objectKey(obj)] = true
+	}
+	return keys
+}
+
+// objectKey identifies an object in the cluster by its kind, namespace and name
+func objectKey(obj *unstructured.Unstructured) string {
+	return obj.GetKind() + "/" + obj.GetNamespace() + "/" + obj.GetName()
+}
@@ -1,16 +1,23 @@
 package routes
 
 import (
+	"errors"
+
 	"github.com/FearLessSaad/SNFOK/agent/controllers/policies/features"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8scache"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8sclient"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/templates"
 	"github.com/FearLessSaad/SNFOK/constants/message"
 	"github.com/FearLessSaad/SNFOK/constants/response"
 	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
 	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
 	"github.com/gofiber/fiber/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
 type PolicyPathRequest struct {
-	Path string `json:"path"`
+	Path          string `json:"path"`
+	ImplementedID string `json:"implemented_id,omitempty"`
 }
 
 func DeployPolicy(router fiber.Router) {
@@ -21,15 +28,90 @@ func DeployPolicy(router fiber.Router) {
 			return c.Status(fiber.StatusBadRequest).JSON("")
 		}
 
-		path, err := features.DeployPolicy(details.FilePath, details.Namespace, details.AppLabel)
+		deployed, err := features.DeployPolicy(*details)
+
+		if err != nil {
+			return c.Status(policyErrorStatus(err)).JSON(err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(deployed)
+	})
+
+	// Applies several policies as one unit, rolling back every member if one fails
+	router.Post("/deploy/bundle", func(c *fiber.Ctx) error {
+		details := new(agent_dto.DeployBundle)
+		if err := c.BodyParser(details); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON("")
+		}
+
+		// Get the dynamic Kubernetes client
+		client, err := k8sclient.GetDynamicClient()
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
+		}
+
+		deployed, err := features.DeployBundle(client, *details)
+		if err != nil {
+			return c.Status(policyErrorStatus(err)).JSON(err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(deployed)
+	})
+
+	// Replaces an applied policy with another version of its template, restoring the previous version on failure
+	router.Post("/upgrade", func(c *fiber.Ctx) error {
+		details := new(agent_dto.UpgradePolicy)
+		if err := c.BodyParser(details); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON("")
+		}
+
+		client, err := k8sclient.GetDynamicClient()
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
+		}
+
+		upgraded, err := features.UpgradePolicy(client, *details)
+		if err != nil {
+			return c.Status(policyErrorStatus(err)).JSON(err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(upgraded)
+	})
 
+	// Parameters declared by a policy template
+	router.Get("/params", func(c *fiber.Ctx) error {
+		params, err := templates.TemplateParams(c.Query("file"))
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(err.Error())
 		}
 
-		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"policy_path": path,
-		})
+		return c.Status(fiber.StatusOK).JSON(params)
+	})
+
+	// Renders the policy and applies it in dry-run mode, nothing is persisted
+	router.Post("/preview", func(c *fiber.Ctx) error {
+		details := new(agent_dto.DeployPolicy)
+		if err := c.BodyParser(details); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON("")
+		}
+
+		client, err := k8sclient.GetDynamicClient()
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
+		}
+
+		// Get the shared resource cache
+		resources, err := k8scache.GetCache()
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
+		}
+
+		preview, err := features.PreviewPolicy(client, resources, *details)
+		if err != nil {
+			return c.Status(policyErrorStatus(err)).JSON(err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(preview)
 	})
 
 	router.Post("/delete", func(c *fiber.Ctx) error {
@@ -45,11 +127,36 @@ func DeployPolicy(router fiber.Router) {
 				},
 			})
 		}
-		_, err := features.DeletePolicy(details.Path)
+		_, err := features.DeletePolicy(details.Path, details.ImplementedID)
 
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(err.Error())
+			return c.Status(policyErrorStatus(err)).JSON(err.Error())
 		}
 		return c.Status(fiber.StatusOK).JSON("")
 	})
+
+	router.Get("/managed", func(c *fiber.Ctx) error {
+
+		// Get the dynamic Kubernetes client
+		client, err := k8sclient.GetDynamicClient()
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
+		}
+
+		managed, err := features.ListManagedPolicies(client, c.Query("namespace"))
+		if err != nil {
+			return c.Status(policyErrorStatus(err)).JSON(err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(managed)
+	})
+}
+
+// policyErrorStatus passes the HTTP status of Kubernetes API errors through to the caller
+func policyErrorStatus(err error) int {
+	var status apierrors.APIStatus
+	if errors.As(err, &status) && status.Status().Code != 0 {
+		return int(status.Status().Code)
+	}
+	return fiber.StatusBadRequest
 }
@@ -1,7 +1,68 @@
 package routes
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"time"
+
+	"github.com/FearLessSaad/SNFOK/agent/controllers/policies/features"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8sclient"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"github.com/gofiber/fiber/v2"
+)
 
 func PodIsolation(router fiber.Router) {
 
+	router.Post("/isolate", func(c *fiber.Ctx) error {
+		details := new(agent_dto.IsolatePod)
+		if err := c.BodyParser(details); err != nil || details.Namespace == "" || details.Pod == "" {
+			return c.Status(fiber.StatusBadRequest).JSON("namespace and pod are required")
+		}
+
+		// Get the singleton Kubernetes clientset
+		clientset, err := k8sclient.GetClientset()
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
+		}
+
+		isolated, err := features.IsolatePod(clientset, details.Namespace, details.Pod, time.Duration(details.TTLSeconds)*time.Second)
+		if err != nil {
+			return c.Status(policyErrorStatus(err)).JSON(err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(isolated)
+	})
+
+	router.Get("/isolated", func(c *fiber.Ctx) error {
+
+		// Get the singleton Kubernetes clientset
+		clientset, err := k8sclient.GetClientset()
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
+		}
+
+		isolated, err := features.ListIsolatedPods(clientset)
+		if err != nil {
+			return c.Status(policyErrorStatus(err)).JSON(err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(isolated)
+	})
+
+	router.Post("/release", func(c *fiber.Ctx) error {
+		details := new(agent_dto.ReleasePod)
+		if err := c.BodyParser(details); err != nil || details.Namespace == "" || details.ID == "" {
+			return c.Status(fiber.StatusBadRequest).JSON("namespace and id are required")
+		}
+
+		// Get the singleton Kubernetes clientset
+		clientset, err := k8sclient.GetClientset()
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
+		}
+
+		if err := features.ReleasePod(clientset, details.Namespace, details.ID); err != nil {
+			return c.Status(policyErrorStatus(err)).JSON(err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON("")
+	})
 }
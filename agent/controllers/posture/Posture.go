@@ -0,0 +1,13 @@
+package posture
+
+import (
+	"github.com/FearLessSaad/SNFOK/agent/controllers/posture/routes"
+	"github.com/gofiber/fiber/v2"
+)
+
+func PostureController(router fiber.Router) {
+	routes.ScanWorkloads(router)
+	routes.AnalyzeRBAC(router)
+	routes.NetworkCoverage(router)
+	routes.ImageInventory(router)
+}
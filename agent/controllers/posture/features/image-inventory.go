@@ -0,0 +1,214 @@
+package features
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8scache"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8sclient"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ALLOWED_REGISTRIES_ENV holds the default comma separated registry allowlist, used when a request does not send one
+const ALLOWED_REGISTRIES_ENV = "ALLOWED_IMAGE_REGISTRIES"
+
+// mutableTags are tags that are expected to point to different images over time. The workload scan and the image
+// inventory both use isMutableImage, so an image gets the same verdict in either report.
+var mutableTags = map[string]bool{
+	"latest":  true,
+	"main":    true,
+	"master":  true,
+	"stable":  true,
+	"edge":    true,
+	"nightly": true,
+}
+
+// isMutableImage reports whether an image is referenced by one of the mutableTags, or by no tag at all, which
+// means latest. Images pinned to a digest are never mutable.
+func isMutableImage(image string) bool {
+	if strings.Contains(image, "@") {
+		return false
+	}
+	_, _, tag := parseImage(image)
+	return mutableTags[tag]
+}
+
+// AllowedRegistries parses a comma separated allowlist, falling back to $ALLOWED_IMAGE_REGISTRIES
+func AllowedRegistries(list string) []string {
+	if list == "" {
+		list = os.Getenv(ALLOWED_REGISTRIES_ENV)
+	}
+
+	allowed := []string{}
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			allowed = append(allowed, strings.TrimSuffix(entry, "/"))
+		}
+	}
+	return allowed
+}
+
+// GetImageInventory lists every unique image used by the containers of the pods in the cluster, with the
+// digests they resolved to and the workloads running them. An empty allowlist allows every registry.
+func GetImageInventory(resources *k8scache.Cache, allowed []string) (agent_dto.ImageInventory, error) {
+	pods, err := resources.Pods.List(labels.Everything())
+	if err != nil {
+		return agent_dto.ImageInventory{}, fmt.Errorf("failed to list pods: %v", err)
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		if pods[i].Namespace != pods[j].Namespace {
+			return pods[i].Namespace < pods[j].Namespace
+		}
+		return pods[i].Name < pods[j].Name
+	})
+
+	inventory := agent_dto.ImageInventory{
+		CollectedAt:       time.Now().UTC(),
+		AllowedRegistries: allowed,
+		Images:            []agent_dto.ImageInventoryItem{},
+	}
+	if cluster, err := k8sclient.GetClusterName(""); err == nil {
+		inventory.Cluster = cluster
+	}
+
+	items := make(map[string]*agent_dto.ImageInventoryItem)
+	for _, pod := range pods {
+		// Digests are reported per container name in the status
+		digests := make(map[string]string)
+		for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+			for _, status := range statuses {
+				digests[status.Name] = imageDigest(status.ImageID)
+			}
+		}
+
+		kind, name := podWorkload(pod)
+		containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+		for _, container := range containers {
+			item, exists := items[container.Image]
+			if !exists {
+				registry, repository, tag := parseImage(container.Image)
+				item = &agent_dto.ImageInventoryItem{
+					Image:           container.Image,
+					Registry:        registry,
+					Repository:      repository,
+					Tag:             tag,
+					Digests:         []string{},
+					PullPolicies:    []string{},
+					MutableTag:      isMutableImage(container.Image),
+					AllowedRegistry: isAllowedRegistry(registry, repository, allowed),
+					Workloads:       []agent_dto.ImageWorkload{},
+					Pods:            []agent_dto.ImagePod{},
+				}
+				items[container.Image] = item
+			}
+
+			digest := digests[container.Name]
+			if digest != "" && !containsString(item.Digests, digest) {
+				item.Digests = append(item.Digests, digest)
+			}
+			if policy := string(container.ImagePullPolicy); policy != "" && !containsString(item.PullPolicies, policy) {
+				item.PullPolicies = append(item.PullPolicies, policy)
+			}
+
+			workload := agent_dto.ImageWorkload{Namespace: pod.Namespace, Kind: kind, Name: name}
+			if !containsWorkload(item.Workloads, workload) {
+				item.Workloads = append(item.Workloads, workload)
+			}
+			item.Pods = append(item.Pods, agent_dto.ImagePod{
+				Namespace: pod.Namespace,
+				Pod:       pod.Name,
+				Container: container.Name,
+				Node:      pod.Spec.NodeName,
+				Digest:    digest,
+			})
+		}
+	}
+
+	for _, item := range items {
+		inventory.Images = append(inventory.Images, *item)
+	}
+	sort.Slice(inventory.Images, func(i, j int) bool { return inventory.Images[i].Image < inventory.Images[j].Image })
+
+	return inventory, nil
+}
+
+// parseImage splits an image reference into registry, repository and tag the way the container
+// runtime resolves it: Docker Hub is the default registry and "latest" the default tag.
+// The tag is empty when the image is pinned to a digest only.
+func parseImage(image string) (string, string, string) {
+	name, digest, _ := strings.Cut(image, "@")
+
+	tag := ""
+	slash := strings.LastIndex(name, "/")
+	if colon := strings.LastIndex(name, ":"); colon > slash {
+		name, tag = name[:colon], name[colon+1:]
+	}
+	if tag == "" && digest == "" {
+		tag = "latest"
+	}
+
+	registry := "docker.io"
+	if first, rest, found := strings.Cut(name, "/"); found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		registry, name = first, rest
+	}
+	if registry == "docker.io" && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+
+	return registry, name, tag
+}
+
+// imageDigest extracts the digest from a container status imageID, e.g.
+// docker-pullable://nginx@sha256:... or docker.io/library/nginx@sha256:...
+func imageDigest(imageID string) string {
+	if _, digest, found := strings.Cut(imageID, "@"); found {
+		return digest
+	}
+	if strings.HasPrefix(imageID, "sha256:") {
+		return imageID
+	}
+	return ""
+}
+
+// isAllowedRegistry matches a registry against the allowlist. Entries may be a registry (ghcr.io),
+// a wildcard subdomain (*.example.com) or a registry with a repository prefix (ghcr.io/my-org).
+func isAllowedRegistry(registry, repository string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	for _, entry := range allowed {
+		switch {
+		case entry == registry:
+			return true
+		case strings.HasPrefix(entry, "*.") && strings.HasSuffix(registry, entry[1:]):
+			return true
+		case strings.HasPrefix(registry+"/"+repository+"/", entry+"/"):
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsWorkload(workloads []agent_dto.ImageWorkload, workload agent_dto.ImageWorkload) bool {
+	for _, w := range workloads {
+		if w == workload {
+			return true
+		}
+	}
+	return false
+}
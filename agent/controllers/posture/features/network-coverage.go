@@ -0,0 +1,142 @@
+package features
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8scache"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8sclient"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// EvaluateNetworkCoverage evaluates every NetworkPolicy against the pods that are not finished yet
+// and reports, per pod, whether its ingress and egress are isolated and by which policies
+func EvaluateNetworkCoverage(resources *k8scache.Cache) (agent_dto.NetworkCoverage, error) {
+	namespaces, err := resources.Namespaces.List(labels.Everything())
+	if err != nil {
+		return agent_dto.NetworkCoverage{}, fmt.Errorf("failed to list namespaces: %v", err)
+	}
+	sort.Slice(namespaces, func(i, j int) bool { return namespaces[i].Name < namespaces[j].Name })
+
+	coverage := agent_dto.NetworkCoverage{
+		EvaluatedAt: time.Now().UTC(),
+		Namespaces:  []agent_dto.NamespaceNetworkCoverage{},
+		Pods:        []agent_dto.PodNetworkCoverage{},
+	}
+	if cluster, err := k8sclient.GetClusterName(""); err == nil {
+		coverage.Cluster = cluster
+	}
+
+	for _, namespace := range namespaces {
+		policies, err := resources.NetworkPolicies.NetworkPolicies(namespace.Name).List(labels.Everything())
+		if err != nil {
+			return agent_dto.NetworkCoverage{}, fmt.Errorf("failed to list network policies in namespace %s: %v", namespace.Name, err)
+		}
+		sort.Slice(policies, func(i, j int) bool { return policies[i].Name < policies[j].Name })
+
+		summary := agent_dto.NamespaceNetworkCoverage{
+			Namespace: namespace.Name,
+			Policies:  []string{},
+		}
+		for _, policy := range policies {
+			summary.Policies = append(summary.Policies, policy.Name)
+			ingress, egress := isDefaultDeny(policy)
+			summary.DefaultDenyIngress = summary.DefaultDenyIngress || ingress
+			summary.DefaultDenyEgress = summary.DefaultDenyEgress || egress
+		}
+		coverage.Namespaces = append(coverage.Namespaces, summary)
+
+		pods, err := resources.Pods.Pods(namespace.Name).List(labels.Everything())
+		if err != nil {
+			return agent_dto.NetworkCoverage{}, fmt.Errorf("failed to list pods in namespace %s: %v", namespace.Name, err)
+		}
+		sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+
+		for _, pod := range pods {
+			if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+				continue
+			}
+			coverage.Pods = append(coverage.Pods, podNetworkCoverage(pod, policies))
+		}
+	}
+
+	return coverage, nil
+}
+
+// podNetworkCoverage evaluates the policies of the pod's namespace against the pod
+func podNetworkCoverage(pod *corev1.Pod, policies []*networkingv1.NetworkPolicy) agent_dto.PodNetworkCoverage {
+	kind, name := podWorkload(pod)
+	result := agent_dto.PodNetworkCoverage{
+		Namespace:    pod.Namespace,
+		Pod:          pod.Name,
+		WorkloadKind: kind,
+		WorkloadName: name,
+		HostNetwork:  pod.Spec.HostNetwork,
+		Policies:     []string{},
+	}
+
+	for _, policy := range policies {
+		selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.PodSelector)
+		if err != nil || !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		result.Policies = append(result.Policies, policy.Name)
+
+		ingress, egress := policyTypes(policy)
+		result.IngressIsolated = result.IngressIsolated || ingress
+		result.EgressIsolated = result.EgressIsolated || egress
+	}
+
+	return result
+}
+
+// policyTypes reports which directions a policy isolates. Without explicit policyTypes, a policy
+// always isolates ingress and isolates egress only when it has egress rules.
+func policyTypes(policy *networkingv1.NetworkPolicy) (bool, bool) {
+	if len(policy.Spec.PolicyTypes) == 0 {
+		return true, len(policy.Spec.Egress) > 0
+	}
+
+	var ingress, egress bool
+	for _, policyType := range policy.Spec.PolicyTypes {
+		switch policyType {
+		case networkingv1.PolicyTypeIngress:
+			ingress = true
+		case networkingv1.PolicyTypeEgress:
+			egress = true
+		}
+	}
+	return ingress, egress
+}
+
+// isDefaultDeny reports whether a policy selects every pod of its namespace and allows no traffic
+// in the isolated directions
+func isDefaultDeny(policy *networkingv1.NetworkPolicy) (bool, bool) {
+	if len(policy.Spec.PodSelector.MatchLabels) != 0 || len(policy.Spec.PodSelector.MatchExpressions) != 0 {
+		return false, false
+	}
+
+	ingress, egress := policyTypes(policy)
+	return ingress && len(policy.Spec.Ingress) == 0, egress && len(policy.Spec.Egress) == 0
+}
+
+// podWorkload resolves the workload owning a pod. Pods of a Deployment are owned by a ReplicaSet
+// named after the Deployment followed by the pod-template-hash.
+func podWorkload(pod *corev1.Pod) (string, string) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return "Pod", pod.Name
+	}
+	if owner.Kind == "ReplicaSet" {
+		if hash, exists := pod.Labels["pod-template-hash"]; exists && strings.HasSuffix(owner.Name, "-"+hash) {
+			return "Deployment", strings.TrimSuffix(owner.Name, "-"+hash)
+		}
+	}
+	return owner.Kind, owner.Name
+}
@@ -0,0 +1,389 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8scache"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8sclient"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// RBAC risk check identifiers
+const (
+	CHECK_CLUSTER_ADMIN              = "CLUSTER_ADMIN"
+	CHECK_WILDCARD_VERBS             = "WILDCARD_VERBS"
+	CHECK_WILDCARD_RESOURCES         = "WILDCARD_RESOURCES"
+	CHECK_SECRETS_READ               = "SECRETS_READ"
+	CHECK_POD_EXEC                   = "POD_EXEC"
+	CHECK_PRIVILEGE_ESCALATION_VERBS = "ESCALATE_BIND_IMPERSONATE"
+	CHECK_NODES_PROXY                = "NODES_PROXY"
+)
+
+// severityRank orders severities so the highest one of a set can be picked
+var severityRank = map[string]int{
+	agent_dto.SEVERITY_LOW:      1,
+	agent_dto.SEVERITY_MEDIUM:   2,
+	agent_dto.SEVERITY_HIGH:     3,
+	agent_dto.SEVERITY_CRITICAL: 4,
+}
+
+// rbacRole is a role with the rules needed to evaluate access
+type rbacRole struct {
+	info  agent_dto.RBACRole
+	rules []rbacv1.PolicyRule
+}
+
+// rbacState holds every role, indexed by kind, namespace and name, and every grant
+type rbacState struct {
+	roles  map[string]*rbacRole
+	grants []agent_dto.RBACGrant
+}
+
+func roleKey(kind, namespace, name string) string {
+	if kind == "ClusterRole" {
+		namespace = ""
+	}
+	return kind + "/" + namespace + "/" + name
+}
+
+// loadRBAC reads all Roles, ClusterRoles and their bindings from the API server
+func loadRBAC(clientset *kubernetes.Clientset) (*rbacState, error) {
+	ctx := context.TODO()
+	state := &rbacState{roles: make(map[string]*rbacRole)}
+
+	clusterRoles, err := clientset.RbacV1().ClusterRoles().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clusterroles: %v", err)
+	}
+	for _, role := range clusterRoles.Items {
+		state.addRole("ClusterRole", "", role.Name, role.Labels, role.Rules)
+	}
+
+	roles, err := clientset.RbacV1().Roles("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %v", err)
+	}
+	for _, role := range roles.Items {
+		state.addRole("Role", role.Namespace, role.Name, role.Labels, role.Rules)
+	}
+
+	clusterBindings, err := clientset.RbacV1().ClusterRoleBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clusterrolebindings: %v", err)
+	}
+	for _, binding := range clusterBindings.Items {
+		state.addGrants("ClusterRoleBinding", "", binding.Name, binding.RoleRef, binding.Subjects)
+	}
+
+	bindings, err := clientset.RbacV1().RoleBindings("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rolebindings: %v", err)
+	}
+	for _, binding := range bindings.Items {
+		state.addGrants("RoleBinding", binding.Namespace, binding.Name, binding.RoleRef, binding.Subjects)
+	}
+
+	return state, nil
+}
+
+func (s *rbacState) addRole(kind, namespace, name string, role_labels map[string]string, rules []rbacv1.PolicyRule) {
+	info := agent_dto.RBACRole{
+		Kind:      kind,
+		Namespace: namespace,
+		Name:      name,
+		Builtin:   role_labels["kubernetes.io/bootstrapping"] == "rbac-defaults" || strings.HasPrefix(name, "system:"),
+		Risks:     []agent_dto.RBACRisk{},
+	}
+
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		for _, risk := range ruleRisks(rule) {
+			if seen[risk.Check] {
+				continue
+			}
+			seen[risk.Check] = true
+			info.Risks = append(info.Risks, risk)
+		}
+	}
+
+	s.roles[roleKey(kind, namespace, name)] = &rbacRole{info: info, rules: rules}
+}
+
+func (s *rbacState) addGrants(kind, namespace, name string, ref rbacv1.RoleRef, subjects []rbacv1.Subject) {
+	for _, subject := range subjects {
+		subjectNamespace := subject.Namespace
+		if subject.Kind != rbacv1.ServiceAccountKind {
+			subjectNamespace = ""
+		}
+		s.grants = append(s.grants, agent_dto.RBACGrant{
+			Subject: agent_dto.RBACSubject{
+				Kind:      subject.Kind,
+				Name:      subject.Name,
+				Namespace: subjectNamespace,
+			},
+			BindingKind: kind,
+			BindingName: name,
+			RoleKind:    ref.Kind,
+			RoleName:    ref.Name,
+			Namespace:   namespace,
+		})
+	}
+}
+
+// role returns the role a grant refers to. Bindings may refer to roles that do not exist.
+func (s *rbacState) role(grant agent_dto.RBACGrant) (*rbacRole, bool) {
+	role, exists := s.roles[roleKey(grant.RoleKind, grant.Namespace, grant.RoleName)]
+	return role, exists
+}
+
+// AnalyzeRBAC flags risky rules in every role and maps the grants to the ServiceAccounts of running pods
+func AnalyzeRBAC(clientset *kubernetes.Clientset, resources *k8scache.Cache) (agent_dto.RBACAnalysis, error) {
+	state, err := loadRBAC(clientset)
+	if err != nil {
+		return agent_dto.RBACAnalysis{}, err
+	}
+
+	analysis := agent_dto.RBACAnalysis{
+		AnalyzedAt:      time.Now().UTC(),
+		Roles:           []agent_dto.RBACRole{},
+		Grants:          state.grants,
+		ServiceAccounts: []agent_dto.ServiceAccountPrivileges{},
+	}
+	if cluster, err := k8sclient.GetClusterName(""); err == nil {
+		analysis.Cluster = cluster
+	}
+
+	for _, role := range state.roles {
+		analysis.Roles = append(analysis.Roles, role.info)
+	}
+	sort.Slice(analysis.Roles, func(i, j int) bool {
+		return roleKey(analysis.Roles[i].Kind, analysis.Roles[i].Namespace, analysis.Roles[i].Name) <
+			roleKey(analysis.Roles[j].Kind, analysis.Roles[j].Namespace, analysis.Roles[j].Name)
+	})
+
+	pods, err := resources.Pods.List(labels.Everything())
+	if err != nil {
+		return agent_dto.RBACAnalysis{}, fmt.Errorf("failed to list pods: %v", err)
+	}
+
+	accounts := make(map[string]*agent_dto.ServiceAccountPrivileges)
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		name := pod.Spec.ServiceAccountName
+		if name == "" {
+			name = "default"
+		}
+		key := pod.Namespace + "/" + name
+		if _, exists := accounts[key]; !exists {
+			accounts[key] = &agent_dto.ServiceAccountPrivileges{
+				Namespace: pod.Namespace,
+				Name:      name,
+				Pods:      []string{},
+				Grants:    []agent_dto.RBACGrant{},
+				Risks:     []agent_dto.RBACRisk{},
+			}
+		}
+		accounts[key].Pods = append(accounts[key].Pods, pod.Name)
+	}
+
+	for _, account := range accounts {
+		sort.Strings(account.Pods)
+		seen := make(map[string]bool)
+		for _, grant := range state.grants {
+			if !grantsServiceAccount(grant.Subject, account.Namespace, account.Name) {
+				continue
+			}
+			account.Grants = append(account.Grants, grant)
+
+			role, exists := state.role(grant)
+			if !exists {
+				continue
+			}
+			for _, risk := range role.info.Risks {
+				if seen[risk.Check] {
+					continue
+				}
+				seen[risk.Check] = true
+				risk.Message = fmt.Sprintf("%s %s: %s", grant.RoleKind, grant.RoleName, risk.Message)
+				account.Risks = append(account.Risks, risk)
+				if severityRank[risk.Severity] > severityRank[account.Severity] {
+					account.Severity = risk.Severity
+				}
+			}
+		}
+		analysis.ServiceAccounts = append(analysis.ServiceAccounts, *account)
+	}
+	sort.Slice(analysis.ServiceAccounts, func(i, j int) bool {
+		if analysis.ServiceAccounts[i].Namespace != analysis.ServiceAccounts[j].Namespace {
+			return analysis.ServiceAccounts[i].Namespace < analysis.ServiceAccounts[j].Namespace
+		}
+		return analysis.ServiceAccounts[i].Name < analysis.ServiceAccounts[j].Name
+	})
+
+	return analysis, nil
+}
+
+// WhoCan returns the grants allowing verb on resource. resource may name a subresource, e.g. pods/exec.
+// An empty group matches every API group; an empty namespace matches grants in every namespace.
+func WhoCan(clientset *kubernetes.Clientset, verb, resource, group, namespace string) ([]agent_dto.RBACGrant, error) {
+	if verb == "" || resource == "" {
+		return nil, fmt.Errorf("verb and resource are required")
+	}
+
+	state, err := loadRBAC(clientset)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := []agent_dto.RBACGrant{}
+	for _, grant := range state.grants {
+		if namespace != "" && grant.Namespace != "" && grant.Namespace != namespace {
+			continue
+		}
+		role, exists := state.role(grant)
+		if !exists {
+			continue
+		}
+		for _, rule := range role.rules {
+			if ruleAllows(rule, verb, group, resource) {
+				allowed = append(allowed, grant)
+				break
+			}
+		}
+	}
+	return allowed, nil
+}
+
+// grantsServiceAccount reports whether a subject covers the ServiceAccount, directly or through its groups
+func grantsServiceAccount(subject agent_dto.RBACSubject, namespace, name string) bool {
+	switch subject.Kind {
+	case rbacv1.ServiceAccountKind:
+		return subject.Name == name && subject.Namespace == namespace
+	case rbacv1.GroupKind:
+		return subject.Name == "system:serviceaccounts" ||
+			subject.Name == "system:serviceaccounts:"+namespace ||
+			subject.Name == "system:authenticated"
+	case rbacv1.UserKind:
+		return subject.Name == "system:serviceaccount:"+namespace+":"+name
+	}
+	return false
+}
+
+// ruleAllows evaluates a rule the way the RBAC authorizer does for resource requests
+func ruleAllows(rule rbacv1.PolicyRule, verb, group, resource string) bool {
+	if !contains(rule.Verbs, verb) {
+		return false
+	}
+	if group != "" && !contains(rule.APIGroups, group) {
+		return false
+	}
+
+	_, subresource, hasSubresource := strings.Cut(resource, "/")
+	for _, r := range rule.Resources {
+		if r == rbacv1.ResourceAll || r == resource {
+			return true
+		}
+		if hasSubresource && r == "*/"+subresource {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether values contains value or the RBAC wildcard
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == "*" || v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleRisks returns the risky permissions granted by a single rule
+func ruleRisks(rule rbacv1.PolicyRule) []agent_dto.RBACRisk {
+	var risks []agent_dto.RBACRisk
+	add := func(check, severity, title, message string) {
+		risks = append(risks, agent_dto.RBACRisk{Check: check, Severity: severity, Title: title, Message: message})
+	}
+
+	wildcardVerbs := containsExactly(rule.Verbs, rbacv1.VerbAll)
+	wildcardResources := containsExactly(rule.Resources, rbacv1.ResourceAll)
+	wildcardGroups := containsExactly(rule.APIGroups, rbacv1.APIGroupAll)
+
+	if wildcardVerbs && wildcardResources && wildcardGroups {
+		add(CHECK_CLUSTER_ADMIN, agent_dto.SEVERITY_CRITICAL, "Full access to all resources",
+			"grants every verb on every resource of every API group")
+		return risks
+	}
+	if wildcardVerbs {
+		add(CHECK_WILDCARD_VERBS, agent_dto.SEVERITY_HIGH, "Wildcard verbs",
+			fmt.Sprintf("grants every verb on %s", strings.Join(rule.Resources, ", ")))
+	}
+	if wildcardResources {
+		add(CHECK_WILDCARD_RESOURCES, agent_dto.SEVERITY_HIGH, "Wildcard resources",
+			fmt.Sprintf("grants %s on every resource of API groups %q", strings.Join(rule.Verbs, ", "), rule.APIGroups))
+	}
+
+	coreGroup := contains(rule.APIGroups, "")
+	if coreGroup {
+		for _, verb := range []string{"get", "list", "watch"} {
+			if ruleAllows(rule, verb, "", "secrets") {
+				add(CHECK_SECRETS_READ, agent_dto.SEVERITY_HIGH, "Secrets readable",
+					fmt.Sprintf("grants %s on secrets", verb))
+				break
+			}
+		}
+		for _, subresource := range []string{"pods/exec", "pods/attach"} {
+			if ruleAllows(rule, "create", "", subresource) || ruleAllows(rule, "get", "", subresource) {
+				add(CHECK_POD_EXEC, agent_dto.SEVERITY_HIGH, "Command execution in pods",
+					fmt.Sprintf("grants access to %s", subresource))
+				break
+			}
+		}
+		if ruleAllows(rule, "get", "", "nodes/proxy") || ruleAllows(rule, "create", "", "nodes/proxy") {
+			add(CHECK_NODES_PROXY, agent_dto.SEVERITY_CRITICAL, "Kubelet API access through nodes/proxy",
+				"grants access to nodes/proxy, which reaches the kubelet API of every node")
+		}
+	}
+
+	escalation := []struct{ verb, group, resource string }{
+		{"escalate", rbacv1.GroupName, "roles"},
+		{"escalate", rbacv1.GroupName, "clusterroles"},
+		{"bind", rbacv1.GroupName, "roles"},
+		{"bind", rbacv1.GroupName, "clusterroles"},
+		{"impersonate", "", "users"},
+		{"impersonate", "", "groups"},
+		{"impersonate", "", "serviceaccounts"},
+	}
+	for _, e := range escalation {
+		if ruleAllows(rule, e.verb, e.group, e.resource) {
+			add(CHECK_PRIVILEGE_ESCALATION_VERBS, agent_dto.SEVERITY_CRITICAL, "Privilege escalation verbs",
+				fmt.Sprintf("grants %s on %s", e.verb, e.resource))
+			break
+		}
+	}
+
+	return risks
+}
+
+// containsExactly reports whether values contains value itself, without wildcard matching
+func containsExactly(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
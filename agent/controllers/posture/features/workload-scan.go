@@ -0,0 +1,314 @@
+package features
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8scache"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8sclient"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// postureCheck describes a misconfiguration the scanner looks for
+type postureCheck struct {
+	severity    string
+	title       string
+	remediation string
+}
+
+// Posture check identifiers
+const (
+	CHECK_PRIVILEGED_CONTAINER       = "PRIVILEGED_CONTAINER"
+	CHECK_HOST_PID                   = "HOST_PID"
+	CHECK_HOST_NETWORK               = "HOST_NETWORK"
+	CHECK_HOST_IPC                   = "HOST_IPC"
+	CHECK_HOST_PATH_MOUNT            = "HOST_PATH_MOUNT"
+	CHECK_RUNS_AS_ROOT               = "RUNS_AS_ROOT"
+	CHECK_ADDED_CAPABILITIES         = "ADDED_CAPABILITIES"
+	CHECK_PRIVILEGE_ESCALATION       = "PRIVILEGE_ESCALATION"
+	CHECK_WRITABLE_ROOT_FILESYSTEM   = "WRITABLE_ROOT_FILESYSTEM"
+	CHECK_MISSING_RESOURCE_LIMITS    = "MISSING_RESOURCE_LIMITS"
+	CHECK_MUTABLE_IMAGE_TAG          = "MUTABLE_IMAGE_TAG"
+	CHECK_AUTOMOUNTED_SERVICEACCOUNT = "AUTOMOUNTED_SERVICE_ACCOUNT_TOKEN"
+)
+
+var postureChecks = map[string]postureCheck{
+	CHECK_PRIVILEGED_CONTAINER: {
+		severity:    agent_dto.SEVERITY_CRITICAL,
+		title:       "Privileged container",
+		remediation: "Remove securityContext.privileged or set it to false. Grant only the specific capabilities the container needs.",
+	},
+	CHECK_HOST_PID: {
+		severity:    agent_dto.SEVERITY_HIGH,
+		title:       "Host PID namespace shared",
+		remediation: "Set spec.hostPID to false so the pod cannot see or signal host processes.",
+	},
+	CHECK_HOST_NETWORK: {
+		severity:    agent_dto.SEVERITY_HIGH,
+		title:       "Host network namespace shared",
+		remediation: "Set spec.hostNetwork to false and expose the pod through a Service instead.",
+	},
+	CHECK_HOST_IPC: {
+		severity:    agent_dto.SEVERITY_HIGH,
+		title:       "Host IPC namespace shared",
+		remediation: "Set spec.hostIPC to false.",
+	},
+	CHECK_HOST_PATH_MOUNT: {
+		severity:    agent_dto.SEVERITY_HIGH,
+		title:       "hostPath volume mounted",
+		remediation: "Replace the hostPath volume with a PersistentVolumeClaim, emptyDir or ConfigMap. If it is required, mount it read-only.",
+	},
+	CHECK_RUNS_AS_ROOT: {
+		severity:    agent_dto.SEVERITY_MEDIUM,
+		title:       "Container may run as root",
+		remediation: "Set securityContext.runAsNonRoot to true and runAsUser to a non-zero UID.",
+	},
+	CHECK_ADDED_CAPABILITIES: {
+		severity:    agent_dto.SEVERITY_HIGH,
+		title:       "Linux capabilities added",
+		remediation: "Drop ALL capabilities and add back only those that are strictly required, avoiding SYS_ADMIN, NET_ADMIN and SYS_PTRACE.",
+	},
+	CHECK_PRIVILEGE_ESCALATION: {
+		severity:    agent_dto.SEVERITY_MEDIUM,
+		title:       "Privilege escalation allowed",
+		remediation: "Set securityContext.allowPrivilegeEscalation to false.",
+	},
+	CHECK_WRITABLE_ROOT_FILESYSTEM: {
+		severity:    agent_dto.SEVERITY_LOW,
+		title:       "Writable root filesystem",
+		remediation: "Set securityContext.readOnlyRootFilesystem to true and mount an emptyDir for paths that must be writable.",
+	},
+	CHECK_MISSING_RESOURCE_LIMITS: {
+		severity:    agent_dto.SEVERITY_LOW,
+		title:       "Missing resource limits",
+		remediation: "Set resources.limits.cpu and resources.limits.memory so a compromised container cannot exhaust the node.",
+	},
+	CHECK_MUTABLE_IMAGE_TAG: {
+		severity:    agent_dto.SEVERITY_MEDIUM,
+		title:       "Mutable image tag",
+		remediation: "Pin the image to a specific version tag or, preferably, to its digest (image@sha256:...).",
+	},
+	CHECK_AUTOMOUNTED_SERVICEACCOUNT: {
+		severity:    agent_dto.SEVERITY_LOW,
+		title:       "Service account token automounted",
+		remediation: "Set automountServiceAccountToken to false on the pod or its ServiceAccount unless the workload calls the Kubernetes API.",
+	},
+}
+
+// dangerousCapabilities are escalated to CRITICAL when added to a container
+var dangerousCapabilities = map[string]bool{
+	"ALL":        true,
+	"SYS_ADMIN":  true,
+	"SYS_PTRACE": true,
+	"SYS_MODULE": true,
+	"NET_ADMIN":  true,
+	"BPF":        true,
+}
+
+// workload is a pod template owned by a scanned workload
+type workload struct {
+	kind      string
+	namespace string
+	name      string
+	spec      *corev1.PodSpec
+}
+
+// ScanWorkloads inspects the pod template of every Deployment, StatefulSet and DaemonSet,
+// and every pod that is not managed by one of them, and reports the misconfigurations found
+func ScanWorkloads(resources *k8scache.Cache) (agent_dto.PostureScan, error) {
+	workloads, err := listWorkloads(resources)
+	if err != nil {
+		return agent_dto.PostureScan{}, err
+	}
+
+	scan := agent_dto.PostureScan{
+		ScannedAt: time.Now().UTC(),
+		Workloads: len(workloads),
+		Findings:  []agent_dto.PostureFinding{},
+	}
+	if cluster, err := k8sclient.GetClusterName(""); err == nil {
+		scan.Cluster = cluster
+	}
+
+	for _, w := range workloads {
+		scan.Findings = append(scan.Findings, scanPodSpec(w)...)
+	}
+
+	return scan, nil
+}
+
+// listWorkloads collects the pod templates to scan, sorted by namespace, kind and name
+func listWorkloads(resources *k8scache.Cache) ([]workload, error) {
+	var workloads []workload
+
+	deployments, err := resources.Deployments.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %v", err)
+	}
+	for _, dep := range deployments {
+		workloads = append(workloads, workload{"Deployment", dep.Namespace, dep.Name, &dep.Spec.Template.Spec})
+	}
+
+	statefulSets, err := resources.StatefulSets.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets: %v", err)
+	}
+	for _, sts := range statefulSets {
+		workloads = append(workloads, workload{"StatefulSet", sts.Namespace, sts.Name, &sts.Spec.Template.Spec})
+	}
+
+	daemonSets, err := resources.DaemonSets.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list daemonsets: %v", err)
+	}
+	for _, ds := range daemonSets {
+		workloads = append(workloads, workload{"DaemonSet", ds.Namespace, ds.Name, &ds.Spec.Template.Spec})
+	}
+
+	// Pods of the workloads above are already covered by their template
+	pods, err := resources.Pods.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %v", err)
+	}
+	for _, pod := range pods {
+		if owner := metav1.GetControllerOf(pod); owner != nil {
+			switch owner.Kind {
+			case "ReplicaSet", "StatefulSet", "DaemonSet":
+				continue
+			}
+		}
+		workloads = append(workloads, workload{"Pod", pod.Namespace, pod.Name, &pod.Spec})
+	}
+
+	sort.Slice(workloads, func(i, j int) bool {
+		if workloads[i].namespace != workloads[j].namespace {
+			return workloads[i].namespace < workloads[j].namespace
+		}
+		if workloads[i].kind != workloads[j].kind {
+			return workloads[i].kind < workloads[j].kind
+		}
+		return workloads[i].name < workloads[j].name
+	})
+
+	return workloads, nil
+}
+
+// scanPodSpec runs every check against a pod template
+func scanPodSpec(w workload) []agent_dto.PostureFinding {
+	var findings []agent_dto.PostureFinding
+	report := func(check string, container string, message string) {
+		findings = append(findings, newFinding(w, check, container, message))
+	}
+
+	spec := w.spec
+	if spec.HostPID {
+		report(CHECK_HOST_PID, "", "spec.hostPID is true")
+	}
+	if spec.HostNetwork {
+		report(CHECK_HOST_NETWORK, "", "spec.hostNetwork is true")
+	}
+	if spec.HostIPC {
+		report(CHECK_HOST_IPC, "", "spec.hostIPC is true")
+	}
+	for _, volume := range spec.Volumes {
+		if volume.HostPath != nil {
+			report(CHECK_HOST_PATH_MOUNT, "", fmt.Sprintf("volume %q mounts host path %s", volume.Name, volume.HostPath.Path))
+		}
+	}
+	if spec.AutomountServiceAccountToken == nil || *spec.AutomountServiceAccountToken {
+		report(CHECK_AUTOMOUNTED_SERVICEACCOUNT, "", "automountServiceAccountToken is not disabled on the pod")
+	}
+
+	podContext := spec.SecurityContext
+	if podContext == nil {
+		podContext = &corev1.PodSecurityContext{}
+	}
+
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, container := range containers {
+		sc := container.SecurityContext
+		if sc == nil {
+			sc = &corev1.SecurityContext{}
+		}
+
+		privileged := sc.Privileged != nil && *sc.Privileged
+		if privileged {
+			report(CHECK_PRIVILEGED_CONTAINER, container.Name, "securityContext.privileged is true")
+		}
+
+		// The container settings override the pod settings
+		runAsNonRoot := podContext.RunAsNonRoot
+		if sc.RunAsNonRoot != nil {
+			runAsNonRoot = sc.RunAsNonRoot
+		}
+		runAsUser := podContext.RunAsUser
+		if sc.RunAsUser != nil {
+			runAsUser = sc.RunAsUser
+		}
+		if runAsUser != nil && *runAsUser == 0 {
+			report(CHECK_RUNS_AS_ROOT, container.Name, "runAsUser is 0")
+		} else if runAsUser == nil && (runAsNonRoot == nil || !*runAsNonRoot) {
+			report(CHECK_RUNS_AS_ROOT, container.Name, "neither runAsNonRoot nor a non-zero runAsUser is set")
+		}
+
+		if sc.Capabilities != nil && len(sc.Capabilities.Add) > 0 {
+			var added []string
+			for _, capability := range sc.Capabilities.Add {
+				added = append(added, string(capability))
+			}
+			finding := newFinding(w, CHECK_ADDED_CAPABILITIES, container.Name, "adds capabilities "+strings.Join(added, ", "))
+			for _, capability := range added {
+				if dangerousCapabilities[strings.TrimPrefix(strings.ToUpper(capability), "CAP_")] {
+					finding.Severity = agent_dto.SEVERITY_CRITICAL
+				}
+			}
+			findings = append(findings, finding)
+		}
+
+		// Privileged containers can always escalate, which is already reported above
+		if !privileged && (sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation) {
+			report(CHECK_PRIVILEGE_ESCALATION, container.Name, "allowPrivilegeEscalation is not set to false")
+		}
+
+		if sc.ReadOnlyRootFilesystem == nil || !*sc.ReadOnlyRootFilesystem {
+			report(CHECK_WRITABLE_ROOT_FILESYSTEM, container.Name, "readOnlyRootFilesystem is not set to true")
+		}
+
+		var missing []string
+		for _, resource := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			if _, exists := container.Resources.Limits[resource]; !exists {
+				missing = append(missing, string(resource))
+			}
+		}
+		if len(missing) > 0 {
+			report(CHECK_MISSING_RESOURCE_LIMITS, container.Name, "no "+strings.Join(missing, " and ")+" limit")
+		}
+
+		if isMutableImage(container.Image) {
+			report(CHECK_MUTABLE_IMAGE_TAG, container.Name, fmt.Sprintf("image %s uses a mutable tag", container.Image))
+		}
+	}
+
+	return findings
+}
+
+// newFinding creates a finding of a check for a workload
+func newFinding(w workload, check string, container string, message string) agent_dto.PostureFinding {
+	definition := postureChecks[check]
+	return agent_dto.PostureFinding{
+		Check:       check,
+		Severity:    definition.severity,
+		Title:       definition.title,
+		Kind:        w.kind,
+		Namespace:   w.namespace,
+		Name:        w.name,
+		Container:   container,
+		Message:     message,
+		Remediation: definition.remediation,
+	}
+}
@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/FearLessSaad/SNFOK/agent/controllers/posture/features"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8scache"
+	"github.com/gofiber/fiber/v2"
+)
+
+func ImageInventory(router fiber.Router) {
+
+	// Query: allowed_registries, a comma separated registry allowlist
+	router.Get("/images", func(c *fiber.Ctx) error {
+
+		// Get the shared resource cache
+		resources, err := k8scache.GetCache()
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
+		}
+
+		inventory, err := features.GetImageInventory(resources, features.AllowedRegistries(c.Query("allowed_registries")))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(inventory)
+	})
+}
@@ -0,0 +1,50 @@
+package routes
+
+import (
+	"github.com/FearLessSaad/SNFOK/agent/controllers/posture/features"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8scache"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8sclient"
+	"github.com/gofiber/fiber/v2"
+)
+
+func AnalyzeRBAC(router fiber.Router) {
+
+	router.Get("/rbac/analysis", func(c *fiber.Ctx) error {
+
+		// Get the singleton Kubernetes clientset
+		clientset, err := k8sclient.GetClientset()
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
+		}
+
+		// Get the shared resource cache
+		resources, err := k8scache.GetCache()
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
+		}
+
+		analysis, err := features.AnalyzeRBAC(clientset, resources)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(analysis)
+	})
+
+	// Query: verb, resource (e.g. secrets or pods/exec), group and namespace
+	router.Get("/rbac/who-can", func(c *fiber.Ctx) error {
+
+		// Get the singleton Kubernetes clientset
+		clientset, err := k8sclient.GetClientset()
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
+		}
+
+		grants, err := features.WhoCan(clientset, c.Query("verb"), c.Query("resource"), c.Query("group"), c.Query("namespace"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(grants)
+	})
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/FearLessSaad/SNFOK/agent/controllers/posture/features"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8scache"
+	"github.com/gofiber/fiber/v2"
+)
+
+func ScanWorkloads(router fiber.Router) {
+
+	router.Get("/workloads/scan", func(c *fiber.Ctx) error {
+
+		// Get the shared resource cache
+		resources, err := k8scache.GetCache()
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(err.Error())
+		}
+
+		scan, err := features.ScanWorkloads(resources)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(scan)
+	})
+}
@@ -2,10 +2,19 @@ package main
 
 import (
 	"encoding/json"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/FearLessSaad/SNFOK/agent/controllers/health"
 	"github.com/FearLessSaad/SNFOK/agent/controllers/kubernetes"
 	"github.com/FearLessSaad/SNFOK/agent/controllers/policies"
+	policy_features "github.com/FearLessSaad/SNFOK/agent/controllers/policies/features"
+	"github.com/FearLessSaad/SNFOK/agent/controllers/posture"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8scache"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8sclient"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/helmet"
@@ -13,6 +22,23 @@ import (
 
 func main() {
 
+	// Reload Kubernetes credentials on SIGHUP, e.g. after a kubeconfig rotation
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+	go func() {
+		for range reloadChan {
+			log.Println("Reloading Kubernetes client configuration")
+			k8sclient.Reload()
+			k8scache.Stop()
+		}
+	}()
+
+	// Warm up the resource cache so the first dashboard request does not wait for the initial List
+	go k8scache.GetCache()
+
+	// Release isolated pods whose TTL has expired
+	go policy_features.RunIsolationReaper(30 * time.Second)
+
 	app := fiber.New(fiber.Config{
 		AppName:      "HashX SNFOK AGENT API",
 		ServerHeader: "HashX SNFOK AGENT",
@@ -34,5 +60,6 @@ func main() {
 	health.HealthController(api.Group("/health"))
 	kubernetes.KubernetesController(api.Group("/kubernetes"))
 	policies.PoliciesController(api.Group("/policies"))
+	posture.PostureController(api.Group("/posture"))
 	app.Listen("0.0.0.0:8990")
 }
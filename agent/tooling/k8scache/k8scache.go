@@ -0,0 +1,118 @@
+package k8scache
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/FearLessSaad/SNFOK/agent/tooling/k8sclient"
+	"k8s.io/client-go/informers"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	networkinglisters "k8s.io/client-go/listers/networking/v1"
+)
+
+const (
+	// resyncPeriod is how often the informers replay their full state to handlers
+	resyncPeriod = 10 * time.Minute
+	// syncTimeout bounds how long the first call waits for the initial List of every resource
+	syncTimeout = 2 * time.Minute
+)
+
+// Cache serves pods, namespaces, nodes, services, workloads and network policies from shared informers
+// so that requests do not hit the API server with fresh List calls
+type Cache struct {
+	factory informers.SharedInformerFactory
+	stop    chan struct{}
+
+	Pods         corelisters.PodLister
+	Namespaces   corelisters.NamespaceLister
+	Nodes        corelisters.NodeLister
+	Services     corelisters.ServiceLister
+	Deployments  appslisters.DeploymentLister
+	StatefulSets appslisters.StatefulSetLister
+	DaemonSets   appslisters.DaemonSetLister
+
+	NetworkPolicies networkinglisters.NetworkPolicyLister
+}
+
+// singleton instance, guarded by mu so a failed start can be retried
+var (
+	mu       sync.Mutex
+	instance *Cache
+)
+
+// GetCache returns the singleton resource cache.
+// On the first call it starts the informers and waits until they are synced.
+func GetCache() (*Cache, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if instance != nil {
+		return instance, nil
+	}
+
+	c, err := start()
+	if err != nil {
+		log.Printf("Failed to start Kubernetes resource cache: %v", err)
+		return nil, err
+	}
+	instance = c
+	return instance, nil
+}
+
+// Stop shuts down the informers. The next GetCache call starts a new cache.
+func Stop() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if instance != nil {
+		close(instance.stop)
+		instance.factory.Shutdown()
+		instance = nil
+	}
+}
+
+// start creates the informer factory, registers all cached resources and waits for the initial sync
+func start() (*Cache, error) {
+	clientset, err := k8sclient.GetClientset()
+	if err != nil {
+		return nil, err
+	}
+
+	factory := informers.NewSharedInformerFactory(clientset, resyncPeriod)
+	c := &Cache{
+		factory:      factory,
+		stop:         make(chan struct{}),
+		Pods:         factory.Core().V1().Pods().Lister(),
+		Namespaces:   factory.Core().V1().Namespaces().Lister(),
+		Nodes:        factory.Core().V1().Nodes().Lister(),
+		Services:     factory.Core().V1().Services().Lister(),
+		Deployments:  factory.Apps().V1().Deployments().Lister(),
+		StatefulSets: factory.Apps().V1().StatefulSets().Lister(),
+		DaemonSets:   factory.Apps().V1().DaemonSets().Lister(),
+
+		NetworkPolicies: factory.Networking().V1().NetworkPolicies().Lister(),
+	}
+
+	factory.Start(c.stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+	for informerType, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			close(c.stop)
+			factory.Shutdown()
+			return nil, fmt.Errorf("timed out waiting for %v informer to sync", informerType)
+		}
+	}
+
+	return c, nil
+}
+
+// Factory exposes the underlying informer factory, e.g. to register event handlers
+func (c *Cache) Factory() informers.SharedInformerFactory {
+	return c.factory
+}
@@ -1,99 +1,257 @@
 package k8sclient
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
 )
 
-// singleton instance and synchronization primitives
+// Environment variables used to configure the Kubernetes connection
+const (
+	KUBECONFIG_ENV   = "KUBECONFIG"      // Path (or path list) of kubeconfig files
+	KUBE_CONTEXT_ENV = "KUBE_CONTEXT"    // Kubeconfig context to use instead of current-context
+	IN_CLUSTER_ENV   = "KUBE_IN_CLUSTER" // "true" forces the service-account config, "false" disables it
+	CLUSTER_NAME_ENV = "CLUSTER_NAME"    // Cluster name reported by the agent
+)
+
+// clients holds the clients built from one configuration source
+type clients struct {
+	config    *rest.Config
+	clientset *kubernetes.Clientset
+	dynamic   *dynamic.DynamicClient
+	inCluster bool
+	files     map[string]time.Time // kubeconfig files and their modification times
+}
+
+// default instance and per-kubeconfig instances, guarded by mu
 var (
-	clientsetInstance *kubernetes.Clientset
-	once              sync.Once
-	initError         error
+	mu             sync.Mutex
+	defaultClients *clients
+	customClients  = map[string]*clients{}
 )
 
-// GetClientset returns the singleton Kubernetes clientset instance.
-// It initializes the clientset on the first call using the default kubeconfig path (~/.kube/config).
-// If initialization fails, it returns the error.
+// GetClientset returns the shared Kubernetes clientset instance.
+// The configuration is loaded from $KUBECONFIG, the in-cluster service account
+// or ~/.kube/config, in that order, and reloaded when the kubeconfig file changes.
 func GetClientset() (*kubernetes.Clientset, error) {
-	once.Do(func() {
-		// Build the Kubernetes client configuration
-		kubeconfig := filepath.Join(homedir.HomeDir(), ".kube", "config")
-		config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
-		if err != nil {
-			initError = err
-			log.Printf("Failed to load kubeconfig: %v", err)
-			return
-		}
+	c, err := getDefaultClients()
+	if err != nil {
+		return nil, err
+	}
+	return c.clientset, nil
+}
 
-		// Create a new Kubernetes clientset
-		clientsetInstance, err = kubernetes.NewForConfig(config)
+// GetClientsetWithConfig returns a Kubernetes clientset for a custom kubeconfig path.
+// Each path gets its own instance, independent of the default one.
+func GetClientsetWithConfig(kubeconfigPath string) (*kubernetes.Clientset, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	c, exists := customClients[kubeconfigPath]
+	if !exists || c.changed() {
+		var err error
+		c, err = newClients(kubeconfigPath)
 		if err != nil {
-			initError = err
-			log.Printf("Failed to create Kubernetes client: %v", err)
-			return
+			log.Printf("Failed to load kubeconfig from %s: %v", kubeconfigPath, err)
+			return nil, err
 		}
-	})
+		customClients[kubeconfigPath] = c
+	}
+	return c.clientset, nil
+}
 
-	if initError != nil {
-		return nil, initError
+// GetDynamicClient returns the shared dynamic client instance.
+// It is used for objects that are not part of the typed clientset, such as
+// Tetragon TracingPolicies and KubeArmor policies.
+func GetDynamicClient() (*dynamic.DynamicClient, error) {
+	c, err := getDefaultClients()
+	if err != nil {
+		return nil, err
 	}
-	if clientsetInstance == nil {
-		return nil, fmt.Errorf("clientset initialization failed")
+	return c.dynamic, nil
+}
+
+// GetRestConfig returns a copy of the REST config behind the shared clients
+func GetRestConfig() (*rest.Config, error) {
+	c, err := getDefaultClients()
+	if err != nil {
+		return nil, err
 	}
-	return clientsetInstance, nil
+	return rest.CopyConfig(c.config), nil
 }
 
-// GetClientsetWithConfig returns the singleton Kubernetes clientset instance,
-// initialized with a custom kubeconfig path.
-// It ensures the clientset is only initialized once, even with multiple calls.
-func GetClientsetWithConfig(kubeconfigPath string) (*kubernetes.Clientset, error) {
-	once.Do(func() {
-		// Build the Kubernetes client configuration
-		config, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+// IsInCluster reports whether the shared clients use the in-cluster service account
+func IsInCluster() bool {
+	c, err := getDefaultClients()
+	if err != nil {
+		return false
+	}
+	return c.inCluster
+}
+
+// Reload drops all cached clients so the next call rebuilds them from the current credentials
+func Reload() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	defaultClients = nil
+	customClients = map[string]*clients{}
+}
+
+// getDefaultClients returns the default clients, rebuilding them on first use or after a kubeconfig change
+func getDefaultClients() (*clients, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if defaultClients != nil && !defaultClients.changed() {
+		return defaultClients, nil
+	}
+
+	c, err := newClients("")
+	if err != nil {
+		log.Printf("Failed to create Kubernetes client: %v", err)
+		return nil, err
+	}
+	defaultClients = c
+	return defaultClients, nil
+}
+
+// newClients loads the REST config and creates the typed and dynamic clients for it
+func newClients(kubeconfigPath string) (*clients, error) {
+	config, files, err := loadConfig(kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes clientset: %w", err)
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes dynamic client: %w", err)
+	}
+
+	c := &clients{
+		config:    config,
+		clientset: clientset,
+		dynamic:   dynamicClient,
+		inCluster: len(files) == 0,
+		files:     map[string]time.Time{},
+	}
+	for _, file := range files {
+		c.files[file] = modTime(file)
+	}
+	return c, nil
+}
+
+// loadConfig builds the REST config and returns the kubeconfig files it was read from.
+// An empty file list means the in-cluster service account was used.
+func loadConfig(kubeconfigPath string) (*rest.Config, []string, error) {
+	inCluster := os.Getenv(IN_CLUSTER_ENV)
+
+	if kubeconfigPath == "" && inCluster == "true" {
+		config, err := rest.InClusterConfig()
 		if err != nil {
-			initError = err
-			log.Printf("Failed to load kubeconfig from %s: %v", kubeconfigPath, err)
-			return
+			return nil, nil, fmt.Errorf("failed to load in-cluster config: %w", err)
 		}
+		return config, nil, nil
+	}
 
-		// Create a new Kubernetes clientset
-		clientsetInstance, err = kubernetes.NewForConfig(config)
-		if err != nil {
-			initError = err
-			log.Printf("Failed to create Kubernetes client: %v", err)
-			return
+	rules := &clientcmd.ClientConfigLoadingRules{}
+	switch {
+	case kubeconfigPath != "":
+		rules.ExplicitPath = kubeconfigPath
+	case os.Getenv(KUBECONFIG_ENV) != "":
+		rules.Precedence = filepath.SplitList(os.Getenv(KUBECONFIG_ENV))
+	default:
+		// Service account tokens are re-read from disk by client-go, so rotation needs no reload here
+		if inCluster != "false" {
+			if config, err := rest.InClusterConfig(); err == nil {
+				return config, nil, nil
+			}
 		}
-	})
+		rules.ExplicitPath = filepath.Join(homedir.HomeDir(), ".kube", "config")
+	}
 
-	if initError != nil {
-		return nil, initError
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: os.Getenv(KUBE_CONTEXT_ENV)}
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load kubeconfig: %w", err)
 	}
-	if clientsetInstance == nil {
-		return nil, fmt.Errorf("clientset initialization failed")
+	return config, rules.GetLoadingPrecedence(), nil
+}
+
+// changed reports whether any kubeconfig file was modified since the clients were built
+func (c *clients) changed() bool {
+	for file, loaded := range c.files {
+		if !modTime(file).Equal(loaded) {
+			return true
+		}
 	}
-	return clientsetInstance, nil
+	return false
 }
 
+// modTime returns the modification time of a file, or the zero time if it does not exist
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// GetClusterName returns the name of the cluster the agent is connected to.
+// $CLUSTER_NAME takes precedence. In-cluster, the UID of the kube-system namespace is used,
+// otherwise the cluster of the selected kubeconfig context.
 func GetClusterName(kubeconfigPath string) (string, error) {
-	if kubeconfigPath == "" {
-		kubeconfigPath = filepath.Join(homedir.HomeDir(), ".kube", "config")
+	if name := os.Getenv(CLUSTER_NAME_ENV); name != "" {
+		return name, nil
+	}
+
+	if kubeconfigPath == "" && IsInCluster() {
+		clientset, err := GetClientset()
+		if err != nil {
+			return "", err
+		}
+
+		ns, err := clientset.CoreV1().Namespaces().Get(context.TODO(), "kube-system", metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("failed to get kube-system namespace: %w", err)
+		}
+		return string(ns.UID), nil
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeconfigPath != "" {
+		rules.ExplicitPath = kubeconfigPath
+	} else if os.Getenv(KUBECONFIG_ENV) == "" {
+		rules.ExplicitPath = filepath.Join(homedir.HomeDir(), ".kube", "config")
 	}
 
 	// Load the kubeconfig file
-	config, err := clientcmd.LoadFromFile(kubeconfigPath)
+	config, err := rules.Load()
 	if err != nil {
-		return "", fmt.Errorf("failed to load kubeconfig from %s: %w", kubeconfigPath, err)
+		return "", fmt.Errorf("failed to load kubeconfig: %w", err)
 	}
 
-	// Get the current context
-	currentContext := config.CurrentContext
+	// Get the selected context
+	currentContext := os.Getenv(KUBE_CONTEXT_ENV)
+	if currentContext == "" {
+		currentContext = config.CurrentContext
+	}
 	if currentContext == "" {
 		return "", fmt.Errorf("no current context found in kubeconfig")
 	}
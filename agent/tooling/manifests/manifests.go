@@ -0,0 +1,527 @@
+package manifests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/FearLessSaad/SNFOK/constants/agent_consts"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// FieldManager is the server-side apply field manager for every object the agent applies
+const FieldManager = "snfok-agent"
+
+// Resource describes how a supported policy kind is served by the API server
+type Resource struct {
+	Group             string
+	Version           string // Served version used when listing; applied objects keep their own apiVersion
+	Resource          string
+	Namespaced        bool
+	SelectorField     string // Field under spec that holds the pod selector
+	NamespaceSelector bool   // The selector matches namespaces instead of pod labels
+	ScopedKind        string // Equivalent kind with the other scope, used to move a policy between namespace and cluster scope
+}
+
+// supportedKinds lists the policy kinds the agent is allowed to apply
+var supportedKinds = map[string]Resource{
+	"TracingPolicy":           {Group: "cilium.io", Version: "v1alpha1", Resource: "tracingpolicies", Namespaced: false, SelectorField: "podSelector", ScopedKind: "TracingPolicyNamespaced"},
+	"TracingPolicyNamespaced": {Group: "cilium.io", Version: "v1alpha1", Resource: "tracingpoliciesnamespaced", Namespaced: true, SelectorField: "podSelector", ScopedKind: "TracingPolicy"},
+	"KubeArmorPolicy":         {Group: "security.kubearmor.com", Version: "v1", Resource: "kubearmorpolicies", Namespaced: true, SelectorField: "selector", ScopedKind: "KubeArmorClusterPolicy"},
+	"KubeArmorClusterPolicy":  {Group: "security.kubearmor.com", Version: "v1", Resource: "kubearmorclusterpolicies", Namespaced: false, SelectorField: "selector", NamespaceSelector: true, ScopedKind: "KubeArmorPolicy"},
+	"NetworkPolicy":           {Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies", Namespaced: true, SelectorField: "podSelector"},
+}
+
+// ResourceFor resolves the GroupVersionResource of an object and reports whether it is namespaced
+func ResourceFor(obj *unstructured.Unstructured) (schema.GroupVersionResource, bool, error) {
+	gvk := obj.GroupVersionKind()
+
+	res, exists := supportedKinds[gvk.Kind]
+	if !exists {
+		return schema.GroupVersionResource{}, false, fmt.Errorf("unsupported policy kind %q", gvk.Kind)
+	}
+	if res.Group != gvk.Group {
+		return schema.GroupVersionResource{}, false, fmt.Errorf("unsupported api group %q for kind %s", gvk.Group, gvk.Kind)
+	}
+
+	return gvk.GroupVersion().WithResource(res.Resource), res.Namespaced, nil
+}
+
+// Decode splits a (multi-document) YAML manifest into unstructured objects, skipping empty documents
+func Decode(content []byte) ([]*unstructured.Unstructured, error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(content), 4096)
+
+	var objects []*unstructured.Unstructured
+	for {
+		obj := &unstructured.Unstructured{}
+		err := decoder.Decode(&obj.Object)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode policy manifest: %v", err)
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		if obj.GetKind() == "" || obj.GetName() == "" {
+			return nil, fmt.Errorf("policy manifest document is missing kind or metadata.name")
+		}
+		objects = append(objects, obj)
+	}
+
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("policy manifest contains no objects")
+	}
+	return objects, nil
+}
+
+// Encode writes the objects as a multi-document YAML manifest
+func Encode(objects []*unstructured.Unstructured) ([]byte, error) {
+	var content bytes.Buffer
+	for i, obj := range objects {
+		document, err := sigsyaml.Marshal(obj.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s %q: %v", obj.GetKind(), obj.GetName(), err)
+		}
+		if i > 0 {
+			content.WriteString("---\n")
+		}
+		content.Write(document)
+	}
+	return content.Bytes(), nil
+}
+
+// SetSelector replaces the pod selector of a policy object.
+// KubeArmor only matches on labels, so selectors with expressions are rejected for its kinds.
+func SetSelector(obj *unstructured.Unstructured, selector *metav1.LabelSelector) error {
+	res, exists := supportedKinds[obj.GetKind()]
+	if !exists || res.SelectorField == "" || res.NamespaceSelector {
+		return fmt.Errorf("%s %q does not support pod selectors", obj.GetKind(), obj.GetName())
+	}
+	if res.Group == "security.kubearmor.com" && len(selector.MatchExpressions) != 0 {
+		return fmt.Errorf("%s %q only supports matchLabels selectors", obj.GetKind(), obj.GetName())
+	}
+
+	raw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(selector)
+	if err != nil {
+		return err
+	}
+	return unstructured.SetNestedMap(obj.Object, raw, "spec", res.SelectorField)
+}
+
+// SetNamespaceScope makes the policy apply to every pod in the namespace. Cluster-scoped kinds are
+// replaced by their namespaced equivalent.
+func SetNamespaceScope(obj *unstructured.Unstructured, namespace string) error {
+	res, err := setScopedKind(obj, true)
+	if err != nil {
+		return err
+	}
+
+	obj.SetNamespace(namespace)
+	return unstructured.SetNestedMap(obj.Object, map[string]interface{}{}, "spec", res.SelectorField)
+}
+
+// SetClusterScope makes the policy apply to every pod in the cluster. Namespaced kinds are replaced by their
+// cluster-scoped equivalent. Only kinds that select namespaces can be limited to or exclude namespaces.
+// A TracingPolicy has no selector that matches every pod: without a pod selector it applies to every process on
+// every node, including host and non-Kubernetes processes. It is only deployed that way when include_host is set.
+func SetClusterScope(obj *unstructured.Unstructured, namespaces []string, excluded []string, include_host bool) error {
+	res, err := setScopedKind(obj, false)
+	if err != nil {
+		return err
+	}
+
+	obj.SetNamespace("")
+	if !res.NamespaceSelector {
+		if len(namespaces) != 0 || len(excluded) != 0 {
+			return fmt.Errorf("%s %q cannot be limited to namespaces", obj.GetKind(), obj.GetName())
+		}
+		if !include_host {
+			return fmt.Errorf("%s %q applies to host processes as well at cluster scope, this has to be allowed with include_host", obj.GetKind(), obj.GetName())
+		}
+		// Without a pod selector the policy applies to every process
+		unstructured.RemoveNestedField(obj.Object, "spec", res.SelectorField)
+		return nil
+	}
+
+	selector := &metav1.LabelSelector{}
+	if len(namespaces) != 0 {
+		selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+			Key: "namespace", Operator: metav1.LabelSelectorOpIn, Values: namespaces,
+		})
+	}
+	if len(excluded) != 0 {
+		selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+			Key: "namespace", Operator: metav1.LabelSelectorOpNotIn, Values: excluded,
+		})
+	}
+
+	raw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(selector)
+	if err != nil {
+		return err
+	}
+	return unstructured.SetNestedMap(obj.Object, raw, "spec", res.SelectorField)
+}
+
+// enforceActions are the KubeArmor and Tetragon actions that block or kill instead of only reporting
+var enforceActions = map[string]bool{
+	"block":          true,
+	"allow":          true,
+	"sigkill":        true,
+	"signal":         true,
+	"override":       true,
+	"notifyenforcer": true,
+}
+
+// EnforcesActions reports whether any action under value blocks or kills, e.g. a KubeArmor action: Block
+// or a Tetragon matchActions entry with action: Sigkill
+func EnforcesActions(value interface{}) bool {
+	return hasAction(value, func(action string) bool { return enforceActions[strings.ToLower(action)] })
+}
+
+// hasAction reports whether the value of any action key under value matches
+func hasAction(value interface{}, match func(action string) bool) bool {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range value {
+			if action, ok := nested.(string); ok && key == "action" && match(action) {
+				return true
+			}
+			if hasAction(nested, match) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, nested := range value {
+			if hasAction(nested, match) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SetMode makes a policy object only report (agent_dto.POLICY_MODE_AUDIT) or block (agent_dto.POLICY_MODE_ENFORCE).
+// KubeArmor rules switch between action Audit and Block. Allow rules keep denying everything outside the list,
+// so audit mode is refused for a KubeArmor policy with any; its audit variant has to be deployed instead.
+// Tetragon selectors lose their enforcing actions in audit mode. Enforcing actions are never added to a Tetragon
+// policy, so enforce mode is refused for one that does not enforce already; its enforce variant has to be
+// deployed instead. NetworkPolicy has no audit mode.
+func SetMode(obj *unstructured.Unstructured, mode string) error {
+	if mode != agent_dto.POLICY_MODE_AUDIT && mode != agent_dto.POLICY_MODE_ENFORCE {
+		return fmt.Errorf("unknown policy mode %q", mode)
+	}
+	res, exists := supportedKinds[obj.GetKind()]
+	if !exists {
+		return fmt.Errorf("unsupported policy kind %q", obj.GetKind())
+	}
+	spec, found, err := unstructured.NestedMap(obj.Object, "spec")
+	if err != nil || !found {
+		return fmt.Errorf("%s %q has no spec", obj.GetKind(), obj.GetName())
+	}
+
+	switch res.Group {
+	case "security.kubearmor.com":
+		if mode == agent_dto.POLICY_MODE_AUDIT && hasAction(spec, func(action string) bool { return strings.EqualFold(action, "allow") }) {
+			return fmt.Errorf("%s %q has Allow rules that deny everything else, deploy its audit variant instead", obj.GetKind(), obj.GetName())
+		}
+		from, to := "block", "Audit"
+		if mode == agent_dto.POLICY_MODE_ENFORCE {
+			from, to = "audit", "Block"
+		}
+		setKubeArmorAction(spec, from, to)
+	case "cilium.io":
+		if mode == agent_dto.POLICY_MODE_ENFORCE {
+			if !EnforcesActions(spec) {
+				return fmt.Errorf("%s %q has no enforcing actions, deploy its enforce variant instead", obj.GetKind(), obj.GetName())
+			}
+			return nil
+		}
+		removeEnforceActions(spec)
+	default:
+		if mode == agent_dto.POLICY_MODE_AUDIT {
+			return fmt.Errorf("%s %q cannot be deployed in audit mode", obj.GetKind(), obj.GetName())
+		}
+		return nil
+	}
+	return unstructured.SetNestedMap(obj.Object, spec, "spec")
+}
+
+// setKubeArmorAction replaces the action from with to in every rule under value
+func setKubeArmorAction(value interface{}, from string, to string) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range typed {
+			if action, ok := nested.(string); ok && key == "action" && strings.EqualFold(action, from) {
+				typed[key] = to
+				continue
+			}
+			setKubeArmorAction(nested, from, to)
+		}
+	case []interface{}:
+		for _, nested := range typed {
+			setKubeArmorAction(nested, from, to)
+		}
+	}
+}
+
+// removeEnforceActions drops the enforcing entries from every Tetragon matchActions list under value
+func removeEnforceActions(value interface{}) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range typed {
+			actions, ok := nested.([]interface{})
+			if !ok || key != "matchActions" {
+				removeEnforceActions(nested)
+				continue
+			}
+			var kept []interface{}
+			for _, entry := range actions {
+				if entry, ok := entry.(map[string]interface{}); ok {
+					if action, _ := entry["action"].(string); enforceActions[strings.ToLower(action)] {
+						continue
+					}
+				}
+				kept = append(kept, entry)
+			}
+			if len(kept) == 0 {
+				// Without matchActions Tetragon posts the event
+				delete(typed, key)
+			} else {
+				typed[key] = kept
+			}
+		}
+	case []interface{}:
+		for _, nested := range typed {
+			removeEnforceActions(nested)
+		}
+	}
+}
+
+// setScopedKind switches the object to the equivalent kind when its kind has the wrong scope
+func setScopedKind(obj *unstructured.Unstructured, namespaced bool) (Resource, error) {
+	res, exists := supportedKinds[obj.GetKind()]
+	if !exists {
+		return Resource{}, fmt.Errorf("unsupported policy kind %q", obj.GetKind())
+	}
+	if res.Namespaced == namespaced {
+		return res, nil
+	}
+
+	scope := "cluster"
+	if namespaced {
+		scope = "namespace"
+	}
+	if res.ScopedKind == "" {
+		return Resource{}, fmt.Errorf("%s %q cannot be deployed with %s scope", obj.GetKind(), obj.GetName(), scope)
+	}
+
+	obj.SetKind(res.ScopedKind)
+	return supportedKinds[res.ScopedKind], nil
+}
+
+// resourceClient returns the dynamic client scoped to the object's resource and namespace
+func resourceClient(client dynamic.Interface, obj *unstructured.Unstructured) (dynamic.ResourceInterface, error) {
+	gvr, namespaced, err := ResourceFor(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	if !namespaced {
+		return client.Resource(gvr), nil
+	}
+	if obj.GetNamespace() == "" {
+		return nil, fmt.Errorf("%s %q has no metadata.namespace", obj.GetKind(), obj.GetName())
+	}
+	return client.Resource(gvr).Namespace(obj.GetNamespace()), nil
+}
+
+// Apply creates or updates the object using server-side apply with the SNFOK field manager
+func Apply(ctx context.Context, client dynamic.Interface, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	resource, err := resourceClient(client, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	return resource.Apply(ctx, obj.GetName(), obj, metav1.ApplyOptions{
+		FieldManager: FieldManager,
+		Force:        true,
+	})
+}
+
+// DryRun sends the object through a server-side apply dry-run, so schema validation and admission run
+// without anything being persisted
+func DryRun(ctx context.Context, client dynamic.Interface, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	resource, err := resourceClient(client, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	return resource.Apply(ctx, obj.GetName(), obj, metav1.ApplyOptions{
+		FieldManager: FieldManager,
+		Force:        true,
+		DryRun:       []string{metav1.DryRunAll},
+	})
+}
+
+// Get returns the object as it is in the cluster, or nil when it does not exist
+func Get(ctx context.Context, client dynamic.Interface, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	resource, err := resourceClient(client, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := resource.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// Restore puts an applied object back into the state it had before, as returned by Get. An object that did not
+// exist is removed; any other object is applied again with its previous content.
+func Restore(ctx context.Context, client dynamic.Interface, obj *unstructured.Unstructured, previous *unstructured.Unstructured) error {
+	if previous == nil {
+		return Delete(ctx, client, obj)
+	}
+
+	// Fields maintained by the API server cannot be applied
+	restored := previous.DeepCopy()
+	restored.SetManagedFields(nil)
+	restored.SetResourceVersion("")
+	restored.SetUID("")
+	restored.SetCreationTimestamp(metav1.Time{})
+	unstructured.RemoveNestedField(restored.Object, "metadata", "generation")
+	unstructured.RemoveNestedField(restored.Object, "status")
+
+	_, err := Apply(ctx, client, restored)
+	return err
+}
+
+// Delete removes the object from the cluster. Objects that are already gone are not treated as an error.
+func Delete(ctx context.Context, client dynamic.Interface, obj *unstructured.Unstructured) error {
+	resource, err := resourceClient(client, obj)
+	if err != nil {
+		return err
+	}
+
+	err = resource.Delete(ctx, obj.GetName(), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// Label marks the object as managed by SNFOK and links it to the catalog policy and implemented policy it belongs to
+// and the scope it was deployed with
+func Label(obj *unstructured.Unstructured, policy_id string, implemented_id string, scope string) {
+	object_labels := obj.GetLabels()
+	if object_labels == nil {
+		object_labels = make(map[string]string)
+	}
+	object_labels[agent_consts.MANAGED_BY_LABEL] = agent_consts.MANAGED_BY_VALUE
+	if policy_id != "" {
+		object_labels[agent_consts.POLICY_ID_LABEL] = policy_id
+	}
+	if implemented_id != "" {
+		object_labels[agent_consts.IMPLEMENTED_ID_LABEL] = implemented_id
+	}
+	if scope != "" {
+		object_labels[agent_consts.SCOPE_LABEL] = scope
+	}
+	obj.SetLabels(object_labels)
+}
+
+// ListManaged returns the objects of every supported kind that carry the SNFOK managed-by label.
+// Cluster-scoped kinds are always included; namespaced kinds are limited to namespace unless it is empty.
+// Kinds whose CRD is not installed in the cluster are skipped.
+func ListManaged(ctx context.Context, client dynamic.Interface, namespace string) ([]unstructured.Unstructured, error) {
+	return listLabelled(ctx, client, namespace, labels.Set{
+		agent_consts.MANAGED_BY_LABEL: agent_consts.MANAGED_BY_VALUE,
+	})
+}
+
+// ListImplemented returns the managed objects of every supported kind that belong to an implemented policy
+func ListImplemented(ctx context.Context, client dynamic.Interface, implemented_id string) ([]unstructured.Unstructured, error) {
+	return listLabelled(ctx, client, "", labels.Set{
+		agent_consts.MANAGED_BY_LABEL:     agent_consts.MANAGED_BY_VALUE,
+		agent_consts.IMPLEMENTED_ID_LABEL: implemented_id,
+	})
+}
+
+// listLabelled lists the objects of every supported kind that carry the labels, filtered by the API server
+func listLabelled(ctx context.Context, client dynamic.Interface, namespace string, object_labels labels.Set) ([]unstructured.Unstructured, error) {
+	opts := metav1.ListOptions{LabelSelector: object_labels.String()}
+
+	var managed []unstructured.Unstructured
+	for kind, res := range supportedKinds {
+		gvr := schema.GroupVersionResource{Group: res.Group, Version: res.Version, Resource: res.Resource}
+
+		var list *unstructured.UnstructuredList
+		var err error
+		if res.Namespaced {
+			list, err = client.Resource(gvr).Namespace(namespace).List(ctx, opts)
+		} else {
+			list, err = client.Resource(gvr).List(ctx, opts)
+		}
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s objects: %v", kind, err)
+		}
+		managed = append(managed, list.Items...)
+	}
+	return managed, nil
+}
+
+// SelectsPod reports whether a policy object applies to a pod with the given namespace and labels.
+// A policy without a pod selector applies to every pod in its scope.
+func SelectsPod(obj *unstructured.Unstructured, namespace string, pod_labels map[string]string) (bool, error) {
+	if obj.GetNamespace() != "" && obj.GetNamespace() != namespace {
+		return false, nil
+	}
+
+	// KubeArmor cluster policies select namespaces by name
+	if res, exists := supportedKinds[obj.GetKind()]; exists && res.NamespaceSelector {
+		pod_labels = map[string]string{"namespace": namespace}
+	}
+
+	// KubeArmor names its selector "selector", Tetragon and NetworkPolicy use "podSelector"
+	raw, found, err := unstructured.NestedMap(obj.Object, "spec", "podSelector")
+	if err == nil && !found {
+		raw, found, err = unstructured.NestedMap(obj.Object, "spec", "selector")
+	}
+	if err != nil {
+		return false, err
+	}
+	if !found {
+		return true, nil
+	}
+
+	var selector metav1.LabelSelector
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &selector); err != nil {
+		return false, fmt.Errorf("invalid selector in %s %q: %v", obj.GetKind(), obj.GetName(), err)
+	}
+	matcher, err := metav1.LabelSelectorAsSelector(&selector)
+	if err != nil {
+		return false, fmt.Errorf("invalid selector in %s %q: %v", obj.GetKind(), obj.GetName(), err)
+	}
+	return matcher.Matches(labels.Set(pod_labels)), nil
+}
@@ -0,0 +1,225 @@
+package manifests
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const kubeArmorPolicy = `
+apiVersion: security.kubearmor.com/v1
+kind: KubeArmorPolicy
+metadata:
+  name: block-shells
+spec:
+  selector:
+    matchLabels:
+      app: web
+  process:
+    matchPaths:
+    - path: /bin/sh
+    action: Block
+`
+
+const kubeArmorAllowPolicy = `
+apiVersion: security.kubearmor.com/v1
+kind: KubeArmorPolicy
+metadata:
+  name: allow-app-files
+spec:
+  selector:
+    matchLabels:
+      app: web
+  file:
+    matchDirectories:
+    - dir: /app/
+      recursive: true
+    action: Allow
+`
+
+const tetragonPolicy = `
+apiVersion: cilium.io/v1alpha1
+kind: TracingPolicyNamespaced
+metadata:
+  name: file-monitoring
+  namespace: default
+spec:
+  kprobes:
+  - call: security_file_permission
+    selectors:
+    - matchArgs:
+      - index: 0
+        operator: Prefix
+        values: ["/etc/shadow"]
+      matchActions:
+      - action: Post
+      - action: Override
+        argError: -1
+    - matchArgs:
+      - index: 0
+        operator: Prefix
+        values: ["/root/.ssh"]
+      matchActions:
+      - action: Sigkill
+`
+
+const tetragonAuditPolicy = `
+apiVersion: cilium.io/v1alpha1
+kind: TracingPolicyNamespaced
+metadata:
+  name: file-monitoring
+  namespace: default
+spec:
+  kprobes:
+  - call: security_file_permission
+    selectors:
+    - matchArgs:
+      - index: 0
+        operator: Prefix
+        values: ["/etc"]
+      matchActions:
+      - action: Post
+    - matchArgs:
+      - index: 0
+        operator: Prefix
+        values: ["/boot"]
+`
+
+const networkPolicy = `
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: deny-all
+  namespace: default
+spec:
+  podSelector: {}
+  policyTypes: [Ingress]
+`
+
+func decodeOne(t *testing.T, content string) *unstructured.Unstructured {
+	t.Helper()
+	objects, err := Decode([]byte(content))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(objects) != 1 {
+		t.Fatalf("decoded %d objects, want 1", len(objects))
+	}
+	return objects[0]
+}
+
+func nestedAction(t *testing.T, obj *unstructured.Unstructured, fields ...string) string {
+	t.Helper()
+	action, _, err := unstructured.NestedString(obj.Object, fields...)
+	if err != nil {
+		t.Fatalf("read %v: %v", fields, err)
+	}
+	return action
+}
+
+func selectorActions(t *testing.T, obj *unstructured.Unstructured) [][]string {
+	t.Helper()
+	kprobes, _, _ := unstructured.NestedSlice(obj.Object, "spec", "kprobes")
+	selectors := kprobes[0].(map[string]interface{})["selectors"].([]interface{})
+
+	var actions [][]string
+	for _, selector := range selectors {
+		var names []string
+		entries, _ := selector.(map[string]interface{})["matchActions"].([]interface{})
+		for _, entry := range entries {
+			names = append(names, entry.(map[string]interface{})["action"].(string))
+		}
+		actions = append(actions, names)
+	}
+	return actions
+}
+
+func TestSetModeKubeArmor(t *testing.T) {
+	obj := decodeOne(t, kubeArmorPolicy)
+
+	if err := SetMode(obj, agent_dto.POLICY_MODE_AUDIT); err != nil {
+		t.Fatalf("audit: %v", err)
+	}
+	if action := nestedAction(t, obj, "spec", "process", "action"); action != "Audit" {
+		t.Errorf("process action in audit mode = %q, want Audit", action)
+	}
+
+	if err := SetMode(obj, agent_dto.POLICY_MODE_ENFORCE); err != nil {
+		t.Fatalf("enforce: %v", err)
+	}
+	if action := nestedAction(t, obj, "spec", "process", "action"); action != "Block" {
+		t.Errorf("process action in enforce mode = %q, want Block", action)
+	}
+}
+
+func TestSetModeKubeArmorAllow(t *testing.T) {
+	obj := decodeOne(t, kubeArmorAllowPolicy)
+	before := obj.DeepCopy()
+
+	if err := SetMode(obj, agent_dto.POLICY_MODE_AUDIT); err == nil {
+		t.Fatal("audit mode was accepted for a policy with Allow rules")
+	}
+	if !reflect.DeepEqual(obj.Object, before.Object) {
+		t.Error("refused policy was changed")
+	}
+
+	if err := SetMode(obj, agent_dto.POLICY_MODE_ENFORCE); err != nil {
+		t.Fatalf("enforce: %v", err)
+	}
+	if action := nestedAction(t, obj, "spec", "file", "action"); action != "Allow" {
+		t.Errorf("file action in enforce mode = %q, want Allow", action)
+	}
+}
+
+func TestSetModeTetragonAudit(t *testing.T) {
+	obj := decodeOne(t, tetragonPolicy)
+
+	if err := SetMode(obj, agent_dto.POLICY_MODE_AUDIT); err != nil {
+		t.Fatalf("audit: %v", err)
+	}
+
+	want := [][]string{{"Post"}, nil}
+	if got := selectorActions(t, obj); !reflect.DeepEqual(got, want) {
+		t.Errorf("selector actions = %v, want %v", got, want)
+	}
+	if EnforcesActions(obj.Object["spec"]) {
+		t.Error("policy still enforces in audit mode")
+	}
+}
+
+func TestSetModeTetragonEnforce(t *testing.T) {
+	obj := decodeOne(t, tetragonPolicy)
+	before := obj.DeepCopy()
+
+	if err := SetMode(obj, agent_dto.POLICY_MODE_ENFORCE); err != nil {
+		t.Fatalf("enforce: %v", err)
+	}
+	if !reflect.DeepEqual(obj.Object, before.Object) {
+		t.Error("enforcing policy was changed in enforce mode")
+	}
+}
+
+func TestSetModeTetragonWithoutEnforceVariant(t *testing.T) {
+	obj := decodeOne(t, tetragonAuditPolicy)
+	before := obj.DeepCopy()
+
+	if err := SetMode(obj, agent_dto.POLICY_MODE_ENFORCE); err == nil {
+		t.Fatal("enforce mode was accepted for a policy that only posts events")
+	}
+	if !reflect.DeepEqual(obj.Object, before.Object) {
+		t.Error("refused policy was changed")
+	}
+}
+
+func TestSetModeNetworkPolicy(t *testing.T) {
+	obj := decodeOne(t, networkPolicy)
+
+	if err := SetMode(obj, agent_dto.POLICY_MODE_AUDIT); err == nil {
+		t.Error("audit mode was accepted for a NetworkPolicy")
+	}
+	if err := SetMode(obj, agent_dto.POLICY_MODE_ENFORCE); err != nil {
+		t.Errorf("enforce: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package templates
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// Marker of the comment block in which a template describes itself for the catalog, e.g.
+//
+//	# snfok:catalog
+//	# title: Restrict External Egress Connections
+//	# description: Blocks connections from the selected pods to destinations outside the cluster
+//	# tags: [network, egress]
+//	# mode: enforce
+//	# variant: network/restrict-egress-audit.yaml
+//	# snfok:end
+const catalogStartMarker = "snfok:catalog"
+
+// CatalogAnnotation is the catalog description of a template. Every field is optional.
+type CatalogAnnotation struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	Mode        string   `json:"mode"`
+	Variant     string   `json:"variant"` // Template, relative to the templates directory, of the same policy in the other mode
+}
+
+// ParseCatalogAnnotation reads the catalog block in the header of a policy template, if any
+func ParseCatalogAnnotation(content string) (CatalogAnnotation, error) {
+	block, err := readCommentBlock(content, catalogStartMarker)
+	if err != nil {
+		return CatalogAnnotation{}, err
+	}
+
+	var annotation CatalogAnnotation
+	if block == "" {
+		return annotation, nil
+	}
+	if err := yaml.Unmarshal([]byte(block), &annotation); err != nil {
+		return CatalogAnnotation{}, fmt.Errorf("invalid catalog block: %v", err)
+	}
+
+	annotation.Mode = strings.ToUpper(annotation.Mode)
+	if annotation.Mode != "" && annotation.Mode != agent_dto.POLICY_MODE_AUDIT && annotation.Mode != agent_dto.POLICY_MODE_ENFORCE {
+		return CatalogAnnotation{}, fmt.Errorf("invalid mode %q in catalog block, expected audit or enforce", annotation.Mode)
+	}
+	return annotation, nil
+}
+
+// RemoveCatalogVariant returns the template without the variant line of its catalog block, for a copy of the
+// template that the variant of the original does not belong to
+func RemoveCatalogVariant(content string) string {
+	lines := strings.SplitAfter(content, "\n")
+	inBlock := false
+	kept := lines[:0]
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		comment := strings.TrimSpace(strings.TrimPrefix(trimmed, "#"))
+		switch {
+		case !strings.HasPrefix(trimmed, "#"):
+			inBlock = false
+		case !inBlock:
+			inBlock = comment == catalogStartMarker
+		case comment == paramsEndMarker:
+			inBlock = false
+		case strings.HasPrefix(comment, "variant:"):
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "")
+}
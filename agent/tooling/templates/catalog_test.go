@@ -0,0 +1,48 @@
+package templates
+
+import "testing"
+
+func TestRemoveCatalogVariant(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "variant in catalog block",
+			content: "# snfok:catalog\n# mode: audit\n# variant: file-monitoring-enforce.yaml\n# snfok:end\nkind: TracingPolicy\n",
+			want:    "# snfok:catalog\n# mode: audit\n# snfok:end\nkind: TracingPolicy\n",
+		},
+		{
+			name:    "no catalog block",
+			content: "# variant: kept.yaml\nkind: TracingPolicy\n",
+			want:    "# variant: kept.yaml\nkind: TracingPolicy\n",
+		},
+		{
+			name:    "variant after the catalog block",
+			content: "# snfok:catalog\n# mode: audit\n# snfok:end\n# variant: kept.yaml\nkind: TracingPolicy\n",
+			want:    "# snfok:catalog\n# mode: audit\n# snfok:end\n# variant: kept.yaml\nkind: TracingPolicy\n",
+		},
+		{
+			name:    "params block untouched",
+			content: "# snfok:params\n# - name: Action\n#   type: signal_action\n# snfok:end\n# snfok:catalog\n# variant: a.yaml\n# snfok:end\nkind: TracingPolicy",
+			want:    "# snfok:params\n# - name: Action\n#   type: signal_action\n# snfok:end\n# snfok:catalog\n# snfok:end\nkind: TracingPolicy",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RemoveCatalogVariant(tt.content)
+			if got != tt.want {
+				t.Errorf("RemoveCatalogVariant() = %q, want %q", got, tt.want)
+			}
+			annotation, err := ParseCatalogAnnotation(got)
+			if err != nil {
+				t.Fatalf("ParseCatalogAnnotation(): %v", err)
+			}
+			if annotation.Variant != "" {
+				t.Errorf("variant %q left in the catalog block", annotation.Variant)
+			}
+		})
+	}
+}
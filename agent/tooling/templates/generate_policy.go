@@ -1,47 +1,106 @@
 package templates
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"text/template"
 
 	"github.com/FearLessSaad/SNFOK/constants/agent_consts"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
 	"github.com/google/uuid"
 )
 
-func GeneratePolicy(policy_file string, namespace string, app_label string) (string, error) {
+// ReadTemplate reads a policy template from POLICIES_TEMPLATES_DIR
+func ReadTemplate(policy_file string) (string, error) {
+	if !filepath.IsLocal(policy_file) {
+		return "Policy file is not exists.", fmt.Errorf("policy file %q is outside of the templates directory", policy_file)
+	}
+
 	policy_templates_dir := os.Getenv("POLICIES_TEMPLATES_DIR")
 	policy_template_path := filepath.Join(policy_templates_dir, policy_file)
-	applied_policies_dir := os.Getenv("APPLIED_POLICIES_DIR")
 
 	if _, err := os.Stat(policy_template_path); os.IsNotExist(err) {
 		return "Policy file is not exists.", err
 	}
 
-	err := os.MkdirAll(applied_policies_dir, 0755)
+	content, err := os.ReadFile(policy_template_path)
 	if err != nil {
-		return "Unable to create directory for applied policies. Please check permissions.", err
+		return "Unable to read policy file. Please check permissions.", err
 	}
 
-	content, err := os.ReadFile(policy_template_path)
+	return string(content), nil
+}
+
+// TemplateParams returns the parameters declared by a policy template
+func TemplateParams(policy_file string) ([]agent_dto.PolicyParam, error) {
+	content, err := ReadTemplate(policy_file)
 	if err != nil {
-		return "Unable to read policy file. Please check permissions.", err
+		return nil, err
 	}
 
-	newUUID := uuid.NewString()
-	id := strings.Split(newUUID, "-")[4]
-	policy := strings.ReplaceAll(string(content), agent_consts.POLICY_ID_TEMPLATE, id)
-	policy = strings.ReplaceAll(string(policy), agent_consts.POLICY_NAMESPACE_TEMPLATE, namespace)
-	policy = strings.ReplaceAll(string(policy), agent_consts.POLICY_APP_LABEL_TEMPLATE, app_label)
+	return ParseParams(content)
+}
 
-	dst_path := filepath.Join(applied_policies_dir, newUUID+".yaml")
+// RenderPolicy executes a policy template with the built-in fields and the validated parameter values
+func RenderPolicy(policy_file string, namespace string, app_label string, params map[string]interface{}) (string, error) {
+	content, err := ReadTemplate(policy_file)
+	if err != nil {
+		return content, err
+	}
 
-	file, err := os.Create(dst_path)
+	return RenderContent(filepath.Base(policy_file), content, namespace, app_label, params)
+}
+
+// RenderContent renders a policy template pushed by the server like RenderPolicy renders a template file
+func RenderContent(name string, content string, namespace string, app_label string, params map[string]interface{}) (string, error) {
+	return RenderTemplate(name, content, strings.Split(uuid.NewString(), "-")[4], namespace, app_label, params)
+}
+
+// RenderTemplate executes the content of a policy template, see RenderPolicy. The policy id keeps the names
+// of the rendered objects unique.
+func RenderTemplate(name string, content string, policy_id string, namespace string, app_label string, params map[string]interface{}) (string, error) {
+	declared, err := ParseParams(content)
 	if err != nil {
+		return "Policy template declares invalid parameters.", err
+	}
+	data, err := ResolveParams(declared, params)
+	if err != nil {
+		return "Invalid policy parameters.", err
+	}
+	data[agent_consts.POLICY_ID_PARAM] = policy_id
+	data[agent_consts.POLICY_NAMESPACE_PARAM] = namespace
+	data[agent_consts.POLICY_APP_LABEL_PARAM] = app_label
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(content)
+	if err != nil {
+		return "Unable to parse policy template.", err
+	}
+
+	var policy bytes.Buffer
+	if err := tmpl.Execute(&policy, data); err != nil {
+		return "Unable to render policy template.", err
+	}
+
+	return policy.String(), nil
+}
+
+// SavePolicy writes a rendered policy to APPLIED_POLICIES_DIR and returns its path
+func SavePolicy(policy []byte) (string, error) {
+	applied_policies_dir := os.Getenv("APPLIED_POLICIES_DIR")
+
+	err := os.MkdirAll(applied_policies_dir, 0755)
+	if err != nil {
+		return "Unable to create directory for applied policies. Please check permissions.", err
+	}
+
+	dst_path := filepath.Join(applied_policies_dir, uuid.NewString()+".yaml")
+
+	if err := os.WriteFile(dst_path, policy, 0644); err != nil {
 		return "Unable to create new policy yaml. Please Check permissions.", err
 	}
-	file.WriteString(policy)
-	defer file.Close()
 
 	return dst_path, nil
 }
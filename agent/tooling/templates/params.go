@@ -0,0 +1,329 @@
+package templates
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/FearLessSaad/SNFOK/constants/agent_consts"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// Markers of the comment block in which a template declares its parameters, e.g.
+//
+//	# snfok:params
+//	# - name: AllowedCIDRs
+//	#   type: cidr_list
+//	#   default: ["127.0.0.1", "10.10.0.0/16"]
+//	# snfok:end
+const (
+	paramsStartMarker = "snfok:params"
+	paramsEndMarker   = "snfok:end"
+)
+
+// builtinParams are always passed to a template and cannot be declared
+var builtinParams = map[string]bool{
+	agent_consts.POLICY_ID_PARAM:        true,
+	agent_consts.POLICY_NAMESPACE_PARAM: true,
+	agent_consts.POLICY_APP_LABEL_PARAM: true,
+}
+
+var paramTypes = map[string]bool{
+	agent_dto.PARAM_STRING:        true,
+	agent_dto.PARAM_INT:           true,
+	agent_dto.PARAM_BOOL:          true,
+	agent_dto.PARAM_PORT:          true,
+	agent_dto.PARAM_PORT_LIST:     true,
+	agent_dto.PARAM_CIDR:          true,
+	agent_dto.PARAM_CIDR_LIST:     true,
+	agent_dto.PARAM_PATH:          true,
+	agent_dto.PARAM_PATH_LIST:     true,
+	agent_dto.PARAM_BINARY:        true,
+	agent_dto.PARAM_BINARY_LIST:   true,
+	agent_dto.PARAM_SIGNAL_ACTION: true,
+}
+
+// signalActions are the Tetragon match actions a signal_action parameter accepts
+var signalActions = map[string]bool{
+	"Post":           true,
+	"NoPost":         true,
+	"Sigkill":        true,
+	"Signal":         true,
+	"Override":       true,
+	"NotifyEnforcer": true,
+}
+
+var paramNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// ParseParams reads the parameters declared in the header of a policy template
+func ParseParams(content string) ([]agent_dto.PolicyParam, error) {
+	block, err := readCommentBlock(content, paramsStartMarker)
+	if err != nil {
+		return nil, err
+	}
+
+	params := []agent_dto.PolicyParam{}
+	if block == "" {
+		return params, nil
+	}
+	if err := yaml.Unmarshal([]byte(block), &params); err != nil {
+		return nil, fmt.Errorf("invalid parameter declaration: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, param := range params {
+		if !paramNamePattern.MatchString(param.Name) || builtinParams[param.Name] {
+			return nil, fmt.Errorf("invalid parameter name %q", param.Name)
+		}
+		if seen[param.Name] {
+			return nil, fmt.Errorf("parameter %s is declared twice", param.Name)
+		}
+		seen[param.Name] = true
+
+		if !paramTypes[param.Type] {
+			return nil, fmt.Errorf("unknown type %q of parameter %s", param.Type, param.Name)
+		}
+		if param.Default != nil {
+			if _, err := convertParam(param, param.Default); err != nil {
+				return nil, fmt.Errorf("invalid default of parameter %s: %v", param.Name, err)
+			}
+		}
+	}
+	return params, nil
+}
+
+// readCommentBlock returns the content of the comment block of a template that starts with the marker, without
+// the comment prefixes. The block ends at the end marker or at the first line that is not a comment.
+func readCommentBlock(content string, marker string) (string, error) {
+	var block strings.Builder
+	inBlock := false
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "#") {
+			if inBlock {
+				break
+			}
+			continue
+		}
+		comment := strings.TrimPrefix(strings.TrimPrefix(line, "#"), " ")
+		if !inBlock {
+			inBlock = strings.TrimSpace(comment) == marker
+			continue
+		}
+		if strings.TrimSpace(comment) == paramsEndMarker {
+			break
+		}
+		block.WriteString(comment + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return block.String(), nil
+}
+
+// ResolveParams validates the given values against the declared parameters and fills in the defaults.
+// Values for parameters that are not declared are rejected.
+func ResolveParams(declared []agent_dto.PolicyParam, values map[string]interface{}) (map[string]interface{}, error) {
+	known := make(map[string]bool)
+	for _, param := range declared {
+		known[param.Name] = true
+	}
+	for name := range values {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown parameter %s", name)
+		}
+	}
+
+	resolved := make(map[string]interface{})
+	for _, param := range declared {
+		value, exists := values[param.Name]
+		if !exists || value == nil {
+			value = param.Default
+		}
+		if value == nil {
+			return nil, fmt.Errorf("parameter %s is required", param.Name)
+		}
+
+		converted, err := convertParam(param, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of parameter %s: %v", param.Name, err)
+		}
+		resolved[param.Name] = converted
+	}
+	return resolved, nil
+}
+
+// convertParam validates a value against the parameter type and converts it to the Go type the template receives:
+// string, int, bool, []string or []int. Lists may also be given as a comma separated string.
+func convertParam(param agent_dto.PolicyParam, value interface{}) (interface{}, error) {
+	switch param.Type {
+	case agent_dto.PARAM_STRING:
+		return toSafeString(value)
+	case agent_dto.PARAM_INT:
+		return toInt(value)
+	case agent_dto.PARAM_BOOL:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			return strconv.ParseBool(v)
+		}
+		return nil, fmt.Errorf("%v is not a boolean", value)
+	case agent_dto.PARAM_PORT:
+		return toPort(value)
+	case agent_dto.PARAM_PORT_LIST:
+		items, err := toList(value)
+		if err != nil {
+			return nil, err
+		}
+		ports := []int{}
+		for _, item := range items {
+			port, err := toPort(item)
+			if err != nil {
+				return nil, err
+			}
+			ports = append(ports, port)
+		}
+		return ports, nil
+	case agent_dto.PARAM_CIDR:
+		return toCIDR(value)
+	case agent_dto.PARAM_CIDR_LIST:
+		return toStringList(value, toCIDR)
+	case agent_dto.PARAM_PATH, agent_dto.PARAM_BINARY:
+		return toPath(value)
+	case agent_dto.PARAM_PATH_LIST, agent_dto.PARAM_BINARY_LIST:
+		return toStringList(value, toPath)
+	case agent_dto.PARAM_SIGNAL_ACTION:
+		action, err := toSafeString(value)
+		if err != nil {
+			return nil, err
+		}
+		if !signalActions[action] {
+			return nil, fmt.Errorf("%q is not a supported action", action)
+		}
+		return action, nil
+	}
+	return nil, fmt.Errorf("unknown parameter type %q", param.Type)
+}
+
+// toSafeString accepts strings that cannot break out of a quoted YAML scalar
+func toSafeString(value interface{}) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%v is not a string", value)
+	}
+	if strings.ContainsAny(s, "\"\\\r\n") {
+		return "", fmt.Errorf("%q contains quotes, backslashes or line breaks", s)
+	}
+	return s, nil
+}
+
+func toInt(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		if v != float64(int(v)) {
+			return 0, fmt.Errorf("%v is not an integer", v)
+		}
+		return int(v), nil
+	case string:
+		return strconv.Atoi(strings.TrimSpace(v))
+	}
+	return 0, fmt.Errorf("%v is not an integer", value)
+}
+
+func toPort(value interface{}) (int, error) {
+	port, err := toInt(value)
+	if err != nil {
+		return 0, err
+	}
+	if port < 1 || port > 65535 {
+		return 0, fmt.Errorf("%d is not a valid port", port)
+	}
+	return port, nil
+}
+
+// toCIDR accepts a CIDR or a single IP address
+func toCIDR(value interface{}) (string, error) {
+	s, err := toSafeString(value)
+	if err != nil {
+		return "", err
+	}
+	s = strings.TrimSpace(s)
+	if _, _, err := net.ParseCIDR(s); err == nil {
+		return s, nil
+	}
+	if net.ParseIP(s) != nil {
+		return s, nil
+	}
+	return "", fmt.Errorf("%q is not a valid CIDR or IP address", s)
+}
+
+// toPath accepts clean absolute paths
+func toPath(value interface{}) (string, error) {
+	s, err := toSafeString(value)
+	if err != nil {
+		return "", err
+	}
+	s = strings.TrimSpace(s)
+	if !path.IsAbs(s) {
+		return "", fmt.Errorf("%q is not an absolute path", s)
+	}
+	if path.Clean(s) != strings.TrimSuffix(s, "/") && s != "/" {
+		return "", fmt.Errorf("%q is not a clean path", s)
+	}
+	return s, nil
+}
+
+// toList accepts a list or a comma separated string
+func toList(value interface{}) ([]interface{}, error) {
+	switch v := value.(type) {
+	case []interface{}:
+		return v, nil
+	case []string:
+		items := make([]interface{}, len(v))
+		for i := range v {
+			items[i] = v[i]
+		}
+		return items, nil
+	case string:
+		items := []interface{}{}
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("%v is not a list", value)
+}
+
+func toStringList(value interface{}, convert func(interface{}) (string, error)) ([]string, error) {
+	items, err := toList(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("list is empty")
+	}
+
+	result := []string{}
+	for _, item := range items {
+		converted, err := convert(item)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, converted)
+	}
+	return result, nil
+}
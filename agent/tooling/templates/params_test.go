@@ -0,0 +1,243 @@
+package templates
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+)
+
+func TestParseParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []agent_dto.PolicyParam
+		wantErr string
+	}{
+		{
+			name:    "no block",
+			content: "apiVersion: v1\nkind: ConfigMap\n",
+			want:    []agent_dto.PolicyParam{},
+		},
+		{
+			name: "typed params with defaults",
+			content: `# snfok:params
+# - name: AllowedCIDRs
+#   type: cidr_list
+#   default: ["127.0.0.1", "10.10.0.0/16"]
+# - name: Port
+#   type: port
+#   description: Listening port
+# snfok:end
+apiVersion: v1
+`,
+			want: []agent_dto.PolicyParam{
+				{Name: "AllowedCIDRs", Type: agent_dto.PARAM_CIDR_LIST, Default: []interface{}{"127.0.0.1", "10.10.0.0/16"}},
+				{Name: "Port", Type: agent_dto.PARAM_PORT, Description: "Listening port"},
+			},
+		},
+		{
+			name:    "block ends at first line that is not a comment",
+			content: "# snfok:params\n# - name: Path\n#   type: path\napiVersion: v1\n# - name: Other\n#   type: int\n",
+			want:    []agent_dto.PolicyParam{{Name: "Path", Type: agent_dto.PARAM_PATH}},
+		},
+		{
+			name:    "unknown type",
+			content: "# snfok:params\n# - name: Count\n#   type: float\n# snfok:end\n",
+			wantErr: "unknown type",
+		},
+		{
+			name:    "invalid name",
+			content: "# snfok:params\n# - name: 1st\n#   type: int\n# snfok:end\n",
+			wantErr: "invalid parameter name",
+		},
+		{
+			name:    "builtin name",
+			content: "# snfok:params\n# - name: Namespace\n#   type: string\n# snfok:end\n",
+			wantErr: "invalid parameter name",
+		},
+		{
+			name:    "declared twice",
+			content: "# snfok:params\n# - name: Port\n#   type: port\n# - name: Port\n#   type: int\n# snfok:end\n",
+			wantErr: "declared twice",
+		},
+		{
+			name:    "invalid default",
+			content: "# snfok:params\n# - name: Port\n#   type: port\n#   default: 70000\n# snfok:end\n",
+			wantErr: "invalid default of parameter Port",
+		},
+		{
+			name:    "invalid yaml",
+			content: "# snfok:params\n# - name: [Port\n# snfok:end\n",
+			wantErr: "invalid parameter declaration",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseParams(tt.content)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseParams() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseParams(): %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseParams() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveParams(t *testing.T) {
+	declared := []agent_dto.PolicyParam{
+		{Name: "Ports", Type: agent_dto.PARAM_PORT_LIST, Default: []interface{}{80.0, 443.0}},
+		{Name: "Action", Type: agent_dto.PARAM_SIGNAL_ACTION, Default: "Sigkill"},
+		{Name: "Binary", Type: agent_dto.PARAM_BINARY},
+	}
+
+	tests := []struct {
+		name    string
+		values  map[string]interface{}
+		want    map[string]interface{}
+		wantErr string
+	}{
+		{
+			name:   "defaults fill in left out values",
+			values: map[string]interface{}{"Binary": "/bin/sh"},
+			want:   map[string]interface{}{"Ports": []int{80, 443}, "Action": "Sigkill", "Binary": "/bin/sh"},
+		},
+		{
+			name:   "given values replace defaults",
+			values: map[string]interface{}{"Ports": "8080, 8443", "Action": "Post", "Binary": "/usr/bin/curl"},
+			want:   map[string]interface{}{"Ports": []int{8080, 8443}, "Action": "Post", "Binary": "/usr/bin/curl"},
+		},
+		{
+			name:   "null value takes the default",
+			values: map[string]interface{}{"Action": nil, "Binary": "/bin/sh"},
+			want:   map[string]interface{}{"Ports": []int{80, 443}, "Action": "Sigkill", "Binary": "/bin/sh"},
+		},
+		{
+			name:    "required value without default",
+			values:  map[string]interface{}{},
+			wantErr: "parameter Binary is required",
+		},
+		{
+			name:    "unknown parameter",
+			values:  map[string]interface{}{"Binary": "/bin/sh", "Extra": "x"},
+			wantErr: "unknown parameter Extra",
+		},
+		{
+			name:    "value outside the enum",
+			values:  map[string]interface{}{"Binary": "/bin/sh", "Action": "Kill"},
+			wantErr: "invalid value of parameter Action",
+		},
+		{
+			name:    "wrong type",
+			values:  map[string]interface{}{"Binary": 42.0},
+			wantErr: "invalid value of parameter Binary",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveParams(declared, tt.values)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ResolveParams() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveParams(): %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveParams() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertParam(t *testing.T) {
+	tests := []struct {
+		name    string
+		typ     string
+		value   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "string", typ: agent_dto.PARAM_STRING, value: "web", want: "web"},
+		{name: "string with quote", typ: agent_dto.PARAM_STRING, value: `we"b`, wantErr: true},
+		{name: "string with line break", typ: agent_dto.PARAM_STRING, value: "web\nkind: Secret", wantErr: true},
+		{name: "string from number", typ: agent_dto.PARAM_STRING, value: 1.0, wantErr: true},
+		{name: "int from json number", typ: agent_dto.PARAM_INT, value: 3.0, want: 3},
+		{name: "int from string", typ: agent_dto.PARAM_INT, value: " 12 ", want: 12},
+		{name: "int from fraction", typ: agent_dto.PARAM_INT, value: 1.5, wantErr: true},
+		{name: "bool", typ: agent_dto.PARAM_BOOL, value: true, want: true},
+		{name: "bool from string", typ: agent_dto.PARAM_BOOL, value: "false", want: false},
+		{name: "bool from number", typ: agent_dto.PARAM_BOOL, value: 1.0, wantErr: true},
+		{name: "port", typ: agent_dto.PARAM_PORT, value: 443.0, want: 443},
+		{name: "port zero", typ: agent_dto.PARAM_PORT, value: 0.0, wantErr: true},
+		{name: "port too high", typ: agent_dto.PARAM_PORT, value: "65536", wantErr: true},
+		{name: "port list", typ: agent_dto.PARAM_PORT_LIST, value: []interface{}{22.0, "80"}, want: []int{22, 80}},
+		{name: "port list with invalid port", typ: agent_dto.PARAM_PORT_LIST, value: "22,x", wantErr: true},
+		{name: "cidr", typ: agent_dto.PARAM_CIDR, value: "10.0.0.0/8", want: "10.0.0.0/8"},
+		{name: "cidr from ip", typ: agent_dto.PARAM_CIDR, value: "10.0.0.1", want: "10.0.0.1"},
+		{name: "invalid cidr", typ: agent_dto.PARAM_CIDR, value: "10.0.0.0/33", wantErr: true},
+		{name: "cidr list", typ: agent_dto.PARAM_CIDR_LIST, value: []string{"127.0.0.1", "::1"}, want: []string{"127.0.0.1", "::1"}},
+		{name: "empty cidr list", typ: agent_dto.PARAM_CIDR_LIST, value: "", wantErr: true},
+		{name: "path", typ: agent_dto.PARAM_PATH, value: "/etc/", want: "/etc/"},
+		{name: "relative path", typ: agent_dto.PARAM_PATH, value: "etc/shadow", wantErr: true},
+		{name: "unclean path", typ: agent_dto.PARAM_PATH, value: "/etc/../root", wantErr: true},
+		{name: "binary list", typ: agent_dto.PARAM_BINARY_LIST, value: "/bin/sh, /bin/bash", want: []string{"/bin/sh", "/bin/bash"}},
+		{name: "signal action", typ: agent_dto.PARAM_SIGNAL_ACTION, value: "Override", want: "Override"},
+		{name: "signal action is case sensitive", typ: agent_dto.PARAM_SIGNAL_ACTION, value: "sigkill", wantErr: true},
+		{name: "unknown type", typ: "float", value: 1.5, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := convertParam(agent_dto.PolicyParam{Name: "P", Type: tt.typ}, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("convertParam(%v) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("convertParam(%v) = %#v, want %#v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderTemplateParams(t *testing.T) {
+	const header = "# snfok:params\n# - name: Port\n#   type: port\n#   default: 80\n# snfok:end\n"
+
+	tests := []struct {
+		name    string
+		content string
+		params  map[string]interface{}
+		want    string
+		wantErr bool
+	}{
+		{name: "default", content: header + "port: {{.Port}}\n", want: header + "port: 80\n"},
+		{name: "given value", content: header + "port: {{.Port}}\n", params: map[string]interface{}{"Port": 8080.0}, want: header + "port: 8080\n"},
+		{name: "builtin params", content: "name: p-{{.PolicyID}}\nnamespace: {{.Namespace}}\napp: {{.AppLabel}}\n", want: "name: p-abc\nnamespace: default\napp: web\n"},
+		{name: "undeclared param in template", content: header + "port: {{.Ports}}\n", wantErr: true},
+		{name: "invalid value", content: header + "port: {{.Port}}\n", params: map[string]interface{}{"Port": "http"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderTemplate("test", tt.content, "abc", "default", "web", tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RenderTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("RenderTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package catalog
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/FearLessSaad/SNFOK/constants/auth_constants"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/repository"
+	"github.com/FearLessSaad/SNFOK/db/initializer"
+	"github.com/spf13/cobra"
+)
+
+var CatalogCmd = &cobra.Command{
+	Use:   "catalog",
+	Short: "Manage the policy catalog of SNFOK.",
+}
+
+var ImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import the policy templates directory into the catalog.",
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, _ := cmd.Flags().GetString("dir")
+
+		// Make sure the catalog table and its columns exist
+		initializer.InitializeCluster()
+
+		result, err := repository.ImportCatalog(dir, auth_constants.SNFOK_CLI)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("[+] Scanned %d templates in '%s'.\n", result.Scanned, result.Directory)
+		fmt.Printf("[+] Created %d, updated %d and left %d catalog policies unchanged.\n", result.Created, result.Updated, result.Unchanged)
+		if len(result.Skipped) != 0 {
+			fmt.Printf("[!] Skipped %d templates:\n", len(result.Skipped))
+			for _, skip := range result.Skipped {
+				fmt.Printf("    %s: %s\n", skip.Path, skip.Reason)
+			}
+		}
+	},
+}
+
+func init() {
+	ImportCmd.Flags().String("dir", os.Getenv("POLICIES_TEMPLATES_DIR"), "Policy templates directory")
+	CatalogCmd.AddCommand(ImportCmd)
+}
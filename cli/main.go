@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/FearLessSaad/SNFOK/cli/commands/auth"
+	"github.com/FearLessSaad/SNFOK/cli/commands/catalog"
 	"github.com/spf13/cobra"
 )
 
@@ -27,4 +28,5 @@ var rootCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(auth.AddUserCmd)
 	rootCmd.AddCommand(auth.RotateTokenCmd)
+	rootCmd.AddCommand(catalog.CatalogCmd)
 }
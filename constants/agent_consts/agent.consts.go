@@ -8,6 +8,8 @@ const (
 	KUBERNETES_GET_ALL_NAMESPACES     = "/api/kubernetes/namespaces/all"
 	KUBERNETES_COUNT_ALL_RUNNING_PODS = "/api/kubernetes/count/pods"
 	GET_ALL_APP_LABELS                = "/api/kubernetes/get/all/labels"
+	KUBERNETES_WATCH_RESOURCES        = "/api/kubernetes/watch"
+	KUBERNETES_GET_ALL_NODES          = "/api/kubernetes/nodes/all"
 	DELETE_TETRAGON_POLICY            = "/api/policies/delete"
 )
 
@@ -15,13 +17,59 @@ func GET_ALL_NAMESPACE_RESOURCES(namespace string) string {
 	return fmt.Sprintf("/api/kubernetes/namespaces/%s/resources", namespace)
 }
 
+func KUBERNETES_GET_NODE(name string) string {
+	return fmt.Sprintf("/api/kubernetes/nodes/%s", name)
+}
+
+func KUBERNETES_POD_LOGS(namespace string, pod string) string {
+	return fmt.Sprintf("/api/kubernetes/pod/logs/%s/%s", namespace, pod)
+}
+
+func KUBERNETES_POD_FORENSICS(namespace string, pod string) string {
+	return fmt.Sprintf("/api/kubernetes/pod/forensics/%s/%s", namespace, pod)
+}
+
 const (
 	POLICIES_DEPLOY_POLICY = "/api/policies/deplye/policy"
+	POLICIES_ISOLATE_POD   = "/api/policies/isolate"
+	POLICIES_ISOLATED_PODS = "/api/policies/isolated"
+	POLICIES_RELEASE_POD   = "/api/policies/release"
+	POLICIES_MANAGED       = "/api/policies/managed"
+	POLICIES_PREVIEW       = "/api/policies/preview"
+	POLICIES_PARAMS        = "/api/policies/params"
+	POLICIES_DEPLOY_BUNDLE = "/api/policies/deploy/bundle"
+	POLICIES_UPGRADE       = "/api/policies/upgrade"
+)
+
+const (
+	POSTURE_SCAN_WORKLOADS   = "/api/posture/workloads/scan"
+	POSTURE_RBAC_ANALYSIS    = "/api/posture/rbac/analysis"
+	POSTURE_RBAC_WHO_CAN     = "/api/posture/rbac/who-can"
+	POSTURE_NETWORK_COVERAGE = "/api/posture/network/coverage"
+	POSTURE_IMAGE_INVENTORY  = "/api/posture/images"
+)
+
+// Pod Isolation
+const (
+	ISOLATION_LABEL             = "snfok.io/quarantine"
+	ISOLATION_POLICY_PREFIX     = "snfok-isolate-"
+	ISOLATED_POD_ANNOTATION     = "snfok.io/isolated-pod"
+	ISOLATED_AT_ANNOTATION      = "snfok.io/isolated-at"
+	ISOLATION_EXPIRY_ANNOTATION = "snfok.io/expires-at"
+)
+
+// Managed Policies
+const (
+	MANAGED_BY_LABEL     = "app.kubernetes.io/managed-by"
+	MANAGED_BY_VALUE     = "snfok"
+	POLICY_ID_LABEL      = "snfok.io/policy-id"
+	IMPLEMENTED_ID_LABEL = "snfok.io/implemented-id"
+	SCOPE_LABEL          = "snfok.io/scope"
 )
 
-// Ploicies Template
+// Ploicies Template, fields every template receives next to its declared parameters
 const (
-	POLICY_NAMESPACE_TEMPLATE = "{{.Namespace}}"
-	POLICY_APP_LABEL_TEMPLATE = "{{.AppLabel}}"
-	POLICY_ID_TEMPLATE        = "{{.PolicyID}}"
+	POLICY_NAMESPACE_PARAM = "Namespace"
+	POLICY_APP_LABEL_PARAM = "AppLabel"
+	POLICY_ID_PARAM        = "PolicyID"
 )
@@ -13,3 +13,80 @@ const (
 	CLUSTER_REGISTERED              = "Your cluster is registered successfully."
 	CLUSTER_ALREADY_REGISTERED      = "Cluster with entered details is already registered."
 )
+
+const (
+	POD_ISOLATED         = "Pod is isolated successfully."
+	POD_RELEASED         = "Pod is released from isolation successfully."
+	POD_ISOLATION_FAILED = "SNFOK agent was unable to change the isolation of this pod."
+	ISOLATION_NOT_FOUND  = "No active isolation found with entered details."
+)
+
+const (
+	FORENSIC_COLLECTED         = "Forensic bundle is collected and stored successfully."
+	FORENSIC_COLLECTION_FAILED = "SNFOK agent was unable to collect the forensic bundle of this pod."
+	FORENSIC_BUNDLE_NOT_FOUND  = "No forensic bundle found with entered details."
+	ALERT_NOT_FOUND            = "No alert found with entered details."
+	POD_LOGS_UNAVAILABLE       = "SNFOK agent was unable to read the logs of this pod."
+)
+
+const (
+	NODE_NOT_FOUND = "No node found with entered name."
+)
+
+const (
+	POSTURE_SCAN_COMPLETED = "Workload security scan is completed successfully."
+	POSTURE_SCAN_NOT_FOUND = "No workload security scan found. Please run a scan first."
+)
+
+const (
+	POLICY_NOT_FOUND        = "No policy found with entered details."
+	POLICY_TYPE_UNSUPPORTED = "The cluster does not support this policy type. Please install its security engine first."
+	POLICY_PREVIEW_INVALID  = "The policy would be rejected by the cluster. Please check the reported errors."
+)
+
+const (
+	CLUSTER_UPDATED   = "Cluster settings are updated successfully."
+	CLUSTER_NOT_FOUND = "No cluster found with entered details."
+)
+
+const (
+	BUNDLE_CREATED        = "Policy bundle is created successfully."
+	BUNDLE_DELETED        = "Policy bundle is deleted successfully."
+	BUNDLE_DEPLOYED       = "Every policy of the bundle is deployed successfully."
+	BUNDLE_REMOVED        = "Every policy of the bundle is removed successfully."
+	BUNDLE_NOT_FOUND      = "No policy bundle found with entered details."
+	BUNDLE_ALREADY_EXISTS = "Policy bundle with entered name already exists."
+	BUNDLE_DEPLOY_FAILED  = "A policy of the bundle failed to apply, so the bundle is rolled back. Please check the member status."
+	BUNDLE_REMOVE_FAILED  = "SNFOK agent was unable to remove every policy of the bundle. Please try again."
+)
+
+const (
+	CATALOG_IMPORTED      = "Policy templates are imported into the catalog successfully."
+	CATALOG_IMPORT_FAILED = "Unable to import the policy templates into the catalog. Please check POLICIES_TEMPLATES_DIR."
+)
+
+const (
+	TEMPLATE_CREATED   = "Policy template is created successfully."
+	TEMPLATE_UPDATED   = "Policy template is updated successfully."
+	TEMPLATE_CLONED    = "Policy template is cloned successfully."
+	TEMPLATE_DELETED   = "Policy template is deleted successfully."
+	TEMPLATE_NOT_FOUND = "No custom policy template found with entered details."
+	TEMPLATE_INVALID   = "The policy template is not valid. Please check the reported errors."
+	TEMPLATE_IN_USE    = "The policy template is deployed or part of a bundle. Please remove it from there first."
+)
+
+const (
+	POLICY_UPGRADED          = "Policy is upgraded to the new version successfully."
+	POLICY_ROLLED_BACK       = "Policy is rolled back to its previous version successfully."
+	POLICY_VERSION_NOT_FOUND = "No version of the policy found with entered details."
+	POLICY_UP_TO_DATE        = "The policy is already deployed with this version."
+	POLICY_UPGRADE_FAILED    = "SNFOK agent was unable to apply the new version, the previous version is kept. Please check the reported errors."
+	NO_PREVIOUS_VERSION      = "The policy has no previous version to roll back to."
+)
+
+const (
+	POLICY_MODE_CHANGED       = "Policy mode is changed successfully."
+	POLICY_MODE_UNCHANGED     = "The policy is already deployed with this mode."
+	POLICY_MODE_CHANGE_FAILED = "SNFOK agent was unable to apply the policy with the new mode, the previous mode is kept. Please check the reported errors."
+	POLICY_MODE_UNSUPPORTED   = "The policy cannot be deployed with this mode. Please deploy a policy written for it."
+)
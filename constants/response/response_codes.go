@@ -14,16 +14,78 @@ const (
 )
 
 const (
-	LOGIN_SUCCESS       = 1
-	CLUSTER_REGISTERED  = 2
-	CLUSETR_AVAILABLE   = 3
-	NAMESPACES_RESPONSE = 4
-	ALL_STATS           = 5
-	POLICY_DEPLOYED     = 6
+	LOGIN_SUCCESS          = 1
+	CLUSTER_REGISTERED     = 2
+	CLUSETR_AVAILABLE      = 3
+	NAMESPACES_RESPONSE    = 4
+	ALL_STATS              = 5
+	POLICY_DEPLOYED        = 6
+	POD_ISOLATED           = 7
+	POD_RELEASED           = 8
+	ISOLATED_PODS          = 9
+	FORENSIC_COLLECTED     = 10
+	FORENSIC_BUNDLES       = 11
+	NODES_RESPONSE         = 12
+	POSTURE_SCAN_COMPLETED = 13
+	POSTURE_SCANS          = 14
+	POSTURE_FINDINGS       = 15
+	RBAC_ANALYSIS          = 16
+	NETWORK_COVERAGE       = 17
+	IMAGE_INVENTORY        = 18
+	MANAGED_POLICIES       = 19
+	CLUSTER_UPDATED        = 20
+	POLICY_DRIFT           = 21
+	POLICY_PREVIEW         = 22
+	POLICY_PARAMS          = 23
+	POLICY_BUNDLES         = 24
+	BUNDLE_CREATED         = 25
+	BUNDLE_DELETED         = 26
+	BUNDLE_DEPLOYED        = 27
+	BUNDLE_REMOVED         = 28
+	CATALOG_IMPORTED       = 29
+	POLICY_CATALOG         = 30
+	POLICY_TEMPLATES       = 31
+	TEMPLATE_CREATED       = 32
+	TEMPLATE_UPDATED       = 33
+	TEMPLATE_CLONED        = 34
+	TEMPLATE_DELETED       = 35
+	POLICY_VERSIONS        = 36
+	POLICY_UPGRADES        = 37
+	POLICY_UPGRADE_PREVIEW = 38
+	POLICY_UPGRADED        = 39
+	POLICY_ROLLED_BACK     = 40
+	POLICY_MODE_CHANGED    = 41
+	POLICY_MODE_CHANGES    = 42
 )
 
 const (
 	NO_CLUSTER_AVAILABLE          = 2000
 	SNFOK_AGENT_IS_NOT_ACCESSABLE = 2001
 	CLUSTER_ALREADY_REGISTERED    = 2002
+	POD_ISOLATION_FAILED          = 2003
+	ISOLATION_NOT_FOUND           = 2004
+	FORENSIC_COLLECTION_FAILED    = 2005
+	FORENSIC_BUNDLE_NOT_FOUND     = 2006
+	ALERT_NOT_FOUND               = 2007
+	POD_LOGS_UNAVAILABLE          = 2008
+	NODE_NOT_FOUND                = 2009
+	POSTURE_SCAN_NOT_FOUND        = 2010
+	POLICY_NOT_FOUND              = 2011
+	POLICY_TYPE_UNSUPPORTED       = 2012
+	CLUSTER_NOT_FOUND             = 2013
+	BUNDLE_NOT_FOUND              = 2014
+	BUNDLE_ALREADY_EXISTS         = 2015
+	BUNDLE_DEPLOY_FAILED          = 2016
+	BUNDLE_REMOVE_FAILED          = 2017
+	CATALOG_IMPORT_FAILED         = 2018
+	TEMPLATE_NOT_FOUND            = 2019
+	TEMPLATE_INVALID              = 2020
+	TEMPLATE_IN_USE               = 2021
+	POLICY_VERSION_NOT_FOUND      = 2022
+	POLICY_UP_TO_DATE             = 2023
+	POLICY_UPGRADE_FAILED         = 2024
+	NO_PREVIOUS_VERSION           = 2025
+	POLICY_MODE_UNCHANGED         = 2026
+	POLICY_MODE_CHANGE_FAILED     = 2027
+	POLICY_MODE_UNSUPPORTED       = 2028
 )
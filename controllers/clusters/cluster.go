@@ -54,4 +54,23 @@ func ClusterInfo(router fiber.Router) {
 		response, status := repository.AddNewCluster(*details, user_id)
 		return c.Status(status).JSON(response)
 	})
+
+	// Configures whether the drift reconciler repairs the cluster
+	router.Put("/:id/drift", func(c *fiber.Ctx) error {
+		details := new(dto.DriftSettingsRequest)
+		if err := c.BodyParser(details); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(global_dto.Response[string]{
+				Status:  "error",
+				Message: message.INVALID_REQUEST_PAYLOAD,
+				Data:    nil,
+				Meta: &global_dto.Meta{
+					Code: response.INVALID_REQUEST_PAYLOAD,
+				},
+			})
+		}
+
+		user_id := c.Locals("user_id").(string)
+		response, status := repository.UpdateDriftSettings(c.Params("id"), *details, user_id)
+		return c.Status(status).JSON(response)
+	})
 }
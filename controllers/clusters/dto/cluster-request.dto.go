@@ -5,3 +5,9 @@ type ClusterRequest struct {
 	AgentPort   string `json:"agent_port" validate:"required"`
 	Description string `json:"description" validate:"required"`
 }
+
+// DriftSettingsRequest configures how the drift reconciler repairs a cluster
+type DriftSettingsRequest struct {
+	ReapplyMissing bool `json:"reapply_missing"`
+	AdoptOrphans   bool `json:"adopt_orphans"`
+}
@@ -54,3 +54,36 @@ func CheckMasterIPExists(masterIP string) (bool, error) {
 
 	return exists, nil
 }
+
+func GetClusterById(id string) (k8s.Clusters, error) {
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	cluster := new(k8s.Clusters)
+	err := conn.NewSelect().Model(cluster).Where("id = ?", id).Limit(1).Scan(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.clusters'.", logger.Field{Key: "error", Value: err.Error()})
+		return k8s.Clusters{}, err
+	}
+
+	return *cluster, nil
+}
+
+func UpdateClusterDriftSettings(data k8s.Clusters) error {
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	_, err := conn.NewUpdate().
+		Model(&data).
+		Column("reapply_missing", "adopt_orphans", "updated_by", "updated_at").
+		WherePK().
+		Exec(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute update query on 'k8s.clusters'.", logger.Field{Key: "error", Value: err.Error()})
+		return err
+	}
+
+	return nil
+}
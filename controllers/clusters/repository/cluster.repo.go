@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/FearLessSaad/SNFOK/constants/agent_consts"
 	"github.com/FearLessSaad/SNFOK/constants/message"
@@ -16,6 +17,7 @@ import (
 	"github.com/FearLessSaad/SNFOK/tooling/httpclient"
 	"github.com/FearLessSaad/SNFOK/tooling/logger"
 	"github.com/gofiber/fiber/v2"
+	"github.com/uptrace/bun"
 )
 
 type runningPods struct {
@@ -115,11 +117,13 @@ func GetAllClusters() (global_dto.Response[[]dto.ClusterResponse], int) {
 
 	for i := 0; i < len(clusters); i++ {
 		res = append(res, dto.ClusterResponse{
-			ID:          clusters[i].ID,
-			ClusterName: clusters[i].ClusterName,
-			MasterIP:    clusters[i].MasterIP,
-			AgentPort:   clusters[i].AgentPort,
-			Description: clusters[i].Description,
+			ID:             clusters[i].ID,
+			ClusterName:    clusters[i].ClusterName,
+			MasterIP:       clusters[i].MasterIP,
+			AgentPort:      clusters[i].AgentPort,
+			Description:    clusters[i].Description,
+			ReapplyMissing: clusters[i].ReapplyMissing,
+			AdoptOrphans:   clusters[i].AdoptOrphans,
 		})
 	}
 
@@ -229,3 +233,52 @@ func AddNewCluster(data dto.ClusterRequest, uid string) (global_dto.Response[str
 		},
 	}, fiber.StatusOK
 }
+
+// UpdateDriftSettings sets whether the drift reconciler re-applies missing policies and adopts orphaned objects in the cluster
+func UpdateDriftSettings(id string, data dto.DriftSettingsRequest, uid string) (global_dto.Response[dto.ClusterResponse], int) {
+
+	cluster, err := persistance.GetClusterById(id)
+	if err != nil {
+		return global_dto.Response[dto.ClusterResponse]{
+			Status:  "error",
+			Message: message.CLUSTER_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.CLUSTER_NOT_FOUND,
+			},
+		}, fiber.StatusNotFound
+	}
+
+	cluster.ReapplyMissing = data.ReapplyMissing
+	cluster.AdoptOrphans = data.AdoptOrphans
+	cluster.UpdatedBy = uid
+	cluster.UpdatedAt = bun.NullTime{Time: time.Now()}
+
+	if err := persistance.UpdateClusterDriftSettings(cluster); err != nil {
+		return global_dto.Response[dto.ClusterResponse]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[dto.ClusterResponse]{
+		Status:  "success",
+		Message: message.CLUSTER_UPDATED,
+		Data: &dto.ClusterResponse{
+			ID:             cluster.ID,
+			ClusterName:    cluster.ClusterName,
+			MasterIP:       cluster.MasterIP,
+			AgentPort:      cluster.AgentPort,
+			Description:    cluster.Description,
+			ReapplyMissing: cluster.ReapplyMissing,
+			AdoptOrphans:   cluster.AdoptOrphans,
+		},
+		Meta: &global_dto.Meta{
+			Code: response.CLUSTER_UPDATED,
+		},
+	}, fiber.StatusOK
+}
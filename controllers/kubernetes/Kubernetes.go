@@ -4,4 +4,7 @@ import "github.com/gofiber/fiber/v2"
 
 func KubernetesController(router fiber.Router) {
 	KubernetesInfo(router)
+	KubernetesWatch(router)
+	KubernetesForensics(router)
+	KubernetesLogs(router)
 }
@@ -0,0 +1,9 @@
+package dto
+
+// CollectForensicsRequest collects the forensic bundle of a pod for an alert.
+// Namespace and Pod default to the pod the alert was raised for.
+type CollectForensicsRequest struct {
+	AlertID   string `json:"alert_id" validate:"required,uuid"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+}
@@ -0,0 +1,66 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/kubernetes/dto"
+	"github.com/FearLessSaad/SNFOK/controllers/kubernetes/repository"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/security/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+func KubernetesForensics(router fiber.Router) {
+
+	router.Post("/forensics", func(c *fiber.Ctx) error {
+		details := new(dto.CollectForensicsRequest)
+		if err := c.BodyParser(details); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(global_dto.Response[string]{
+				Status:  "error",
+				Message: message.INVALID_REQUEST_PAYLOAD,
+				Data:    nil,
+				Meta: &global_dto.Meta{
+					Code: response.INVALID_REQUEST_PAYLOAD,
+				},
+			})
+		}
+		if errs := validation.ValidateStruct(details); len(errs) > 0 {
+			errors := make([]any, len(errs))
+			for i, err := range errs {
+				errors[i] = err
+			}
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(global_dto.Response[string]{
+				Status:  "error",
+				Message: message.FAILED_DATA_VALIDATION,
+				Errors:  errors,
+				Data:    nil,
+				Meta: &global_dto.Meta{
+					Code: response.FAILED_DATA_VALIDATION,
+				},
+			})
+		}
+
+		user_id := c.Locals("user_id").(string)
+		response, status := repository.CollectForensicBundle(*details, user_id)
+		return c.Status(status).JSON(response)
+	})
+
+	router.Get("/forensics", func(c *fiber.Ctx) error {
+		response, status := repository.GetForensicBundles(c.Query("alert_id"))
+		return c.Status(status).JSON(response)
+	})
+
+	router.Get("/forensics/:id/download", func(c *fiber.Ctx) error {
+		bundle, response, status := repository.GetForensicBundle(c.AllParams()["id"])
+		if status != fiber.StatusOK {
+			return c.Status(status).JSON(response)
+		}
+
+		c.Set("Content-Type", "application/gzip")
+		c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", bundle.FileName))
+		c.Set("X-Content-SHA256", bundle.SHA256)
+		return c.Status(fiber.StatusOK).Send(bundle.Bundle)
+	})
+}
@@ -22,4 +22,14 @@ func KubernetesInfo(router fiber.Router) {
 		return c.Status(status).JSON(response)
 	})
 
+	router.Get("/nodes/all", func(c *fiber.Ctx) error {
+		response, status := repository.GetAllNodes()
+		return c.Status(status).JSON(response)
+	})
+
+	router.Get("/nodes/:name", func(c *fiber.Ctx) error {
+		response, status := repository.GetNode(c.AllParams()["name"])
+		return c.Status(status).JSON(response)
+	})
+
 }
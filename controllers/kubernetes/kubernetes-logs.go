@@ -0,0 +1,30 @@
+package kubernetes
+
+import (
+	"context"
+
+	"github.com/FearLessSaad/SNFOK/controllers/kubernetes/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+func KubernetesLogs(router fiber.Router) {
+
+	// Relays the log of a pod container from the agent.
+	// Query: container, since (duration or RFC3339), tailLines, previous and follow.
+	router.Get("/pods/:namespace/:pod/logs", func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		res, response, status := repository.OpenPodLogs(ctx, c.AllParams()["namespace"], c.AllParams()["pod"], string(c.Request().URI().QueryString()))
+		if res == nil {
+			cancel()
+			return c.Status(status).JSON(response)
+		}
+
+		c.Set("Content-Type", "text/plain; charset=utf-8")
+		c.Set("Cache-Control", "no-cache")
+		c.Set("X-Accel-Buffering", "no")
+
+		relayStream(c, res, cancel)
+		return nil
+	})
+}
@@ -0,0 +1,91 @@
+package kubernetes
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/FearLessSaad/SNFOK/controllers/kubernetes/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+func KubernetesWatch(router fiber.Router) {
+
+	// Relays the agent's Server-Sent Events stream of resource changes to the UI
+	router.Get("/watch", func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		res, response, status := repository.OpenResourceWatch(ctx, string(c.Request().URI().QueryString()), c.Get("Last-Event-ID"))
+		if res == nil {
+			cancel()
+			return c.Status(status).JSON(response)
+		}
+
+		c.Set("Content-Type", "text/event-stream")
+		c.Set("Cache-Control", "no-cache")
+		c.Set("Connection", "keep-alive")
+		c.Set("X-Accel-Buffering", "no")
+
+		relayStream(c, res, cancel)
+		return nil
+	})
+}
+
+// relayIdleTimeout ends a relayed stream when the agent sends nothing for this long. The agent writes a heartbeat
+// to idle streams every 15 seconds, so a silent stream is stalled, and a write is the only way to notice that the
+// UI has disconnected.
+const relayIdleTimeout = time.Minute
+
+// relayStream copies an agent stream to the client as it arrives and releases it once either side is done
+func relayStream(c *fiber.Ctx, res *http.Response, cancel context.CancelFunc) {
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		defer res.Body.Close()
+
+		// Reading blocks until the agent sends something, so it runs apart from the writes. The read fails
+		// once the request is cancelled when this writer returns.
+		chunks := make(chan []byte)
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			defer close(chunks)
+			for {
+				buffer := make([]byte, 4096)
+				n, err := res.Body.Read(buffer)
+				if n > 0 {
+					select {
+					case chunks <- buffer[:n]:
+					case <-done:
+						return
+					}
+				}
+				if err != nil {
+					return
+				}
+			}
+		}()
+
+		idle := time.NewTimer(relayIdleTimeout)
+		defer idle.Stop()
+
+		for {
+			select {
+			case chunk, open := <-chunks:
+				if !open {
+					return
+				}
+				if _, err := w.Write(chunk); err != nil {
+					return
+				}
+				// A failed flush means the UI has disconnected
+				if err := w.Flush(); err != nil {
+					return
+				}
+				idle.Reset(relayIdleTimeout)
+			case <-idle.C:
+				return
+			}
+		}
+	})
+}
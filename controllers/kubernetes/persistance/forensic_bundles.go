@@ -0,0 +1,60 @@
+package persistance
+
+import (
+	"context"
+
+	"github.com/FearLessSaad/SNFOK/db"
+	"github.com/FearLessSaad/SNFOK/db/models/k8s"
+	"github.com/FearLessSaad/SNFOK/tooling/logger"
+)
+
+// GetAllForensicBundles lists the bundles without their archive, optionally only those of one alert
+func GetAllForensicBundles(alert_id string) ([]k8s.ForensicBundles, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	bundles := new([]k8s.ForensicBundles)
+	query := conn.NewSelect().Model(bundles).ExcludeColumn("bundle")
+	if alert_id != "" {
+		query = query.Where("alert_id = ?", alert_id)
+	}
+	err := query.Order("collected_at DESC").Scan(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.forensic_bundles'.", logger.Field{Key: "error", Value: err.Error()})
+		return []k8s.ForensicBundles{}, err
+	}
+
+	return *bundles, nil
+}
+
+func GetForensicBundleById(id string) (k8s.ForensicBundles, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	bundle := new(k8s.ForensicBundles)
+	err := conn.NewSelect().Model(bundle).Where("id = ?", id).Limit(1).Scan(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.forensic_bundles'.", logger.Field{Key: "error", Value: err.Error()})
+		return k8s.ForensicBundles{}, err
+	}
+
+	return *bundle, nil
+}
+
+func CreateForensicBundle(data *k8s.ForensicBundles) error {
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	_, err := conn.NewInsert().Model(data).Returning("id").Exec(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute insert query on 'k8s.forensic_bundles'.", logger.Field{Key: "error", Value: err.Error()})
+		return err
+	}
+
+	return nil
+}
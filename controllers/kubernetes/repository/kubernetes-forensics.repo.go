@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/FearLessSaad/SNFOK/constants/agent_consts"
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/kubernetes/dto"
+	"github.com/FearLessSaad/SNFOK/controllers/kubernetes/persistance"
+	"github.com/FearLessSaad/SNFOK/db/models/k8s"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/httpclient"
+	"github.com/FearLessSaad/SNFOK/tooling/logger"
+	"github.com/gofiber/fiber"
+
+	cluster "github.com/FearLessSaad/SNFOK/controllers/clusters/persistance"
+)
+
+// forensicsTimeout leaves the agent enough time to read the logs of every container
+const forensicsTimeout = 3 * time.Minute
+
+// CollectForensicBundle asks the agent for the forensic bundle of a pod and stores it linked to the alert
+func CollectForensicBundle(data dto.CollectForensicsRequest, uid string) (global_dto.Response[k8s.ForensicBundles], int) {
+
+	alert, err := cluster.GetAlertsById(data.AlertID)
+	if err != nil {
+		return global_dto.Response[k8s.ForensicBundles]{
+			Status:  "error",
+			Message: message.ALERT_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.ALERT_NOT_FOUND,
+			},
+		}, fiber.StatusNotFound
+	}
+	if data.Namespace == "" || data.Pod == "" {
+		data.Namespace = alert.Namespace
+		data.Pod = alert.Pod
+	}
+
+	clusters, _ := cluster.GetAllClusters()
+	if len(clusters) == 0 {
+		return global_dto.Response[k8s.ForensicBundles]{
+			Status:  "error",
+			Message: message.NO_REGISTERED_CLUSTER_AVAILABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.NO_CLUSTER_AVAILABLE,
+			},
+		}, fiber.StatusNotFound
+	}
+	ip := clusters[0].MasterIP
+	port := clusters[0].AgentPort
+
+	client := httpclient.NewClient(forensicsTimeout)
+
+	res, err := client.Get("http://"+ip+":"+fmt.Sprintf("%d", port)+agent_consts.KUBERNETES_POD_FORENSICS(data.Namespace, data.Pod), map[string]string{})
+	if err != nil {
+		logger.Log(logger.DEBUG, "HTTP Request Error", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[k8s.ForensicBundles]{
+			Status:  "error",
+			Message: message.FORENSIC_COLLECTION_FAILED,
+			Errors:  []any{err.Error()},
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.FORENSIC_COLLECTION_FAILED,
+			},
+		}, fiber.StatusBadGateway
+	}
+
+	manifest, err := readForensicManifest(res.Body)
+	if err != nil {
+		logger.Log(logger.DEBUG, "Invalid Forensic Bundle", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[k8s.ForensicBundles]{
+			Status:  "error",
+			Message: message.FORENSIC_COLLECTION_FAILED,
+			Errors:  []any{err.Error()},
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.FORENSIC_COLLECTION_FAILED,
+			},
+		}, fiber.StatusBadGateway
+	}
+
+	sum := sha256.Sum256(res.Body)
+	bundle := k8s.ForensicBundles{
+		ClusterID:   clusters[0].ID,
+		AlertID:     alert.ID,
+		Namespace:   manifest.Namespace,
+		Pod:         manifest.Pod,
+		PodUID:      manifest.PodUID,
+		FileName:    fmt.Sprintf("%s_%s_%s.tar.gz", manifest.Namespace, manifest.Pod, manifest.CollectedAt.Format("20060102T150405Z")),
+		Size:        int64(len(res.Body)),
+		SHA256:      hex.EncodeToString(sum[:]),
+		CollectedAt: manifest.CollectedAt,
+		Manifest:    manifest,
+		Bundle:      res.Body,
+		AuditFields: k8s.AuditFields{
+			CreatedBy: uid,
+			CreatedAt: time.Now(),
+		},
+	}
+
+	if err := persistance.CreateForensicBundle(&bundle); err != nil {
+		return global_dto.Response[k8s.ForensicBundles]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.CREATION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[k8s.ForensicBundles]{
+		Status:  "success",
+		Message: message.FORENSIC_COLLECTED,
+		Data:    &bundle,
+		Meta: &global_dto.Meta{
+			Code: response.FORENSIC_COLLECTED,
+		},
+	}, fiber.StatusOK
+}
+
+// GetForensicBundles lists the stored bundles, optionally only those linked to one alert
+func GetForensicBundles(alert_id string) (global_dto.Response[[]k8s.ForensicBundles], int) {
+
+	bundles, err := persistance.GetAllForensicBundles(alert_id)
+	if err != nil {
+		return global_dto.Response[[]k8s.ForensicBundles]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[[]k8s.ForensicBundles]{
+		Status:  "success",
+		Message: "",
+		Data:    &bundles,
+		Meta: &global_dto.Meta{
+			Code: response.FORENSIC_BUNDLES,
+		},
+	}, fiber.StatusOK
+}
+
+// GetForensicBundle returns a stored bundle including its archive
+func GetForensicBundle(id string) (k8s.ForensicBundles, global_dto.Response[string], int) {
+
+	bundle, err := persistance.GetForensicBundleById(id)
+	if err != nil {
+		return k8s.ForensicBundles{}, global_dto.Response[string]{
+			Status:  "error",
+			Message: message.FORENSIC_BUNDLE_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.FORENSIC_BUNDLE_NOT_FOUND,
+			},
+		}, fiber.StatusNotFound
+	}
+
+	return bundle, global_dto.Response[string]{}, fiber.StatusOK
+}
+
+// readForensicManifest extracts the manifest from a forensic bundle and verifies the hash of every file in it
+func readForensicManifest(bundle []byte) (agent_dto.ForensicManifest, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(bundle))
+	if err != nil {
+		return agent_dto.ForensicManifest{}, err
+	}
+	defer gz.Close()
+
+	hashes := make(map[string]string)
+	var manifest *agent_dto.ForensicManifest
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return agent_dto.ForensicManifest{}, err
+		}
+
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return agent_dto.ForensicManifest{}, err
+		}
+
+		if header.Name == agent_dto.FORENSIC_MANIFEST_FILE {
+			manifest = new(agent_dto.ForensicManifest)
+			if err := json.Unmarshal(content, manifest); err != nil {
+				return agent_dto.ForensicManifest{}, err
+			}
+			continue
+		}
+		sum := sha256.Sum256(content)
+		hashes[header.Name] = hex.EncodeToString(sum[:])
+	}
+
+	if manifest == nil {
+		return agent_dto.ForensicManifest{}, fmt.Errorf("forensic bundle has no %s", agent_dto.FORENSIC_MANIFEST_FILE)
+	}
+	for _, file := range manifest.Files {
+		if hashes[file.Name] != file.SHA256 {
+			return agent_dto.ForensicManifest{}, fmt.Errorf("hash mismatch for %s in forensic bundle", file.Name)
+		}
+	}
+
+	return *manifest, nil
+}
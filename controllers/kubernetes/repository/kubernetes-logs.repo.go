@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/FearLessSaad/SNFOK/constants/agent_consts"
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/clusters/persistance"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/httpclient"
+	"github.com/FearLessSaad/SNFOK/tooling/logger"
+	"github.com/gofiber/fiber"
+)
+
+// OpenPodLogs opens the agent's log stream of a pod. The query string (container, since, tailLines,
+// previous, follow) is passed through unchanged. On success the caller must close the response body.
+func OpenPodLogs(ctx context.Context, namespace string, pod string, query string) (*http.Response, global_dto.Response[string], int) {
+
+	clusters, _ := persistance.GetAllClusters()
+	if len(clusters) == 0 {
+		return nil, global_dto.Response[string]{
+			Status:  "error",
+			Message: message.NO_REGISTERED_CLUSTER_AVAILABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.NO_CLUSTER_AVAILABLE,
+			},
+		}, fiber.StatusNotFound
+	}
+	ip := clusters[0].MasterIP
+	port := clusters[0].AgentPort
+
+	path := agent_consts.KUBERNETES_POD_LOGS(namespace, pod)
+	if query != "" {
+		path += "?" + query
+	}
+
+	client := httpclient.NewClient(0)
+
+	res, err := client.Stream(ctx, "http://"+ip+":"+fmt.Sprintf("%d", port)+path, map[string]string{})
+	if err != nil {
+		logger.Log(logger.DEBUG, "HTTP Request Error", logger.Field{Key: "error", Value: err.Error()})
+		return nil, global_dto.Response[string]{
+			Status:  "error",
+			Message: message.POD_LOGS_UNAVAILABLE,
+			Errors:  []any{err.Error()},
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POD_LOGS_UNAVAILABLE,
+			},
+		}, fiber.StatusBadGateway
+	}
+
+	return res, global_dto.Response[string]{}, fiber.StatusOK
+}
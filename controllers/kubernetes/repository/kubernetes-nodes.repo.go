@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/FearLessSaad/SNFOK/constants/agent_consts"
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/clusters/persistance"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/httpclient"
+	"github.com/FearLessSaad/SNFOK/tooling/logger"
+	"github.com/gofiber/fiber"
+)
+
+// GetAllNodes returns the inventory of every node with the security engines running on it
+func GetAllNodes() (global_dto.Response[[]agent_dto.NodeDetails], int) {
+
+	clusters, _ := persistance.GetAllClusters()
+	if len(clusters) == 0 {
+		return global_dto.Response[[]agent_dto.NodeDetails]{
+			Status:  "error",
+			Message: message.NO_REGISTERED_CLUSTER_AVAILABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.NO_CLUSTER_AVAILABLE,
+			},
+		}, fiber.StatusNotFound
+	}
+	ip := clusters[0].MasterIP
+	port := clusters[0].AgentPort
+
+	client := httpclient.NewClient(0)
+
+	res, err := client.Get("http://"+ip+":"+fmt.Sprintf("%d", port)+agent_consts.KUBERNETES_GET_ALL_NODES, map[string]string{})
+	if err != nil {
+		logger.Log(logger.DEBUG, "HTTP Request Error", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[[]agent_dto.NodeDetails]{
+			Status:  "error",
+			Message: message.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			},
+		}, fiber.StatusBadGateway
+	}
+
+	var res_data []agent_dto.NodeDetails
+	if err := json.Unmarshal(res.Body, &res_data); err != nil {
+		logger.Log(logger.DEBUG, "Unmarshal Response", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[[]agent_dto.NodeDetails]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[[]agent_dto.NodeDetails]{
+		Status:  "success",
+		Message: "",
+		Data:    &res_data,
+		Meta: &global_dto.Meta{
+			Code: response.NODES_RESPONSE,
+		},
+	}, fiber.StatusOK
+}
+
+// GetNode returns the inventory of a single node
+func GetNode(name string) (global_dto.Response[agent_dto.NodeDetails], int) {
+
+	clusters, _ := persistance.GetAllClusters()
+	if len(clusters) == 0 {
+		return global_dto.Response[agent_dto.NodeDetails]{
+			Status:  "error",
+			Message: message.NO_REGISTERED_CLUSTER_AVAILABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.NO_CLUSTER_AVAILABLE,
+			},
+		}, fiber.StatusNotFound
+	}
+	ip := clusters[0].MasterIP
+	port := clusters[0].AgentPort
+
+	client := httpclient.NewClient(0)
+
+	res, err := client.Get("http://"+ip+":"+fmt.Sprintf("%d", port)+agent_consts.KUBERNETES_GET_NODE(name), map[string]string{})
+	if err != nil {
+		logger.Log(logger.DEBUG, "HTTP Request Error", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[agent_dto.NodeDetails]{
+			Status:  "error",
+			Message: message.NODE_NOT_FOUND,
+			Errors:  []any{err.Error()},
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.NODE_NOT_FOUND,
+			},
+		}, fiber.StatusNotFound
+	}
+
+	var res_data agent_dto.NodeDetails
+	if err := json.Unmarshal(res.Body, &res_data); err != nil {
+		logger.Log(logger.DEBUG, "Unmarshal Response", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[agent_dto.NodeDetails]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[agent_dto.NodeDetails]{
+		Status:  "success",
+		Message: "",
+		Data:    &res_data,
+		Meta: &global_dto.Meta{
+			Code: response.NODES_RESPONSE,
+		},
+	}, fiber.StatusOK
+}
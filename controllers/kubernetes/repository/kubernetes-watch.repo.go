@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/FearLessSaad/SNFOK/constants/agent_consts"
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/clusters/persistance"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/httpclient"
+	"github.com/FearLessSaad/SNFOK/tooling/logger"
+	"github.com/gofiber/fiber"
+)
+
+// OpenResourceWatch opens the agent's resource event stream. The query string is passed through
+// unchanged. On success the caller owns the returned response and must close its body.
+func OpenResourceWatch(ctx context.Context, query string, last_event_id string) (*http.Response, global_dto.Response[string], int) {
+
+	clusters, _ := persistance.GetAllClusters()
+	if len(clusters) == 0 {
+		return nil, global_dto.Response[string]{
+			Status:  "error",
+			Message: message.NO_REGISTERED_CLUSTER_AVAILABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.NO_CLUSTER_AVAILABLE,
+			},
+		}, fiber.StatusNotFound
+	}
+	ip := clusters[0].MasterIP
+	port := clusters[0].AgentPort
+
+	path := agent_consts.KUBERNETES_WATCH_RESOURCES
+	if query != "" {
+		path += "?" + query
+	}
+
+	headers := map[string]string{"Accept": "text/event-stream"}
+	if last_event_id != "" {
+		headers["Last-Event-ID"] = last_event_id
+	}
+
+	client := httpclient.NewClient(0)
+
+	res, err := client.Stream(ctx, "http://"+ip+":"+fmt.Sprintf("%d", port)+path, headers)
+	if err != nil {
+		logger.Log(logger.DEBUG, "HTTP Request Error", logger.Field{Key: "error", Value: err.Error()})
+		return nil, global_dto.Response[string]{
+			Status:  "error",
+			Message: message.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			},
+		}, fiber.StatusBadGateway
+	}
+
+	return res, global_dto.Response[string]{}, fiber.StatusOK
+}
@@ -6,4 +6,10 @@ import (
 
 func PoliciesController(router fiber.Router) {
 	DeployTetragonPolicy(router)
+	PodIsolation(router)
+	PolicyBundles(router)
+	PolicyCatalog(router)
+	PolicyTemplates(router)
+	PolicyUpgrades(router)
+	PolicyModes(router)
 }
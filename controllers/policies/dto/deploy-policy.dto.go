@@ -0,0 +1,28 @@
+package dto
+
+import "github.com/FearLessSaad/SNFOK/shared/agent_dto"
+
+// DeployTarget selects where a policy is deployed. Scope defaults to WORKLOAD, the pods matching AppLabel or
+// Selector in Namespace; NAMESPACE covers every pod in Namespace and CLUSTER every pod in the cluster,
+// optionally limited by Namespaces and ExcludedNamespaces. Selector replaces the app=<AppLabel> selector of the template.
+// A Tetragon TracingPolicy cannot be limited to pods at CLUSTER scope: it applies to every process on every node,
+// including host and non-Kubernetes processes, and is refused unless IncludeHost is set.
+type DeployTarget struct {
+	Scope              string                   `json:"scope" validate:"omitempty,oneof=WORKLOAD NAMESPACE CLUSTER"`
+	Namespace          string                   `json:"namespace" validate:"required_unless=Scope CLUSTER"`
+	AppLabel           string                   `json:"app_label"`
+	Selector           *agent_dto.LabelSelector `json:"selector"`
+	Namespaces         []string                 `json:"namespaces"`
+	ExcludedNamespaces []string                 `json:"excluded_namespaces"`
+	IncludeHost        bool                     `json:"include_host"`
+}
+
+// DeployPolicyRequest deploys a catalog policy. Params holds the values of the parameters the template declares;
+// parameters that are left out take their default. Mode deploys the policy in audit or enforce mode instead of
+// the mode of the catalog policy; the variant of the policy written for the mode is deployed when it has one.
+type DeployPolicyRequest struct {
+	PolicyID string `json:"policy_id" validate:"required,uuid"`
+	DeployTarget
+	Params map[string]interface{} `json:"params"`
+	Mode   string                 `json:"mode" validate:"omitempty,oneof=AUDIT ENFORCE"`
+}
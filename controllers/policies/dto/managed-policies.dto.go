@@ -0,0 +1,11 @@
+package dto
+
+import "github.com/FearLessSaad/SNFOK/shared/agent_dto"
+
+// ManagedPolicy is a SNFOK-managed object in the cluster.
+// Tracked is false when no implemented policy record exists for it, e.g. after the database was lost.
+type ManagedPolicy struct {
+	agent_dto.ManagedObject
+	PolicyTitle string `json:"policy_title,omitempty"`
+	Tracked     bool   `json:"tracked"`
+}
@@ -0,0 +1,8 @@
+package dto
+
+type IsolatePodRequest struct {
+	Namespace  string `json:"namespace" validate:"required"`
+	Pod        string `json:"pod" validate:"required"`
+	Reason     string `json:"reason"`
+	TTLSeconds int64  `json:"ttl_seconds" validate:"gte=0"`
+}
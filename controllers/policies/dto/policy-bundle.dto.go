@@ -0,0 +1,16 @@
+package dto
+
+// CreateBundleRequest defines a bundle of catalog policies that are deployed together, in the given order
+type CreateBundleRequest struct {
+	Name        string   `json:"name" validate:"required,max=150"`
+	Description string   `json:"description"`
+	PolicyIDs   []string `json:"policy_ids" validate:"required,min=1,unique,dive,uuid"`
+}
+
+// DeployBundleRequest deploys every policy of a bundle to the same target. Params holds the template
+// parameter values of each member, keyed by catalog policy id.
+type DeployBundleRequest struct {
+	BundleID string `json:"bundle_id" validate:"required,uuid"`
+	DeployTarget
+	Params map[string]map[string]interface{} `json:"params"`
+}
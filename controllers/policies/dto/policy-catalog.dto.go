@@ -0,0 +1,32 @@
+package dto
+
+import "time"
+
+// CatalogImportSkip is a template the catalog import could not use
+type CatalogImportSkip struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// CatalogImportResult is the outcome of one walk of the templates directory
+type CatalogImportResult struct {
+	Directory  string              `json:"directory"`
+	ImportedAt time.Time           `json:"imported_at"`
+	Scanned    int                 `json:"scanned"`
+	Created    int                 `json:"created"`
+	Updated    int                 `json:"updated"`
+	Unchanged  int                 `json:"unchanged"`
+	Skipped    []CatalogImportSkip `json:"skipped"`
+}
+
+// CatalogQuery searches and filters the policy catalog. Page and PerPage default to 1 and 20.
+type CatalogQuery struct {
+	Search     string `query:"q"`
+	PolicyType string `query:"type" validate:"omitempty,oneof=TETRAGON KUBEARMOR NETWORKPOLICY"`
+	Source     string `query:"source" validate:"omitempty,oneof=CATALOG CUSTOM"`
+	Framework  string `query:"framework"` // e.g. MITRE, NIST, CIS or PCI-DSS
+	Control    string `query:"control"`   // Technique, control or requirement of a framework, e.g. T1136.001 or 10.2.1
+	Mode       string `query:"mode" validate:"omitempty,oneof=AUDIT ENFORCE"`
+	Page       int    `query:"page" validate:"omitempty,min=1"`
+	PerPage    int    `query:"per_page" validate:"omitempty,min=1,max=100"`
+}
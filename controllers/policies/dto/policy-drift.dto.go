@@ -0,0 +1,21 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+)
+
+// DriftReport is the outcome of the last drift reconciliation of a cluster.
+// Orphaned lists SNFOK-managed objects in the cluster that no implemented policy record accounts for.
+type DriftReport struct {
+	ClusterID   string                    `json:"cluster_id"`
+	ClusterName string                    `json:"cluster_name"`
+	CheckedAt   time.Time                 `json:"checked_at"`
+	InSync      int                       `json:"in_sync"`
+	Missing     int                       `json:"missing"`
+	Reapplied   int                       `json:"reapplied"`
+	Adopted     int                       `json:"adopted"`
+	Orphaned    []agent_dto.ManagedObject `json:"orphaned"`
+	Error       string                    `json:"error,omitempty"`
+}
@@ -0,0 +1,20 @@
+package dto
+
+import (
+	"github.com/FearLessSaad/SNFOK/db/models/k8s"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+)
+
+// PolicyModeRequest switches an implemented policy between audit and enforce mode
+type PolicyModeRequest struct {
+	Mode   string `json:"mode" validate:"required,oneof=AUDIT ENFORCE"`
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+// PolicyModeResult is the implemented policy after its mode changed, with the objects now applied
+type PolicyModeResult struct {
+	Policy   k8s.ImplimentedPolicies   `json:"policy"`
+	FromMode string                    `json:"from_mode"`
+	ToMode   string                    `json:"to_mode"`
+	Objects  []agent_dto.AppliedObject `json:"objects,omitempty"`
+}
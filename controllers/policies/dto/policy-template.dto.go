@@ -0,0 +1,20 @@
+package dto
+
+// PolicyTemplateRequest creates or replaces a custom policy template. Mode is inferred from the actions of the
+// template when it is empty.
+type PolicyTemplateRequest struct {
+	Title       string   `json:"title" validate:"required,max=255"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	Mode        string   `json:"mode" validate:"omitempty,oneof=AUDIT ENFORCE"`
+	Content     string   `json:"content" validate:"required"`
+	VariantID   string   `json:"variant_id" validate:"omitempty,uuid"` // Policy that deploys the template in the other mode
+}
+
+// ClonePolicyTemplateRequest copies a catalog policy or custom template into a new custom template.
+// The title defaults to the title of the source with a "(copy)" suffix. The copy has no variant unless VariantID
+// names one.
+type ClonePolicyTemplateRequest struct {
+	Title     string `json:"title" validate:"omitempty,max=255"`
+	VariantID string `json:"variant_id" validate:"omitempty,uuid"`
+}
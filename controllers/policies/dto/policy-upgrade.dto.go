@@ -0,0 +1,40 @@
+package dto
+
+import (
+	"github.com/FearLessSaad/SNFOK/db/models/k8s"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+)
+
+// PolicyUpgrade is an implemented policy whose catalog policy has a newer version than the one it was rendered from
+type PolicyUpgrade struct {
+	ImplementedID  string `json:"implemented_id"`
+	PolicyID       string `json:"policy_id"`
+	PolicyTitle    string `json:"policy_title"`
+	Scope          string `json:"scope"`
+	Namespace      string `json:"namespace,omitempty"`
+	CurrentVersion int    `json:"current_version"` // 0 when the version was not recorded
+	LatestVersion  int    `json:"latest_version"`
+}
+
+// PolicyUpgradeRequest selects the version an implemented policy is moved to, the latest version when empty
+type PolicyUpgradeRequest struct {
+	Version int `json:"version" query:"version" validate:"omitempty,min=1"`
+}
+
+// PolicyUpgradePreview shows what an upgrade changes before it is applied
+type PolicyUpgradePreview struct {
+	ImplementedID string   `json:"implemented_id"`
+	FromVersion   int      `json:"from_version"`
+	ToVersion     int      `json:"to_version"`
+	Diff          string   `json:"diff"`                     // Unified diff of both versions rendered with the parameter values of the policy
+	DroppedParams []string `json:"dropped_params,omitempty"` // Parameter values the new version does not declare anymore
+	Errors        []string `json:"errors,omitempty"`         // Why a version cannot be rendered, the upgrade would fail
+}
+
+// PolicyUpgradeResult is the implemented policy after an upgrade or rollback, with the objects now applied
+type PolicyUpgradeResult struct {
+	Policy      k8s.ImplimentedPolicies   `json:"policy"`
+	FromVersion int                       `json:"from_version"`
+	ToVersion   int                       `json:"to_version"`
+	Objects     []agent_dto.AppliedObject `json:"objects,omitempty"`
+}
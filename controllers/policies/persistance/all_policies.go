@@ -2,10 +2,12 @@ package persistance
 
 import (
 	"context"
+	"strings"
 
 	"github.com/FearLessSaad/SNFOK/db"
 	"github.com/FearLessSaad/SNFOK/db/models/k8s"
 	"github.com/FearLessSaad/SNFOK/tooling/logger"
+	"github.com/uptrace/bun"
 )
 
 func GetAllPolices() ([]k8s.AllPolicies, error) {
@@ -24,6 +26,61 @@ func GetAllPolices() ([]k8s.AllPolicies, error) {
 	return *all_policies, nil
 }
 
+// catalogDocument is the text the catalog search matches, the title and description of a policy
+const catalogDocument = "to_tsvector('english', coalesce(h.policy_title, '') || ' ' || coalesce(h.description, ''))"
+
+// catalogTagMatch matches policies with a tag equal to the argument, ignoring case
+const catalogTagMatch = "EXISTS (SELECT 1 FROM jsonb_array_elements_text(CASE WHEN jsonb_typeof(h.tags) = 'array' THEN h.tags ELSE '[]'::jsonb END) AS tag WHERE upper(tag) = upper(?))"
+
+// likeEscaper escapes the wildcards of a LIKE pattern, so search text matches literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchPolicies returns one page of the catalog policies matching the filters, best search matches first,
+// together with the number of matching policies. Empty filters are ignored.
+func SearchPolicies(search string, policy_type string, source string, framework string, control string, mode string, limit int, offset int) ([]k8s.AllPolicies, int, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	policies := new([]k8s.AllPolicies)
+	query := conn.NewSelect().Model(policies)
+	if search != "" {
+		query = query.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where(catalogDocument+" @@ websearch_to_tsquery('english', ?)", search).
+				WhereOr(`h.policy_title ILIKE ? ESCAPE '\'`, "%"+likeEscaper.Replace(search)+"%")
+		})
+	}
+	if policy_type != "" {
+		query = query.Where("h.policy_type = ?", policy_type)
+	}
+	if source != "" {
+		query = query.Where("h.source = ?", source)
+	}
+	if framework != "" {
+		// Templates outside the framework packs can still name the framework in their tags
+		query = query.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("upper(h.framework) = upper(?)", framework).WhereOr(catalogTagMatch, framework)
+		})
+	}
+	if control != "" {
+		query = query.Where(catalogTagMatch, control)
+	}
+	if mode != "" {
+		query = query.Where("h.mode = ?", mode)
+	}
+	if search != "" {
+		query = query.OrderExpr("ts_rank("+catalogDocument+", websearch_to_tsquery('english', ?)) DESC", search)
+	}
+	count, err := query.Order("h.policy_title ASC", "h.id ASC").Limit(limit).Offset(offset).ScanAndCount(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.all_policies'.", logger.Field{Key: "error", Value: err.Error()})
+		return []k8s.AllPolicies{}, 0, err
+	}
+
+	return *policies, count, nil
+}
+
 func GetPlicysById(id string) (k8s.AllPolicies, error) {
 
 	conn := db.GetDB()
@@ -39,3 +96,188 @@ func GetPlicysById(id string) (k8s.AllPolicies, error) {
 
 	return *alert, nil
 }
+
+// GetPolicyByContentHash returns the catalog policy with the template hash, if any
+func GetPolicyByContentHash(hash string) (k8s.AllPolicies, bool, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	policies := new([]k8s.AllPolicies)
+	err := conn.NewSelect().Model(policies).Where("content_hash = ?", hash).Limit(1).Scan(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.all_policies'.", logger.Field{Key: "error", Value: err.Error()})
+		return k8s.AllPolicies{}, false, err
+	}
+	if len(*policies) == 0 {
+		return k8s.AllPolicies{}, false, nil
+	}
+
+	return (*policies)[0], true, nil
+}
+
+// GetPolicyByFilePath returns the catalog policy rendered from the template file, if any
+func GetPolicyByFilePath(file_path string) (k8s.AllPolicies, bool, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	policies := new([]k8s.AllPolicies)
+	err := conn.NewSelect().Model(policies).Where("policy_file_path = ?", file_path).Order("created_at DESC").Limit(1).Scan(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.all_policies'.", logger.Field{Key: "error", Value: err.Error()})
+		return k8s.AllPolicies{}, false, err
+	}
+	if len(*policies) == 0 {
+		return k8s.AllPolicies{}, false, nil
+	}
+
+	return (*policies)[0], true, nil
+}
+
+// GetPolicyByVariant returns a policy that declares the policy as its variant, if any
+func GetPolicyByVariant(id string) (k8s.AllPolicies, bool, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	policies := new([]k8s.AllPolicies)
+	err := conn.NewSelect().Model(policies).Where("variant_id = ?", id).Order("created_at DESC").Limit(1).Scan(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.all_policies'.", logger.Field{Key: "error", Value: err.Error()})
+		return k8s.AllPolicies{}, false, err
+	}
+	if len(*policies) == 0 {
+		return k8s.AllPolicies{}, false, nil
+	}
+
+	return (*policies)[0], true, nil
+}
+
+// CreatePolicyWithVersion stores a new policy together with its first version in a single transaction
+func CreatePolicyWithVersion(data *k8s.AllPolicies, version *k8s.PolicyVersions) error {
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	err := conn.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		data.Version = version.Version
+		if _, err := tx.NewInsert().Model(data).Returning("*").Exec(ctx); err != nil {
+			return err
+		}
+		version.PolicyID = data.ID
+		_, err := tx.NewInsert().Model(version).Returning("*").Exec(ctx)
+		return err
+	})
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute insert query on 'k8s.all_policies'.", logger.Field{Key: "error", Value: err.Error()})
+		return err
+	}
+
+	return nil
+}
+
+func UpdatePolicy(data k8s.AllPolicies) error {
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	_, err := conn.NewUpdate().Model(&data).WherePK().Exec(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute update query on 'k8s.all_policies'.", logger.Field{Key: "error", Value: err.Error()})
+		return err
+	}
+
+	return nil
+}
+
+// UpdatePolicyWithVersion stores a changed policy together with its new version, if any, in a single transaction
+func UpdatePolicyWithVersion(data k8s.AllPolicies, version *k8s.PolicyVersions) error {
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	err := conn.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if version != nil {
+			if _, err := tx.NewInsert().Model(version).Returning("*").Exec(ctx); err != nil {
+				return err
+			}
+		}
+		_, err := tx.NewUpdate().Model(&data).WherePK().Exec(ctx)
+		return err
+	})
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute update query on 'k8s.all_policies'.", logger.Field{Key: "error", Value: err.Error()})
+		return err
+	}
+
+	return nil
+}
+
+// GetPoliciesBySource returns the catalog policies of one source, newest first
+func GetPoliciesBySource(source string) ([]k8s.AllPolicies, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	policies := new([]k8s.AllPolicies)
+	err := conn.NewSelect().Model(policies).Where("source = ?", source).Order("created_at DESC").Scan(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.all_policies'.", logger.Field{Key: "error", Value: err.Error()})
+		return []k8s.AllPolicies{}, err
+	}
+
+	return *policies, nil
+}
+
+// PolicyInUse reports whether a catalog policy is deployed or a member of a bundle
+func PolicyInUse(id string) (bool, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	deployed, err := conn.NewSelect().Model((*k8s.ImplimentedPolicies)(nil)).Where("policy_id = ?", id).Exists(ctx)
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.implimented_policies'.", logger.Field{Key: "error", Value: err.Error()})
+		return false, err
+	}
+	if deployed {
+		return true, nil
+	}
+
+	bundled, err := conn.NewSelect().Model((*k8s.PolicyBundles)(nil)).Where("policy_ids @> jsonb_build_array(?::text)", id).Exists(ctx)
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.policy_bundles'.", logger.Field{Key: "error", Value: err.Error()})
+		return false, err
+	}
+
+	return bundled, nil
+}
+
+// DeletePolicyById deletes a policy and its versions in a single transaction. Policies that have it as their
+// variant lose the reference.
+func DeletePolicyById(id string) error {
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	err := conn.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewDelete().Model((*k8s.PolicyVersions)(nil)).Where("policy_id = ?", id).Exec(ctx); err != nil {
+			return err
+		}
+		if _, err := tx.NewUpdate().Model((*k8s.AllPolicies)(nil)).Set("variant_id = NULL").Where("variant_id = ?", id).Exec(ctx); err != nil {
+			return err
+		}
+		_, err := tx.NewDelete().Model((*k8s.AllPolicies)(nil)).Where("id = ?", id).Exec(ctx)
+		return err
+	})
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute delete query on 'k8s.all_policies'.", logger.Field{Key: "error", Value: err.Error()})
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package persistance
+
+import (
+	"context"
+
+	"github.com/FearLessSaad/SNFOK/db"
+	"github.com/FearLessSaad/SNFOK/db/models/k8s"
+	"github.com/FearLessSaad/SNFOK/tooling/logger"
+	"github.com/uptrace/bun"
+)
+
+// GetDriftReports returns the last drift report of every cluster, ordered by cluster name
+func GetDriftReports() ([]k8s.DriftReports, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	reports := new([]k8s.DriftReports)
+	err := conn.NewSelect().Model(reports).Order("cluster_name ASC").Scan(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.drift_reports'.", logger.Field{Key: "error", Value: err.Error()})
+		return []k8s.DriftReports{}, err
+	}
+
+	return *reports, nil
+}
+
+// ReplaceDriftReports replaces the stored drift reports with the reports of the last reconciliation in a single
+// transaction, so clusters that were removed lose their report
+func ReplaceDriftReports(reports []k8s.DriftReports) error {
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	err := conn.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewDelete().Model((*k8s.DriftReports)(nil)).Where("TRUE").Exec(ctx); err != nil {
+			return err
+		}
+		if len(reports) == 0 {
+			return nil
+		}
+		_, err := tx.NewInsert().Model(&reports).Exec(ctx)
+		return err
+	})
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to replace the rows of 'k8s.drift_reports'.", logger.Field{Key: "error", Value: err.Error()})
+		return err
+	}
+
+	return nil
+}
@@ -68,3 +68,17 @@ func DeleteImplimentedPolicyById(id string) error {
 
 	return nil
 }
+
+func UpdateImplimentedPolicy(data k8s.ImplimentedPolicies) error {
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	_, err := conn.NewUpdate().Model(&data).WherePK().Exec(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute update query on 'k8s.implimented_policies'.", logger.Field{Key: "error", Value: err.Error()})
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package persistance
+
+import (
+	"context"
+
+	"github.com/FearLessSaad/SNFOK/db"
+	"github.com/FearLessSaad/SNFOK/db/models/k8s"
+	"github.com/FearLessSaad/SNFOK/tooling/logger"
+)
+
+func GetAllPodIsolations() ([]k8s.PodIsolations, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	isolations := new([]k8s.PodIsolations)
+	err := conn.NewSelect().Model(isolations).Order("created_at DESC").Scan(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.pod_isolations'.", logger.Field{Key: "error", Value: err.Error()})
+		return []k8s.PodIsolations{}, err
+	}
+
+	return *isolations, nil
+}
+
+func GetPodIsolationById(id string) (k8s.PodIsolations, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	isolation := new(k8s.PodIsolations)
+	err := conn.NewSelect().Model(isolation).Where("id = ?", id).Limit(1).Scan(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.pod_isolations'.", logger.Field{Key: "error", Value: err.Error()})
+		return k8s.PodIsolations{}, err
+	}
+
+	return *isolation, nil
+}
+
+func CreatePodIsolation(data k8s.PodIsolations) error {
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	_, err := conn.NewInsert().Model(&data).Exec(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute insert query on 'k8s.pod_isolations'.", logger.Field{Key: "error", Value: err.Error()})
+		return err
+	}
+
+	return nil
+}
+
+func UpdatePodIsolation(data k8s.PodIsolations) error {
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	_, err := conn.NewUpdate().Model(&data).WherePK().Exec(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute update query on 'k8s.pod_isolations'.", logger.Field{Key: "error", Value: err.Error()})
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package persistance
+
+import (
+	"context"
+
+	"github.com/FearLessSaad/SNFOK/db"
+	"github.com/FearLessSaad/SNFOK/db/models/k8s"
+	"github.com/FearLessSaad/SNFOK/tooling/logger"
+)
+
+func GetAllPolicyBundles() ([]k8s.PolicyBundles, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	bundles := new([]k8s.PolicyBundles)
+	err := conn.NewSelect().Model(bundles).Order("name ASC").Scan(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.policy_bundles'.", logger.Field{Key: "error", Value: err.Error()})
+		return []k8s.PolicyBundles{}, err
+	}
+
+	return *bundles, nil
+}
+
+func GetPolicyBundleById(id string) (k8s.PolicyBundles, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	bundle := new(k8s.PolicyBundles)
+	err := conn.NewSelect().Model(bundle).Where("id = ?", id).Limit(1).Scan(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.policy_bundles'.", logger.Field{Key: "error", Value: err.Error()})
+		return k8s.PolicyBundles{}, err
+	}
+
+	return *bundle, nil
+}
+
+func PolicyBundleNameExists(name string) (bool, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	exists, err := conn.NewSelect().Model((*k8s.PolicyBundles)(nil)).Where("name = ?", name).Exists(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.policy_bundles'.", logger.Field{Key: "error", Value: err.Error()})
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func CreatePolicyBundle(data *k8s.PolicyBundles) error {
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	_, err := conn.NewInsert().Model(data).Returning("*").Exec(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute insert query on 'k8s.policy_bundles'.", logger.Field{Key: "error", Value: err.Error()})
+		return err
+	}
+
+	return nil
+}
+
+func DeletePolicyBundleById(id string) error {
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	_, err := conn.NewDelete().
+		Model((*k8s.PolicyBundles)(nil)).
+		Where("id = ?", id).
+		Exec(ctx)
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute delete query on 'k8s.policy_bundles'.", logger.Field{Key: "error", Value: err.Error()})
+		return err
+	}
+
+	return nil
+}
+
+func GetAllImplementedBundles() ([]k8s.ImplementedBundles, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	bundles := new([]k8s.ImplementedBundles)
+	err := conn.NewSelect().Model(bundles).Order("created_at DESC").Scan(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.implemented_bundles'.", logger.Field{Key: "error", Value: err.Error()})
+		return []k8s.ImplementedBundles{}, err
+	}
+
+	return *bundles, nil
+}
+
+func GetImplementedBundleById(id string) (k8s.ImplementedBundles, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	bundle := new(k8s.ImplementedBundles)
+	err := conn.NewSelect().Model(bundle).Where("id = ?", id).Limit(1).Scan(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.implemented_bundles'.", logger.Field{Key: "error", Value: err.Error()})
+		return k8s.ImplementedBundles{}, err
+	}
+
+	return *bundle, nil
+}
+
+func CreateImplementedBundle(data *k8s.ImplementedBundles) error {
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	_, err := conn.NewInsert().Model(data).Returning("*").Exec(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute insert query on 'k8s.implemented_bundles'.", logger.Field{Key: "error", Value: err.Error()})
+		return err
+	}
+
+	return nil
+}
+
+func UpdateImplementedBundle(data k8s.ImplementedBundles) error {
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	_, err := conn.NewUpdate().Model(&data).WherePK().Exec(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute update query on 'k8s.implemented_bundles'.", logger.Field{Key: "error", Value: err.Error()})
+		return err
+	}
+
+	return nil
+}
+
+func DeleteImplementedBundleById(id string) error {
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	_, err := conn.NewDelete().
+		Model((*k8s.ImplementedBundles)(nil)).
+		Where("id = ?", id).
+		Exec(ctx)
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute delete query on 'k8s.implemented_bundles'.", logger.Field{Key: "error", Value: err.Error()})
+		return err
+	}
+
+	return nil
+}
+
+// GetImplimentedPoliciesByBundle returns the implemented policies deployed with a bundle
+func GetImplimentedPoliciesByBundle(bundle_id string) ([]k8s.ImplimentedPolicies, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	i_policies := new([]k8s.ImplimentedPolicies)
+	err := conn.NewSelect().Model(i_policies).Where("bundle_id = ?", bundle_id).Scan(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.implimented_policies'.", logger.Field{Key: "error", Value: err.Error()})
+		return []k8s.ImplimentedPolicies{}, err
+	}
+
+	return *i_policies, nil
+}
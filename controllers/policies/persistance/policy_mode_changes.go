@@ -0,0 +1,40 @@
+package persistance
+
+import (
+	"context"
+
+	"github.com/FearLessSaad/SNFOK/db"
+	"github.com/FearLessSaad/SNFOK/db/models/k8s"
+	"github.com/FearLessSaad/SNFOK/tooling/logger"
+)
+
+// GetPolicyModeChanges returns the mode history of an implemented policy, newest first
+func GetPolicyModeChanges(implemented_id string) ([]k8s.PolicyModeChanges, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	changes := new([]k8s.PolicyModeChanges)
+	err := conn.NewSelect().Model(changes).Where("implemented_id = ?", implemented_id).Order("created_at DESC").Scan(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.policy_mode_changes'.", logger.Field{Key: "error", Value: err.Error()})
+		return []k8s.PolicyModeChanges{}, err
+	}
+
+	return *changes, nil
+}
+
+func CreatePolicyModeChange(data k8s.PolicyModeChanges) error {
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	_, err := conn.NewInsert().Model(&data).Exec(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute insert query on 'k8s.policy_mode_changes'.", logger.Field{Key: "error", Value: err.Error()})
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package persistance
+
+import (
+	"context"
+
+	"github.com/FearLessSaad/SNFOK/db"
+	"github.com/FearLessSaad/SNFOK/db/models/k8s"
+	"github.com/FearLessSaad/SNFOK/tooling/logger"
+)
+
+// GetPolicyVersions returns the version history of a catalog policy, newest first
+func GetPolicyVersions(policy_id string) ([]k8s.PolicyVersions, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	versions := new([]k8s.PolicyVersions)
+	err := conn.NewSelect().Model(versions).Where("policy_id = ?", policy_id).Order("version DESC").Scan(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.policy_versions'.", logger.Field{Key: "error", Value: err.Error()})
+		return []k8s.PolicyVersions{}, err
+	}
+
+	return *versions, nil
+}
+
+func GetPolicyVersion(policy_id string, version int) (k8s.PolicyVersions, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	policy_version := new(k8s.PolicyVersions)
+	err := conn.NewSelect().Model(policy_version).Where("policy_id = ?", policy_id).Where("version = ?", version).Limit(1).Scan(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.policy_versions'.", logger.Field{Key: "error", Value: err.Error()})
+		return k8s.PolicyVersions{}, err
+	}
+
+	return *policy_version, nil
+}
+
+// GetLatestPolicyVersion returns the newest version of a catalog policy, if it has any
+func GetLatestPolicyVersion(policy_id string) (k8s.PolicyVersions, bool, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	versions := new([]k8s.PolicyVersions)
+	err := conn.NewSelect().Model(versions).Where("policy_id = ?", policy_id).Order("version DESC").Limit(1).Scan(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.policy_versions'.", logger.Field{Key: "error", Value: err.Error()})
+		return k8s.PolicyVersions{}, false, err
+	}
+	if len(*versions) == 0 {
+		return k8s.PolicyVersions{}, false, nil
+	}
+
+	return (*versions)[0], true, nil
+}
+
+// GetOutdatedImplimentedPolicies returns the implemented policies whose catalog policy has a newer version
+// than the one they were rendered from
+func GetOutdatedImplimentedPolicies() ([]k8s.ImplimentedPolicies, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	i_policies := new([]k8s.ImplimentedPolicies)
+	err := conn.NewSelect().Model(i_policies).
+		Join("JOIN k8s.all_policies AS p ON p.id = h.policy_id").
+		Where("h.policy_version < p.version").
+		Order("h.created_at ASC").
+		Scan(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.implimented_policies'.", logger.Field{Key: "error", Value: err.Error()})
+		return []k8s.ImplimentedPolicies{}, err
+	}
+
+	return *i_policies, nil
+}
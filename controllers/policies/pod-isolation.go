@@ -0,0 +1,58 @@
+package policies
+
+import (
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/dto"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/repository"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/security/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+func PodIsolation(router fiber.Router) {
+
+	router.Post("/isolate", func(c *fiber.Ctx) error {
+		details := new(dto.IsolatePodRequest)
+		if err := c.BodyParser(details); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(global_dto.Response[string]{
+				Status:  "error",
+				Message: message.INVALID_REQUEST_PAYLOAD,
+				Data:    nil,
+				Meta: &global_dto.Meta{
+					Code: response.INVALID_REQUEST_PAYLOAD,
+				},
+			})
+		}
+		if errs := validation.ValidateStruct(details); len(errs) > 0 {
+			errors := make([]any, len(errs))
+			for i, err := range errs {
+				errors[i] = err
+			}
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(global_dto.Response[string]{
+				Status:  "error",
+				Message: message.FAILED_DATA_VALIDATION,
+				Errors:  errors,
+				Data:    nil,
+				Meta: &global_dto.Meta{
+					Code: response.FAILED_DATA_VALIDATION,
+				},
+			})
+		}
+
+		user_id := c.Locals("user_id").(string)
+		response, status := repository.IsolatePod(*details, user_id)
+		return c.Status(status).JSON(response)
+	})
+
+	router.Get("/isolated", func(c *fiber.Ctx) error {
+		response, status := repository.GetPodIsolations()
+		return c.Status(status).JSON(response)
+	})
+
+	router.Post("/release/:id", func(c *fiber.Ctx) error {
+		user_id := c.Locals("user_id").(string)
+		response, status := repository.ReleasePod(c.AllParams()["id"], user_id)
+		return c.Status(status).JSON(response)
+	})
+}
@@ -0,0 +1,100 @@
+package policies
+
+import (
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/dto"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/repository"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/security/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+func PolicyBundles(router fiber.Router) {
+
+	router.Get("/bundles", func(c *fiber.Ctx) error {
+		response, status := repository.GetPolicyBundles()
+		return c.Status(status).JSON(response)
+	})
+
+	router.Post("/bundles", func(c *fiber.Ctx) error {
+		details := new(dto.CreateBundleRequest)
+		if err := c.BodyParser(details); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(global_dto.Response[string]{
+				Status:  "error",
+				Message: message.INVALID_REQUEST_PAYLOAD,
+				Data:    nil,
+				Meta: &global_dto.Meta{
+					Code: response.INVALID_REQUEST_PAYLOAD,
+				},
+			})
+		}
+		if errs := validation.ValidateStruct(details); len(errs) > 0 {
+			return validationFailed(c, errs)
+		}
+
+		user_id := c.Locals("user_id").(string)
+		response, status := repository.CreatePolicyBundle(*details, user_id)
+		return c.Status(status).JSON(response)
+	})
+
+	router.Delete("/bundles/:id", func(c *fiber.Ctx) error {
+		response, status := repository.DeletePolicyBundle(c.Params("id"))
+		return c.Status(status).JSON(response)
+	})
+
+	// Deploys every policy of a bundle to one target; if one fails, none stays applied
+	router.Post("/bundles/deploy", func(c *fiber.Ctx) error {
+		details := new(dto.DeployBundleRequest)
+		if err := c.BodyParser(details); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(global_dto.Response[string]{
+				Status:  "error",
+				Message: message.INVALID_REQUEST_PAYLOAD,
+				Data:    nil,
+				Meta: &global_dto.Meta{
+					Code: response.INVALID_REQUEST_PAYLOAD,
+				},
+			})
+		}
+		if details.Scope == "" {
+			details.Scope = agent_dto.POLICY_SCOPE_WORKLOAD
+		}
+		if errs := append(validation.ValidateStruct(details), validateDeployTarget(&details.DeployTarget)...); len(errs) > 0 {
+			return validationFailed(c, errs)
+		}
+
+		user_id := c.Locals("user_id").(string)
+		response, status := repository.DeployPolicyBundle(*details, user_id)
+		return c.Status(status).JSON(response)
+	})
+
+	router.Get("/bundles/deployed", func(c *fiber.Ctx) error {
+		response, status := repository.GetImplementedBundles()
+		return c.Status(status).JSON(response)
+	})
+
+	// Removes every policy deployed with the bundle
+	router.Delete("/bundles/deployed/:id", func(c *fiber.Ctx) error {
+		user_id := c.Locals("user_id").(string)
+		response, status := repository.RemoveImplementedBundle(c.Params("id"), user_id)
+		return c.Status(status).JSON(response)
+	})
+}
+
+// validationFailed sends the validation errors of a request
+func validationFailed(c *fiber.Ctx, errs []validation.ValidationError) error {
+	errors := make([]any, len(errs))
+	for i, err := range errs {
+		errors[i] = err
+	}
+	return c.Status(fiber.StatusUnprocessableEntity).JSON(global_dto.Response[string]{
+		Status:  "error",
+		Message: message.FAILED_DATA_VALIDATION,
+		Errors:  errors,
+		Data:    nil,
+		Meta: &global_dto.Meta{
+			Code: response.FAILED_DATA_VALIDATION,
+		},
+	})
+}
@@ -0,0 +1,48 @@
+package policies
+
+import (
+	"strings"
+
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/dto"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/repository"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/security/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+func PolicyCatalog(router fiber.Router) {
+
+	// Searches the catalog, e.g. /catalog?q=shell&type=KUBEARMOR&framework=MITRE&control=T1059&mode=ENFORCE&page=2
+	router.Get("/catalog", func(c *fiber.Ctx) error {
+		query := new(dto.CatalogQuery)
+		if err := c.QueryParser(query); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(global_dto.Response[string]{
+				Status:  "error",
+				Message: message.INVALID_REQUEST_PAYLOAD,
+				Data:    nil,
+				Meta: &global_dto.Meta{
+					Code: response.INVALID_REQUEST_PAYLOAD,
+				},
+			})
+		}
+		query.Search = strings.TrimSpace(query.Search)
+		query.PolicyType = strings.ToUpper(query.PolicyType)
+		query.Source = strings.ToUpper(query.Source)
+		query.Mode = strings.ToUpper(query.Mode)
+		if errs := validation.ValidateStruct(query); len(errs) > 0 {
+			return validationFailed(c, errs)
+		}
+
+		response, status := repository.GetPolicyCatalog(*query)
+		return c.Status(status).JSON(response)
+	})
+
+	// Imports POLICIES_TEMPLATES_DIR into the catalog now instead of waiting for the import job
+	router.Post("/catalog/import", func(c *fiber.Ctx) error {
+		user_id := c.Locals("user_id").(string)
+		response, status := repository.ImportCatalogNow(user_id)
+		return c.Status(status).JSON(response)
+	})
+}
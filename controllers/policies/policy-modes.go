@@ -0,0 +1,45 @@
+package policies
+
+import (
+	"strings"
+
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/dto"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/repository"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/security/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+func PolicyModes(router fiber.Router) {
+
+	// Mode changes of an implemented policy, newest first
+	router.Get("/modes/:id", func(c *fiber.Ctx) error {
+		response, status := repository.GetPolicyModeChanges(c.Params("id"))
+		return c.Status(status).JSON(response)
+	})
+
+	// Switches an implemented policy between AUDIT and ENFORCE without deleting it
+	router.Post("/modes/:id", func(c *fiber.Ctx) error {
+		details := new(dto.PolicyModeRequest)
+		if err := c.BodyParser(details); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(global_dto.Response[string]{
+				Status:  "error",
+				Message: message.INVALID_REQUEST_PAYLOAD,
+				Data:    nil,
+				Meta: &global_dto.Meta{
+					Code: response.INVALID_REQUEST_PAYLOAD,
+				},
+			})
+		}
+		details.Mode = strings.ToUpper(details.Mode)
+		if errs := validation.ValidateStruct(details); len(errs) > 0 {
+			return validationFailed(c, errs)
+		}
+
+		user_id := c.Locals("user_id").(string)
+		response, status := repository.ChangePolicyMode(c.Params("id"), *details, user_id)
+		return c.Status(status).JSON(response)
+	})
+}
@@ -0,0 +1,100 @@
+package policies
+
+import (
+	"strings"
+
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/dto"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/repository"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/security/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+func PolicyTemplates(router fiber.Router) {
+
+	router.Get("/templates", func(c *fiber.Ctx) error {
+		response, status := repository.GetPolicyTemplates()
+		return c.Status(status).JSON(response)
+	})
+
+	router.Get("/templates/:id", func(c *fiber.Ctx) error {
+		response, status := repository.GetPolicyTemplate(c.Params("id"))
+		return c.Status(status).JSON(response)
+	})
+
+	// Uploads a TracingPolicy or KubeArmorPolicy template, stored in the database and pushed to the agent on deploy
+	router.Post("/templates", func(c *fiber.Ctx) error {
+		details := new(dto.PolicyTemplateRequest)
+		if err := c.BodyParser(details); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(global_dto.Response[string]{
+				Status:  "error",
+				Message: message.INVALID_REQUEST_PAYLOAD,
+				Data:    nil,
+				Meta: &global_dto.Meta{
+					Code: response.INVALID_REQUEST_PAYLOAD,
+				},
+			})
+		}
+		details.Mode = strings.ToUpper(details.Mode)
+		if errs := validation.ValidateStruct(details); len(errs) > 0 {
+			return validationFailed(c, errs)
+		}
+
+		user_id := c.Locals("user_id").(string)
+		response, status := repository.CreatePolicyTemplate(*details, user_id)
+		return c.Status(status).JSON(response)
+	})
+
+	router.Put("/templates/:id", func(c *fiber.Ctx) error {
+		details := new(dto.PolicyTemplateRequest)
+		if err := c.BodyParser(details); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(global_dto.Response[string]{
+				Status:  "error",
+				Message: message.INVALID_REQUEST_PAYLOAD,
+				Data:    nil,
+				Meta: &global_dto.Meta{
+					Code: response.INVALID_REQUEST_PAYLOAD,
+				},
+			})
+		}
+		details.Mode = strings.ToUpper(details.Mode)
+		if errs := validation.ValidateStruct(details); len(errs) > 0 {
+			return validationFailed(c, errs)
+		}
+
+		user_id := c.Locals("user_id").(string)
+		response, status := repository.UpdatePolicyTemplate(c.Params("id"), *details, user_id)
+		return c.Status(status).JSON(response)
+	})
+
+	// Copies a custom template or a catalog policy into a new custom template
+	router.Post("/templates/:id/clone", func(c *fiber.Ctx) error {
+		details := new(dto.ClonePolicyTemplateRequest)
+		if len(c.Body()) != 0 {
+			if err := c.BodyParser(details); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(global_dto.Response[string]{
+					Status:  "error",
+					Message: message.INVALID_REQUEST_PAYLOAD,
+					Data:    nil,
+					Meta: &global_dto.Meta{
+						Code: response.INVALID_REQUEST_PAYLOAD,
+					},
+				})
+			}
+		}
+		if errs := validation.ValidateStruct(details); len(errs) > 0 {
+			return validationFailed(c, errs)
+		}
+
+		user_id := c.Locals("user_id").(string)
+		response, status := repository.ClonePolicyTemplate(c.Params("id"), *details, user_id)
+		return c.Status(status).JSON(response)
+	})
+
+	router.Delete("/templates/:id", func(c *fiber.Ctx) error {
+		response, status := repository.DeletePolicyTemplate(c.Params("id"))
+		return c.Status(status).JSON(response)
+	})
+}
@@ -0,0 +1,78 @@
+package policies
+
+import (
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/dto"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/repository"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/security/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+func PolicyUpgrades(router fiber.Router) {
+
+	// Version history of a catalog policy or custom template
+	router.Get("/catalog/:id/versions", func(c *fiber.Ctx) error {
+		response, status := repository.GetPolicyVersions(c.Params("id"))
+		return c.Status(status).JSON(response)
+	})
+
+	// Implemented policies whose catalog policy has a newer version
+	router.Get("/upgrades", func(c *fiber.Ctx) error {
+		response, status := repository.GetPolicyUpgrades()
+		return c.Status(status).JSON(response)
+	})
+
+	// Diff between the deployed version of an implemented policy and ?version=, the latest version by default
+	router.Get("/upgrades/:id/preview", func(c *fiber.Ctx) error {
+		query := new(dto.PolicyUpgradeRequest)
+		if err := c.QueryParser(query); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(global_dto.Response[string]{
+				Status:  "error",
+				Message: message.INVALID_REQUEST_PAYLOAD,
+				Data:    nil,
+				Meta: &global_dto.Meta{
+					Code: response.INVALID_REQUEST_PAYLOAD,
+				},
+			})
+		}
+		if errs := validation.ValidateStruct(query); len(errs) > 0 {
+			return validationFailed(c, errs)
+		}
+
+		response, status := repository.PreviewPolicyUpgrade(c.Params("id"), query.Version)
+		return c.Status(status).JSON(response)
+	})
+
+	// Re-renders an implemented policy from another version with its original parameters and re-applies it
+	router.Post("/upgrades/:id", func(c *fiber.Ctx) error {
+		details := new(dto.PolicyUpgradeRequest)
+		if len(c.Body()) != 0 {
+			if err := c.BodyParser(details); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(global_dto.Response[string]{
+					Status:  "error",
+					Message: message.INVALID_REQUEST_PAYLOAD,
+					Data:    nil,
+					Meta: &global_dto.Meta{
+						Code: response.INVALID_REQUEST_PAYLOAD,
+					},
+				})
+			}
+		}
+		if errs := validation.ValidateStruct(details); len(errs) > 0 {
+			return validationFailed(c, errs)
+		}
+
+		user_id := c.Locals("user_id").(string)
+		response, status := repository.UpgradeImplementedPolicy(c.Params("id"), details.Version, user_id)
+		return c.Status(status).JSON(response)
+	})
+
+	// Goes back to the version an implemented policy had before its last upgrade
+	router.Post("/upgrades/:id/rollback", func(c *fiber.Ctx) error {
+		user_id := c.Locals("user_id").(string)
+		response, status := repository.RollbackImplementedPolicy(c.Params("id"), user_id)
+		return c.Status(status).JSON(response)
+	})
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/FearLessSaad/SNFOK/constants/agent_consts"
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/dto"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/persistance"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/httpclient"
+	"github.com/FearLessSaad/SNFOK/tooling/logger"
+	"github.com/gofiber/fiber"
+
+	cluster "github.com/FearLessSaad/SNFOK/controllers/clusters/persistance"
+)
+
+// GetManagedPolicies lists the SNFOK-managed objects that are present in the cluster and links them to their implemented policy records
+func GetManagedPolicies(namespace string) (global_dto.Response[[]dto.ManagedPolicy], int) {
+
+	clusters, _ := cluster.GetAllClusters()
+	if len(clusters) == 0 {
+		return global_dto.Response[[]dto.ManagedPolicy]{
+			Status:  "error",
+			Message: message.NO_REGISTERED_CLUSTER_AVAILABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.NO_CLUSTER_AVAILABLE,
+			},
+		}, fiber.StatusNotFound
+	}
+	ip := clusters[0].MasterIP
+	port := clusters[0].AgentPort
+
+	client := httpclient.NewClient(0)
+
+	res, err := client.Get("http://"+ip+":"+fmt.Sprintf("%d", port)+agent_consts.POLICIES_MANAGED+"?namespace="+url.QueryEscape(namespace), map[string]string{})
+	if err != nil {
+		logger.Log(logger.DEBUG, "HTTP Request Error", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[[]dto.ManagedPolicy]{
+			Status:  "error",
+			Message: message.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			},
+		}, fiber.StatusBadGateway
+	}
+
+	var objects []agent_dto.ManagedObject
+	if err := json.Unmarshal(res.Body, &objects); err != nil {
+		logger.Log(logger.DEBUG, "Unmarshal Response", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[[]dto.ManagedPolicy]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	implemented, err := persistance.GetAllImplimentedPolicies()
+	if err != nil {
+		return global_dto.Response[[]dto.ManagedPolicy]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+	titles := make(map[string]string)
+	for _, policy := range implemented {
+		titles[policy.ID] = policy.PolicyTitle
+	}
+
+	managed := []dto.ManagedPolicy{}
+	for _, obj := range objects {
+		title, tracked := titles[obj.ImplementedID]
+		managed = append(managed, dto.ManagedPolicy{
+			ManagedObject: obj,
+			PolicyTitle:   title,
+			Tracked:       tracked,
+		})
+	}
+
+	return global_dto.Response[[]dto.ManagedPolicy]{
+		Status:  "success",
+		Message: "",
+		Data:    &managed,
+		Meta: &global_dto.Meta{
+			Code: response.MANAGED_POLICIES,
+		},
+	}, fiber.StatusOK
+}
@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/FearLessSaad/SNFOK/constants/agent_consts"
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/dto"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/persistance"
+	"github.com/FearLessSaad/SNFOK/db/models/k8s"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/httpclient"
+	"github.com/FearLessSaad/SNFOK/tooling/logger"
+	"github.com/gofiber/fiber"
+	"github.com/uptrace/bun"
+
+	cluster "github.com/FearLessSaad/SNFOK/controllers/clusters/persistance"
+)
+
+func IsolatePod(data dto.IsolatePodRequest, uid string) (global_dto.Response[k8s.PodIsolations], int) {
+
+	clusters, _ := cluster.GetAllClusters()
+	if len(clusters) == 0 {
+		return global_dto.Response[k8s.PodIsolations]{
+			Status:  "error",
+			Message: message.NO_REGISTERED_CLUSTER_AVAILABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.NO_CLUSTER_AVAILABLE,
+			},
+		}, fiber.StatusNotFound
+	}
+	ip := clusters[0].MasterIP
+	port := clusters[0].AgentPort
+
+	client := httpclient.NewClient(0)
+
+	res, err := client.Post("http://"+ip+":"+fmt.Sprintf("%d", port)+agent_consts.POLICIES_ISOLATE_POD, agent_dto.IsolatePod{
+		Namespace:  data.Namespace,
+		Pod:        data.Pod,
+		TTLSeconds: data.TTLSeconds,
+	}, map[string]string{})
+	if err != nil {
+		logger.Log(logger.DEBUG, "HTTP Request Error", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[k8s.PodIsolations]{
+			Status:  "error",
+			Message: message.POD_ISOLATION_FAILED,
+			Errors:  []any{err.Error()},
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POD_ISOLATION_FAILED,
+			},
+		}, fiber.StatusBadGateway
+	}
+
+	var res_data agent_dto.IsolatedPod
+	if err := json.Unmarshal(res.Body, &res_data); err != nil {
+		logger.Log(logger.DEBUG, "Unmarshal Response", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[k8s.PodIsolations]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	isolation := k8s.PodIsolations{
+		ClusterID:   clusters[0].ID,
+		IsolationID: res_data.ID,
+		Namespace:   res_data.Namespace,
+		Pod:         res_data.Pod,
+		PolicyName:  res_data.PolicyName,
+		Reason:      data.Reason,
+		Status:      k8s.IsolationStatusIsolated,
+		AuditFields: k8s.AuditFields{
+			CreatedBy: uid,
+			CreatedAt: time.Now(),
+		},
+	}
+	if res_data.ExpiresAt != nil {
+		isolation.ExpiresAt = bun.NullTime{Time: *res_data.ExpiresAt}
+	}
+
+	if err := persistance.CreatePodIsolation(isolation); err != nil {
+		return global_dto.Response[k8s.PodIsolations]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.CREATION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[k8s.PodIsolations]{
+		Status:  "success",
+		Message: message.POD_ISOLATED,
+		Data:    &isolation,
+		Meta: &global_dto.Meta{
+			Code: response.POD_ISOLATED,
+		},
+	}, fiber.StatusOK
+}
+
+// GetPodIsolations returns the isolation records. Records the agent no longer reports,
+// e.g. because their TTL expired, are marked as released first.
+func GetPodIsolations() (global_dto.Response[[]k8s.PodIsolations], int) {
+
+	isolations, err := persistance.GetAllPodIsolations()
+	if err != nil {
+		return global_dto.Response[[]k8s.PodIsolations]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	clusters, _ := cluster.GetAllClusters()
+	if len(clusters) != 0 {
+		client := httpclient.NewClient(0)
+		res, err := client.Get("http://"+clusters[0].MasterIP+":"+fmt.Sprintf("%d", clusters[0].AgentPort)+agent_consts.POLICIES_ISOLATED_PODS, map[string]string{})
+
+		var live []agent_dto.IsolatedPod
+		if err == nil && json.Unmarshal(res.Body, &live) == nil {
+			active := make(map[string]bool)
+			for _, pod := range live {
+				active[pod.Namespace+"/"+pod.ID] = true
+			}
+
+			for i := range isolations {
+				if isolations[i].Status != k8s.IsolationStatusIsolated || isolations[i].ClusterID != clusters[0].ID {
+					continue
+				}
+				if active[isolations[i].Namespace+"/"+isolations[i].IsolationID] {
+					continue
+				}
+				isolations[i].Status = k8s.IsolationStatusReleased
+				isolations[i].ReleasedAt = bun.NullTime{Time: time.Now()}
+				if !isolations[i].ExpiresAt.IsZero() {
+					isolations[i].ReleasedAt = isolations[i].ExpiresAt
+				}
+				isolations[i].UpdatedBy = "SNFOK:AGENT"
+				isolations[i].UpdatedAt = bun.NullTime{Time: time.Now()}
+				persistance.UpdatePodIsolation(isolations[i])
+			}
+		} else if err != nil {
+			logger.Log(logger.DEBUG, "HTTP Request Error", logger.Field{Key: "error", Value: err.Error()})
+		}
+	}
+
+	return global_dto.Response[[]k8s.PodIsolations]{
+		Status:  "success",
+		Message: "",
+		Data:    &isolations,
+		Meta: &global_dto.Meta{
+			Code: response.ISOLATED_PODS,
+		},
+	}, fiber.StatusOK
+}
+
+func ReleasePod(id string, uid string) (global_dto.Response[string], int) {
+
+	isolation, err := persistance.GetPodIsolationById(id)
+	if err != nil || isolation.Status != k8s.IsolationStatusIsolated {
+		return global_dto.Response[string]{
+			Status:  "error",
+			Message: message.ISOLATION_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.ISOLATION_NOT_FOUND,
+			},
+		}, fiber.StatusNotFound
+	}
+
+	clusters, _ := cluster.GetAllClusters()
+	if len(clusters) == 0 {
+		return global_dto.Response[string]{
+			Status:  "error",
+			Message: message.NO_REGISTERED_CLUSTER_AVAILABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.NO_CLUSTER_AVAILABLE,
+			},
+		}, fiber.StatusNotFound
+	}
+	ip := clusters[0].MasterIP
+	port := clusters[0].AgentPort
+
+	client := httpclient.NewClient(0)
+
+	_, err = client.Post("http://"+ip+":"+fmt.Sprintf("%d", port)+agent_consts.POLICIES_RELEASE_POD, agent_dto.ReleasePod{
+		Namespace: isolation.Namespace,
+		ID:        isolation.IsolationID,
+	}, map[string]string{})
+	if err != nil {
+		logger.Log(logger.DEBUG, "HTTP Request Error", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[string]{
+			Status:  "error",
+			Message: message.POD_ISOLATION_FAILED,
+			Errors:  []any{err.Error()},
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POD_ISOLATION_FAILED,
+			},
+		}, fiber.StatusBadGateway
+	}
+
+	isolation.Status = k8s.IsolationStatusReleased
+	isolation.ReleasedAt = bun.NullTime{Time: time.Now()}
+	isolation.UpdatedBy = uid
+	isolation.UpdatedAt = bun.NullTime{Time: time.Now()}
+
+	if err := persistance.UpdatePodIsolation(isolation); err != nil {
+		return global_dto.Response[string]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[string]{
+		Status:  "success",
+		Message: message.POD_RELEASED,
+		Data:    nil,
+		Meta: &global_dto.Meta{
+			Code: response.POD_RELEASED,
+		},
+	}, fiber.StatusOK
+}
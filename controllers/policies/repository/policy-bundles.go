@@ -0,0 +1,553 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/FearLessSaad/SNFOK/agent/controllers/policies/routes"
+	"github.com/FearLessSaad/SNFOK/constants/agent_consts"
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/dto"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/persistance"
+	"github.com/FearLessSaad/SNFOK/db/models/k8s"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/httpclient"
+	"github.com/FearLessSaad/SNFOK/tooling/logger"
+	"github.com/gofiber/fiber"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+
+	cluster "github.com/FearLessSaad/SNFOK/controllers/clusters/persistance"
+)
+
+func GetPolicyBundles() (global_dto.Response[[]k8s.PolicyBundles], int) {
+
+	bundles, err := persistance.GetAllPolicyBundles()
+	if err != nil {
+		return global_dto.Response[[]k8s.PolicyBundles]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[[]k8s.PolicyBundles]{
+		Status:  "success",
+		Message: "",
+		Data:    &bundles,
+		Meta: &global_dto.Meta{
+			Code: response.POLICY_BUNDLES,
+		},
+	}, fiber.StatusOK
+}
+
+// CreatePolicyBundle stores a bundle after checking that every member exists in the catalog
+func CreatePolicyBundle(data dto.CreateBundleRequest, uid string) (global_dto.Response[k8s.PolicyBundles], int) {
+
+	exists, err := persistance.PolicyBundleNameExists(data.Name)
+	if err != nil {
+		return global_dto.Response[k8s.PolicyBundles]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+	if exists {
+		return global_dto.Response[k8s.PolicyBundles]{
+			Status:  "error",
+			Message: message.BUNDLE_ALREADY_EXISTS,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.BUNDLE_ALREADY_EXISTS,
+			},
+		}, fiber.StatusConflict
+	}
+
+	var missing []any
+	for _, policy_id := range data.PolicyIDs {
+		if _, err := persistance.GetPlicysById(policy_id); err != nil {
+			missing = append(missing, policy_id)
+		}
+	}
+	if len(missing) != 0 {
+		return global_dto.Response[k8s.PolicyBundles]{
+			Status:  "error",
+			Message: message.POLICY_NOT_FOUND,
+			Errors:  missing,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_NOT_FOUND,
+			},
+		}, fiber.StatusNotFound
+	}
+
+	bundle := k8s.PolicyBundles{
+		Name:        data.Name,
+		Description: data.Description,
+		PolicyIDs:   data.PolicyIDs,
+		AuditFields: k8s.AuditFields{
+			CreatedBy: uid,
+			CreatedAt: time.Now(),
+		},
+	}
+	if err := persistance.CreatePolicyBundle(&bundle); err != nil {
+		return global_dto.Response[k8s.PolicyBundles]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.CREATION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[k8s.PolicyBundles]{
+		Status:  "success",
+		Message: message.BUNDLE_CREATED,
+		Data:    &bundle,
+		Meta: &global_dto.Meta{
+			Code: response.BUNDLE_CREATED,
+		},
+	}, fiber.StatusOK
+}
+
+// DeletePolicyBundle removes a bundle from the catalog. Its deployments stay in place and keep the bundle name.
+func DeletePolicyBundle(id string) (global_dto.Response[string], int) {
+
+	if _, err := persistance.GetPolicyBundleById(id); err != nil {
+		return global_dto.Response[string]{
+			Status:  "error",
+			Message: message.BUNDLE_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.BUNDLE_NOT_FOUND,
+			},
+		}, fiber.StatusNotFound
+	}
+
+	if err := persistance.DeletePolicyBundleById(id); err != nil {
+		return global_dto.Response[string]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[string]{
+		Status:  "success",
+		Message: message.BUNDLE_DELETED,
+		Data:    nil,
+		Meta: &global_dto.Meta{
+			Code: response.BUNDLE_DELETED,
+		},
+	}, fiber.StatusOK
+}
+
+// DeployPolicyBundle deploys every policy of a bundle to one target in a single agent call. The agent applies
+// all members or none; the outcome is recorded as one implemented bundle with the status of every member.
+func DeployPolicyBundle(data dto.DeployBundleRequest, uid string) (global_dto.Response[k8s.ImplementedBundles], int) {
+	driftLock.Lock()
+	defer driftLock.Unlock()
+
+	bundle, err := persistance.GetPolicyBundleById(data.BundleID)
+	if err != nil {
+		return global_dto.Response[k8s.ImplementedBundles]{
+			Status:  "error",
+			Message: message.BUNDLE_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.BUNDLE_NOT_FOUND,
+			},
+		}, fiber.StatusNotFound
+	}
+
+	policies := make([]k8s.AllPolicies, 0, len(bundle.PolicyIDs))
+	for _, policy_id := range bundle.PolicyIDs {
+		policy, err := persistance.GetPlicysById(policy_id)
+		if err != nil {
+			return global_dto.Response[k8s.ImplementedBundles]{
+				Status:  "error",
+				Message: message.POLICY_NOT_FOUND,
+				Errors:  []any{policy_id},
+				Data:    nil,
+				Meta: &global_dto.Meta{
+					Code: response.POLICY_NOT_FOUND,
+				},
+			}, fiber.StatusNotFound
+		}
+		policies = append(policies, policy)
+	}
+
+	clusters, _ := cluster.GetAllClusters()
+	if len(clusters) == 0 {
+		return global_dto.Response[k8s.ImplementedBundles]{
+			Status:  "error",
+			Message: message.NO_REGISTERED_CLUSTER_AVAILABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.NO_CLUSTER_AVAILABLE,
+			},
+		}, fiber.StatusNotFound
+	}
+	ip := clusters[0].MasterIP
+	port := clusters[0].AgentPort
+
+	client := httpclient.NewClient(0)
+
+	health, err := client.Get("http://"+ip+":"+fmt.Sprintf("%d", port)+agent_consts.HEALTH_GET_INTO_PATH, map[string]string{})
+	if err != nil {
+		logger.Log(logger.DEBUG, "HTTP Request Error", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[k8s.ImplementedBundles]{
+			Status:  "error",
+			Message: message.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			},
+		}, fiber.StatusBadGateway
+	}
+
+	var health_data agent_dto.HealthResponse
+	if err := json.Unmarshal(health.Body, &health_data); err != nil {
+		logger.Log(logger.DEBUG, "Unmarshal Response", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[k8s.ImplementedBundles]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	var unsupported []any
+	for _, policy := range policies {
+		if err := policyTypeSupported(health_data, policy.PolicyType, data.Scope); err != nil {
+			unsupported = append(unsupported, fmt.Sprintf("%s: %v", policy.PolicyTitle, err))
+		}
+	}
+	if len(unsupported) != 0 {
+		return global_dto.Response[k8s.ImplementedBundles]{
+			Status:  "error",
+			Message: message.POLICY_TYPE_UNSUPPORTED,
+			Errors:  unsupported,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_TYPE_UNSUPPORTED,
+			},
+		}, fiber.StatusUnprocessableEntity
+	}
+
+	// The ids are generated up front so the agent can label the objects of every member with them
+	implemented := k8s.ImplementedBundles{
+		ID:                 uuid.NewString(),
+		ClusterID:          clusters[0].ID,
+		BundleID:           bundle.ID,
+		BundleName:         bundle.Name,
+		Scope:              data.Scope,
+		Namespace:          data.Namespace,
+		AppLabel:           data.AppLabel,
+		Selector:           data.Selector,
+		Namespaces:         data.Namespaces,
+		ExcludedNamespaces: data.ExcludedNamespaces,
+		IncludeHost:        data.IncludeHost,
+		AuditFields: k8s.AuditFields{
+			CreatedBy: uid,
+			CreatedAt: time.Now(),
+		},
+	}
+	request := agent_dto.DeployBundle{}
+	versions := make([]int, len(policies))
+	for i, policy := range policies {
+		template, version, err := policyTemplate(policy, 0)
+		if err != nil {
+			return global_dto.Response[k8s.ImplementedBundles]{
+				Status:  "error",
+				Message: message.SOMETING_WRONG,
+				Data:    nil,
+				Meta: &global_dto.Meta{
+					Code: response.EXECUTION_ERROR,
+				},
+			}, fiber.StatusInternalServerError
+		}
+		versions[i] = version
+
+		member := agent_dto.DeployPolicy{
+			Namespace:          data.Namespace,
+			AppLabel:           data.AppLabel,
+			FilePath:           policy.PolicyFilePath,
+			Template:           template,
+			PolicyID:           policy.ID,
+			ImplementedID:      uuid.NewString(),
+			Params:             data.Params[policy.ID],
+			Selector:           data.Selector,
+			Scope:              data.Scope,
+			Namespaces:         data.Namespaces,
+			ExcludedNamespaces: data.ExcludedNamespaces,
+			IncludeHost:        data.IncludeHost,
+		}
+		request.Members = append(request.Members, member)
+	}
+
+	res, err := client.Post("http://"+ip+":"+fmt.Sprintf("%d", port)+agent_consts.POLICIES_DEPLOY_BUNDLE, request, map[string]string{
+		"Content-Type": "application/json",
+	})
+	if err != nil {
+		logger.Log(logger.DEBUG, "HTTP Request Error", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[k8s.ImplementedBundles]{
+			Status:  "error",
+			Message: message.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			Errors:  []any{err.Error()},
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			},
+		}, fiber.StatusBadGateway
+	}
+
+	var res_data agent_dto.DeployBundleResponse
+	if err := json.Unmarshal(res.Body, &res_data); err != nil || len(res_data.Members) != len(policies) {
+		logger.Log(logger.DEBUG, "Unmarshal Response", logger.Field{Key: "error", Value: fmt.Sprint(err)})
+		return global_dto.Response[k8s.ImplementedBundles]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	// Members that are still applied get an implemented policy, also when their rollback failed,
+	// so they can be removed together with the bundle
+	var errors []any
+	implemented.Status = k8s.BundleStatusDeployed
+	if !res_data.Deployed {
+		implemented.Status = k8s.BundleStatusFailed
+	}
+	for i, result := range res_data.Members {
+		implemented.Members = append(implemented.Members, k8s.BundleMember{
+			PolicyID:      policies[i].ID,
+			PolicyTitle:   policies[i].PolicyTitle,
+			ImplementedID: result.ImplementedID,
+			Status:        result.Status,
+			Error:         result.Error,
+		})
+		if result.Error != "" {
+			errors = append(errors, fmt.Sprintf("%s: %s", policies[i].PolicyTitle, result.Error))
+		}
+		if result.Status != agent_dto.BUNDLE_MEMBER_APPLIED {
+			continue
+		}
+		if !res_data.Deployed {
+			implemented.Status = k8s.BundleStatusRollbackFailed
+		}
+
+		err := persistance.CreateImplimentedPolicy(k8s.ImplimentedPolicies{
+			ID:                 result.ImplementedID,
+			ClusterID:          clusters[0].ID,
+			PolicyID:           policies[i].ID,
+			BundleID:           implemented.ID,
+			PolicyTitle:        policies[i].PolicyTitle,
+			Description:        policies[i].Description,
+			AppLabel:           data.AppLabel,
+			Selector:           effectiveSelector(dto.DeployPolicyRequest{DeployTarget: data.DeployTarget}),
+			Scope:              data.Scope,
+			Namespace:          data.Namespace,
+			Namespaces:         data.Namespaces,
+			ExcludedNamespaces: data.ExcludedNamespaces,
+			IncludeHost:        data.IncludeHost,
+			PolicyFilePath:     result.PolicyPath,
+			Params:             request.Members[i].Params,
+			PolicyVersion:      versions[i],
+			DriftStatus:        k8s.DriftStatusInSync,
+			DriftCheckedAt:     bun.NullTime{Time: time.Now()},
+			AuditFields: k8s.AuditFields{
+				CreatedBy: uid,
+				CreatedAt: time.Now(),
+			},
+		})
+		if err != nil {
+			// Without a record the member would be invisible to bundle removal and drift checks, so it is
+			// removed from the cluster again and the bundle is not deployed
+			member := &implemented.Members[len(implemented.Members)-1]
+			member.Status = agent_dto.BUNDLE_MEMBER_FAILED
+			member.Error = fmt.Sprintf("failed to record the policy: %v", err)
+			if implemented.Status == k8s.BundleStatusDeployed {
+				implemented.Status = k8s.BundleStatusFailed
+			}
+			_, remove_err := client.Post("http://"+ip+":"+fmt.Sprintf("%d", port)+agent_consts.DELETE_TETRAGON_POLICY, routes.PolicyPathRequest{Path: result.PolicyPath, ImplementedID: result.ImplementedID}, map[string]string{})
+			if remove_err != nil {
+				member.Status = agent_dto.BUNDLE_MEMBER_APPLIED
+				member.Error += fmt.Sprintf("; removing it from the cluster failed: %v", remove_err)
+				implemented.Status = k8s.BundleStatusRollbackFailed
+			}
+			errors = append(errors, fmt.Sprintf("%s: %s", policies[i].PolicyTitle, member.Error))
+		}
+	}
+
+	if err := persistance.CreateImplementedBundle(&implemented); err != nil {
+		return global_dto.Response[k8s.ImplementedBundles]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.CREATION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	if implemented.Status != k8s.BundleStatusDeployed {
+		return global_dto.Response[k8s.ImplementedBundles]{
+			Status:  "error",
+			Message: message.BUNDLE_DEPLOY_FAILED,
+			Errors:  errors,
+			Data:    &implemented,
+			Meta: &global_dto.Meta{
+				Code: response.BUNDLE_DEPLOY_FAILED,
+			},
+		}, fiber.StatusBadGateway
+	}
+
+	return global_dto.Response[k8s.ImplementedBundles]{
+		Status:  "success",
+		Message: message.BUNDLE_DEPLOYED,
+		Data:    &implemented,
+		Meta: &global_dto.Meta{
+			Code: response.BUNDLE_DEPLOYED,
+		},
+	}, fiber.StatusOK
+}
+
+func GetImplementedBundles() (global_dto.Response[[]k8s.ImplementedBundles], int) {
+
+	bundles, err := persistance.GetAllImplementedBundles()
+	if err != nil {
+		return global_dto.Response[[]k8s.ImplementedBundles]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[[]k8s.ImplementedBundles]{
+		Status:  "success",
+		Message: "",
+		Data:    &bundles,
+		Meta: &global_dto.Meta{
+			Code: response.POLICY_BUNDLES,
+		},
+	}, fiber.StatusOK
+}
+
+// RemoveImplementedBundle deletes every policy deployed with the bundle, then the bundle record.
+// When a member cannot be removed the record is kept, with the members removed so far marked, so it can be retried.
+func RemoveImplementedBundle(id string, uid string) (global_dto.Response[k8s.ImplementedBundles], int) {
+	driftLock.Lock()
+	defer driftLock.Unlock()
+
+	bundle, err := persistance.GetImplementedBundleById(id)
+	if err != nil {
+		return global_dto.Response[k8s.ImplementedBundles]{
+			Status:  "error",
+			Message: message.BUNDLE_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.BUNDLE_NOT_FOUND,
+			},
+		}, fiber.StatusNotFound
+	}
+
+	rows, err := persistance.GetImplimentedPoliciesByBundle(bundle.ID)
+	if err != nil {
+		return global_dto.Response[k8s.ImplementedBundles]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	c, err := cluster.GetClusterById(bundle.ClusterID)
+	if err != nil && len(rows) != 0 {
+		return global_dto.Response[k8s.ImplementedBundles]{
+			Status:  "error",
+			Message: message.CLUSTER_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.CLUSTER_NOT_FOUND,
+			},
+		}, fiber.StatusNotFound
+	}
+
+	client := httpclient.NewClient(0)
+
+	for _, row := range rows {
+		_, err := client.Post("http://"+c.MasterIP+":"+fmt.Sprintf("%d", c.AgentPort)+agent_consts.DELETE_TETRAGON_POLICY, routes.PolicyPathRequest{Path: row.PolicyFilePath, ImplementedID: row.ID}, map[string]string{})
+		if err == nil {
+			err = persistance.DeleteImplimentedPolicyById(row.ID)
+		}
+		if err != nil {
+			logger.Log(logger.DEBUG, "HTTP Request Error", logger.Field{Key: "error", Value: err.Error()})
+			bundle.UpdatedBy = uid
+			bundle.UpdatedAt = bun.NullTime{Time: time.Now()}
+			persistance.UpdateImplementedBundle(bundle)
+			return global_dto.Response[k8s.ImplementedBundles]{
+				Status:  "error",
+				Message: message.BUNDLE_REMOVE_FAILED,
+				Errors:  []any{fmt.Sprintf("%s: %v", row.PolicyTitle, err)},
+				Data:    &bundle,
+				Meta: &global_dto.Meta{
+					Code: response.BUNDLE_REMOVE_FAILED,
+				},
+			}, fiber.StatusBadGateway
+		}
+		markBundleMember(&bundle, row.ID)
+	}
+
+	if err := persistance.DeleteImplementedBundleById(bundle.ID); err != nil {
+		return global_dto.Response[k8s.ImplementedBundles]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[k8s.ImplementedBundles]{
+		Status:  "success",
+		Message: message.BUNDLE_REMOVED,
+		Data:    &bundle,
+		Meta: &global_dto.Meta{
+			Code: response.BUNDLE_REMOVED,
+		},
+	}, fiber.StatusOK
+}
+
+// markBundleMember marks the member with the implemented policy id as removed
+func markBundleMember(bundle *k8s.ImplementedBundles, implemented_id string) {
+	for i := range bundle.Members {
+		if bundle.Members[i].ImplementedID == implemented_id {
+			bundle.Members[i].Status = k8s.BundleMemberRemoved
+		}
+	}
+}
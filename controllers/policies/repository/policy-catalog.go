@@ -0,0 +1,440 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/FearLessSaad/SNFOK/agent/tooling/manifests"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/templates"
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/dto"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/persistance"
+	"github.com/FearLessSaad/SNFOK/db/models/k8s"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/logger"
+	"github.com/gofiber/fiber"
+	"github.com/uptrace/bun"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// defaultCatalogImportInterval is used when CATALOG_IMPORT_INTERVAL is not set or invalid
+const defaultCatalogImportInterval = time.Hour
+
+// catalogPolicyID is the policy id templates are rendered with for the catalog, it is cut off the object name again
+const catalogPolicyID = "catalog"
+
+// catalogLock keeps the import job, the import endpoint and the CLI of one process from importing at the same time
+var catalogLock sync.Mutex
+
+// catalogKinds maps the kinds the agent can deploy from the catalog to their policy type.
+// Templates with other kinds, e.g. KubeArmorHostPolicy, are skipped.
+var catalogKinds = map[string]string{
+	"TracingPolicy":           k8s.PolicyTypeTetragon,
+	"TracingPolicyNamespaced": k8s.PolicyTypeTetragon,
+	"KubeArmorPolicy":         k8s.PolicyTypeKubeArmor,
+	"KubeArmorClusterPolicy":  k8s.PolicyTypeKubeArmor,
+	"NetworkPolicy":           k8s.PolicyTypeNetwork,
+}
+
+// catalogFrameworks maps the compliance packs of the kuberarmor templates directory to their framework.
+// Templates of the other packs, e.g. kuberarmor/nginx, belong to no framework.
+var catalogFrameworks = map[string]string{
+	"mitre":   "MITRE",
+	"nist":    "NIST",
+	"cis":     "CIS",
+	"pci-dss": "PCI-DSS",
+	"stigs":   "STIG",
+}
+
+// CatalogImportInterval returns how often the templates directory is imported, read from CATALOG_IMPORT_INTERVAL (e.g. "1h")
+func CatalogImportInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("CATALOG_IMPORT_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return defaultCatalogImportInterval
+	}
+	return interval
+}
+
+// RunCatalogImporter periodically imports POLICIES_TEMPLATES_DIR into the catalog
+func RunCatalogImporter(interval time.Duration) {
+	importCatalogJob()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		importCatalogJob()
+	}
+}
+
+func importCatalogJob() {
+	result, err := ImportCatalog(os.Getenv("POLICIES_TEMPLATES_DIR"), "SNFOK:CATALOG")
+	if err != nil {
+		logger.Log(logger.ERROR, "Policy catalog import failed.", logger.Field{Key: "error", Value: err.Error()})
+		return
+	}
+	logger.Log(logger.INFO, "Policy catalog imported.",
+		logger.Field{Key: "created", Value: result.Created},
+		logger.Field{Key: "updated", Value: result.Updated},
+		logger.Field{Key: "skipped", Value: len(result.Skipped)})
+}
+
+// ImportCatalog walks the templates directory and upserts a catalog policy for every template the agent can deploy.
+// Policies are matched on the hash of their template first and on their file path second, so a moved template
+// keeps its catalog entry and a changed template updates the entry of its file. Titles and descriptions of
+// existing entries are only replaced when the template declares them in its catalog block.
+func ImportCatalog(dir string, actor string) (dto.CatalogImportResult, error) {
+	catalogLock.Lock()
+	defer catalogLock.Unlock()
+
+	result := dto.CatalogImportResult{
+		Directory:  dir,
+		ImportedAt: time.Now(),
+		Skipped:    []dto.CatalogImportSkip{},
+	}
+	if dir == "" {
+		return result, fmt.Errorf("POLICIES_TEMPLATES_DIR is not set")
+	}
+
+	seen := make(map[string]string)
+	variants := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ext := filepath.Ext(path); ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		result.Scanned++
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			result.Skipped = append(result.Skipped, dto.CatalogImportSkip{Path: rel, Reason: err.Error()})
+			return nil
+		}
+
+		sum := sha256.Sum256(content)
+		hash := hex.EncodeToString(sum[:])
+		if first, duplicate := seen[hash]; duplicate {
+			result.Skipped = append(result.Skipped, dto.CatalogImportSkip{Path: rel, Reason: "same content as " + first})
+			return nil
+		}
+		seen[hash] = rel
+
+		policy, annotation, err := parseCatalogTemplate(rel, string(content))
+		if err != nil {
+			result.Skipped = append(result.Skipped, dto.CatalogImportSkip{Path: rel, Reason: err.Error()})
+			return nil
+		}
+		policy.ContentHash = hash
+		variants[rel] = annotation.Variant
+
+		created, changed, err := upsertCatalogPolicy(policy, annotation, string(content), actor)
+		if err != nil {
+			return err
+		}
+		switch {
+		case created:
+			result.Created++
+		case changed:
+			result.Updated++
+		default:
+			result.Unchanged++
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if err := linkCatalogVariants(variants, actor); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// linkCatalogVariants points every imported policy at the policy of the template its catalog block names as its
+// variant. Variants that were not imported or cannot deploy the policy in the other mode are left out.
+func linkCatalogVariants(variants map[string]string, actor string) error {
+	for rel, variant_rel := range variants {
+		policy, found, err := persistance.GetPolicyByFilePath(rel)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+
+		variant_id := ""
+		if variant_rel != "" {
+			variant, found, err := persistance.GetPolicyByFilePath(variant_rel)
+			if err != nil {
+				return err
+			}
+			if !found {
+				err = fmt.Errorf("variant %s is not in the catalog", variant_rel)
+			} else if err = checkVariant(policy, variant); err == nil {
+				variant_id = variant.ID
+			}
+			if err != nil {
+				logger.Log(logger.WARN, "Catalog variant ignored.", logger.Field{Key: "path", Value: rel}, logger.Field{Key: "error", Value: err.Error()})
+			}
+		}
+
+		if policy.VariantID == variant_id {
+			continue
+		}
+		policy.VariantID = variant_id
+		policy.UpdatedBy = actor
+		policy.UpdatedAt = bun.NullTime{Time: time.Now()}
+		if err := persistance.UpdatePolicy(policy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upsertCatalogPolicy stores the policy parsed from a template and reports whether it was created or changed.
+// Template content the policy did not have before is recorded as its next version.
+func upsertCatalogPolicy(policy k8s.AllPolicies, annotation templates.CatalogAnnotation, content string, actor string) (bool, bool, error) {
+	existing, found, err := persistance.GetPolicyByContentHash(policy.ContentHash)
+	if err != nil {
+		return false, false, err
+	}
+	if !found {
+		existing, found, err = persistance.GetPolicyByFilePath(policy.PolicyFilePath)
+		if err != nil {
+			return false, false, err
+		}
+	}
+
+	now := time.Now()
+	if !found {
+		policy.ImportedAt = bun.NullTime{Time: now}
+		policy.CreatedBy = actor
+		policy.CreatedAt = now
+		if err := createPolicy(&policy, content, actor); err != nil {
+			return false, false, err
+		}
+		return true, false, nil
+	}
+
+	updated := existing
+	updated.PolicyType = policy.PolicyType
+	updated.PolicyFilePath = policy.PolicyFilePath
+	updated.ContentHash = policy.ContentHash
+	updated.Kind = policy.Kind
+	updated.Name = policy.Name
+	updated.Tags = policy.Tags
+	updated.Framework = policy.Framework
+	updated.Mode = policy.Mode
+	updated.Params = policy.Params
+	if annotation.Title != "" || updated.PolicyTitle == "" {
+		updated.PolicyTitle = policy.PolicyTitle
+	}
+	if annotation.Description != "" || updated.Description == "" {
+		updated.Description = policy.Description
+	}
+
+	// Policies imported before versions were recorded get their current template as version 1
+	version, err := nextPolicyVersion(&updated, content, actor)
+	if err != nil {
+		return false, false, err
+	}
+
+	if catalogPolicyEqual(existing, updated) {
+		return false, false, nil
+	}
+	updated.ImportedAt = bun.NullTime{Time: now}
+	updated.UpdatedBy = actor
+	updated.UpdatedAt = bun.NullTime{Time: now}
+	return false, true, persistance.UpdatePolicyWithVersion(updated, version)
+}
+
+// catalogPolicyEqual compares the imported fields of two catalog policies
+func catalogPolicyEqual(a k8s.AllPolicies, b k8s.AllPolicies) bool {
+	if a.PolicyTitle != b.PolicyTitle || a.Description != b.Description || a.PolicyType != b.PolicyType ||
+		a.PolicyFilePath != b.PolicyFilePath || a.ContentHash != b.ContentHash || a.Kind != b.Kind ||
+		a.Name != b.Name || a.Framework != b.Framework || a.Version != b.Version || a.Mode != b.Mode || !slices.Equal(a.Tags, b.Tags) || len(a.Params) != len(b.Params) {
+		return false
+	}
+	for i := range a.Params {
+		if a.Params[i].Name != b.Params[i].Name || a.Params[i].Type != b.Params[i].Type ||
+			a.Params[i].Description != b.Params[i].Description || fmt.Sprint(a.Params[i].Default) != fmt.Sprint(b.Params[i].Default) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseCatalogTemplate reads the catalog entry of a template from its catalog block and, for what the block
+// leaves out, from the objects it renders to with the default parameter values
+func parseCatalogTemplate(rel string, content string) (k8s.AllPolicies, templates.CatalogAnnotation, error) {
+	annotation, err := templates.ParseCatalogAnnotation(content)
+	if err != nil {
+		return k8s.AllPolicies{}, annotation, err
+	}
+	params, err := templates.ParseParams(content)
+	if err != nil {
+		return k8s.AllPolicies{}, annotation, err
+	}
+
+	rendered, err := templates.RenderTemplate(filepath.Base(rel), content, catalogPolicyID, "default", "snfok", nil)
+	if err != nil {
+		return k8s.AllPolicies{}, annotation, fmt.Errorf("%s %v", rendered, err)
+	}
+	objects, err := manifests.Decode([]byte(rendered))
+	if err != nil {
+		return k8s.AllPolicies{}, annotation, err
+	}
+	for _, obj := range objects {
+		if _, supported := catalogKinds[obj.GetKind()]; !supported {
+			return k8s.AllPolicies{}, annotation, fmt.Errorf("kind %s cannot be deployed by the agent", obj.GetKind())
+		}
+	}
+
+	first := objects[0]
+	policy := k8s.AllPolicies{
+		PolicyTitle:    annotation.Title,
+		Description:    annotation.Description,
+		PolicyType:     catalogKinds[first.GetKind()],
+		PolicyFilePath: rel,
+		Source:         k8s.PolicySourceCatalog,
+		Kind:           first.GetKind(),
+		Name:           strings.TrimSuffix(strings.TrimSuffix(first.GetName(), catalogPolicyID), "-"),
+		Tags:           annotation.Tags,
+		Framework:      catalogFramework(rel),
+		Mode:           annotation.Mode,
+		Params:         params,
+	}
+	if policy.PolicyTitle == "" {
+		policy.PolicyTitle = policy.Name
+	}
+	if policy.Description == "" {
+		// KubeArmor policies carry the alert message of the policy
+		policy.Description, _, _ = unstructured.NestedString(first.Object, "spec", "message")
+	}
+	for _, obj := range objects {
+		spec_tags, _, _ := unstructured.NestedStringSlice(obj.Object, "spec", "tags")
+		for _, tag := range spec_tags {
+			if !slices.Contains(policy.Tags, tag) {
+				policy.Tags = append(policy.Tags, tag)
+			}
+		}
+	}
+	if policy.Mode == "" && policy.PolicyType == k8s.PolicyTypeNetwork {
+		// A NetworkPolicy has no audit mode, traffic it does not allow is dropped
+		policy.Mode = agent_dto.POLICY_MODE_ENFORCE
+	}
+	if policy.Mode == "" {
+		policy.Mode = agent_dto.POLICY_MODE_AUDIT
+		for _, obj := range objects {
+			if manifests.EnforcesActions(obj.Object["spec"]) {
+				policy.Mode = agent_dto.POLICY_MODE_ENFORCE
+			}
+		}
+	}
+
+	return policy, annotation, nil
+}
+
+// catalogFramework returns the framework of a template from its pack in the layout kuberarmor/<pack>/..., e.g.
+// kuberarmor/pci-dss/system/audit-log.yaml belongs to PCI-DSS
+func catalogFramework(rel string) string {
+	parts := strings.Split(rel, "/")
+	if len(parts) < 3 || parts[0] != "kuberarmor" {
+		return ""
+	}
+	return catalogFrameworks[strings.ToLower(parts[1])]
+}
+
+// defaultCatalogPageSize is the page size of the catalog when the request does not set one
+const defaultCatalogPageSize = 20
+
+// GetPolicyCatalog returns one page of the catalog policies matching the search and filters of the query
+func GetPolicyCatalog(query dto.CatalogQuery) (global_dto.Response[[]k8s.AllPolicies], int) {
+
+	page := max(query.Page, 1)
+	per_page := query.PerPage
+	if per_page <= 0 {
+		per_page = defaultCatalogPageSize
+	}
+
+	policies, total, err := persistance.SearchPolicies(query.Search, query.PolicyType, query.Source, query.Framework, query.Control, query.Mode, per_page, (page-1)*per_page)
+	if err != nil {
+		return global_dto.Response[[]k8s.AllPolicies]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	var next_page *int
+	if page*per_page < total {
+		next := page + 1
+		next_page = &next
+	}
+
+	return global_dto.Response[[]k8s.AllPolicies]{
+		Status:  "success",
+		Message: "",
+		Data:    &policies,
+		Meta: &global_dto.Meta{
+			TotalCount:  int64(total),
+			CurrentPage: page,
+			NextPage:    next_page,
+			Code:        response.POLICY_CATALOG,
+		},
+	}, fiber.StatusOK
+}
+
+// ImportCatalogNow imports the templates directory immediately instead of waiting for the next interval
+func ImportCatalogNow(uid string) (global_dto.Response[dto.CatalogImportResult], int) {
+
+	result, err := ImportCatalog(os.Getenv("POLICIES_TEMPLATES_DIR"), uid)
+	if err != nil {
+		return global_dto.Response[dto.CatalogImportResult]{
+			Status:  "error",
+			Message: message.CATALOG_IMPORT_FAILED,
+			Errors:  []any{err.Error()},
+			Data:    &result,
+			Meta: &global_dto.Meta{
+				Code: response.CATALOG_IMPORT_FAILED,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[dto.CatalogImportResult]{
+		Status:  "success",
+		Message: message.CATALOG_IMPORTED,
+		Data:    &result,
+		Meta: &global_dto.Meta{
+			Code: response.CATALOG_IMPORTED,
+		},
+	}, fiber.StatusOK
+}
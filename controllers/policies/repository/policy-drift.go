@@ -0,0 +1,375 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/FearLessSaad/SNFOK/agent/controllers/policies/routes"
+	"github.com/FearLessSaad/SNFOK/constants/agent_consts"
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/dto"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/persistance"
+	"github.com/FearLessSaad/SNFOK/db/models/k8s"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/httpclient"
+	"github.com/FearLessSaad/SNFOK/tooling/logger"
+	"github.com/gofiber/fiber"
+	"github.com/uptrace/bun"
+
+	cluster "github.com/FearLessSaad/SNFOK/controllers/clusters/persistance"
+)
+
+// defaultDriftInterval is used when DRIFT_RECONCILE_INTERVAL is not set or invalid
+const defaultDriftInterval = 5 * time.Minute
+
+// driftLock keeps the reconciler from running while a policy is deployed or deleted,
+// otherwise a policy in flight would be seen as missing or orphaned
+var driftLock sync.Mutex
+
+// DriftInterval returns how often the reconciler runs, read from DRIFT_RECONCILE_INTERVAL (e.g. "5m")
+func DriftInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("DRIFT_RECONCILE_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return defaultDriftInterval
+	}
+	return interval
+}
+
+// RunDriftReconciler periodically compares the implemented policies with the policies present in the clusters
+func RunDriftReconciler(interval time.Duration) {
+	ReconcileDrift()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ReconcileDrift()
+	}
+}
+
+// ReconcileDrift reconciles every registered cluster once and returns the new reports
+func ReconcileDrift() []dto.DriftReport {
+	driftLock.Lock()
+	defer driftLock.Unlock()
+
+	clusters, err := cluster.GetAllClusters()
+	if err != nil {
+		return nil
+	}
+	policies, err := persistance.GetAllImplimentedPolicies()
+	if err != nil {
+		return nil
+	}
+
+	reports := []dto.DriftReport{}
+	for i, c := range clusters {
+		var rows []k8s.ImplimentedPolicies
+		for _, policy := range policies {
+			// Policies deployed before clusters were recorded on the row all went to the first cluster
+			if policy.ClusterID == c.ID || (policy.ClusterID == "" && i == 0) {
+				rows = append(rows, policy)
+			}
+		}
+
+		report := reconcileCluster(c, rows)
+		if report.Error != "" {
+			logger.Log(logger.ERROR, "Policy drift reconciliation failed.", logger.Field{Key: "cluster", Value: c.ClusterName}, logger.Field{Key: "error", Value: report.Error})
+		}
+		reports = append(reports, report)
+	}
+
+	// The reports are stored so the orphans stay visible after a restart, until the next reconciliation
+	records := []k8s.DriftReports{}
+	for _, report := range reports {
+		records = append(records, k8s.DriftReports{
+			ClusterID:   report.ClusterID,
+			ClusterName: report.ClusterName,
+			CheckedAt:   report.CheckedAt,
+			InSync:      report.InSync,
+			Missing:     report.Missing,
+			Reapplied:   report.Reapplied,
+			Adopted:     report.Adopted,
+			Orphaned:    report.Orphaned,
+			Error:       report.Error,
+		})
+	}
+	persistance.ReplaceDriftReports(records)
+
+	return reports
+}
+
+// reconcileCluster marks the implemented policies of a cluster as in sync or missing, depending on whether
+// objects labelled with their id still exist, and collects the managed objects no policy accounts for.
+// Policies without a cluster id were applied before objects were labelled and keep the unknown status.
+func reconcileCluster(c k8s.Clusters, rows []k8s.ImplimentedPolicies) dto.DriftReport {
+	report := dto.DriftReport{
+		ClusterID:   c.ID,
+		ClusterName: c.ClusterName,
+		CheckedAt:   time.Now(),
+		Orphaned:    []agent_dto.ManagedObject{},
+	}
+
+	client := httpclient.NewClient(0)
+
+	res, err := client.Get("http://"+c.MasterIP+":"+fmt.Sprintf("%d", c.AgentPort)+agent_consts.POLICIES_MANAGED, map[string]string{})
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	var objects []agent_dto.ManagedObject
+	if err := json.Unmarshal(res.Body, &objects); err != nil {
+		report.Error = err.Error()
+		return report
+	}
+
+	by_id := make(map[string]k8s.ImplimentedPolicies)
+	for _, row := range rows {
+		by_id[row.ID] = row
+	}
+
+	present := make(map[string][]agent_dto.ManagedObject)
+	for _, obj := range objects {
+		// Isolation policies are tracked by the pod isolation records
+		if _, isolation := obj.Labels[agent_consts.ISOLATION_LABEL]; isolation {
+			continue
+		}
+		// An object outside the scope of its policy does not count for it, e.g. after the policy was redeployed
+		// with another scope while the old object stayed behind
+		if row, tracked := by_id[obj.ImplementedID]; tracked && row.ClusterID != "" && !inPolicyScope(row, obj) {
+			report.Orphaned = append(report.Orphaned, obj)
+			continue
+		}
+		present[obj.ImplementedID] = append(present[obj.ImplementedID], obj)
+	}
+
+	tracked := make(map[string]bool)
+	for _, row := range rows {
+		tracked[row.ID] = true
+		if row.ClusterID == "" {
+			continue
+		}
+
+		status := k8s.DriftStatusInSync
+		if len(present[row.ID]) == 0 {
+			status = k8s.DriftStatusMissing
+			if c.ReapplyMissing {
+				if err := reapplyPolicy(c, &row); err != nil {
+					logger.Log(logger.ERROR, "Failed to re-apply missing policy.", logger.Field{Key: "policy", Value: row.ID}, logger.Field{Key: "error", Value: err.Error()})
+				} else {
+					status = k8s.DriftStatusInSync
+					report.Reapplied++
+				}
+			}
+		}
+
+		if status == k8s.DriftStatusInSync {
+			report.InSync++
+		} else {
+			report.Missing++
+		}
+		row.DriftStatus = status
+		row.DriftCheckedAt = bun.NullTime{Time: report.CheckedAt}
+		persistance.UpdateImplimentedPolicy(row)
+	}
+
+	for implemented_id, orphans := range present {
+		if implemented_id != "" && tracked[implemented_id] {
+			continue
+		}
+		// Objects without an implemented id were applied before objects were labelled and cannot be adopted
+		if implemented_id != "" && c.AdoptOrphans {
+			if err := adoptPolicy(c, implemented_id, orphans); err != nil {
+				logger.Log(logger.ERROR, "Failed to adopt orphaned policy.", logger.Field{Key: "policy", Value: implemented_id}, logger.Field{Key: "error", Value: err.Error()})
+			} else {
+				report.Adopted++
+				continue
+			}
+		}
+		report.Orphaned = append(report.Orphaned, orphans...)
+	}
+	sort.Slice(report.Orphaned, func(i, j int) bool {
+		if report.Orphaned[i].Kind != report.Orphaned[j].Kind {
+			return report.Orphaned[i].Kind < report.Orphaned[j].Kind
+		}
+		return report.Orphaned[i].Namespace+"/"+report.Orphaned[i].Name < report.Orphaned[j].Namespace+"/"+report.Orphaned[j].Name
+	})
+
+	return report
+}
+
+// inPolicyScope reports whether a managed object matches the scope and namespace of its implemented policy.
+// Objects labelled before scopes existed are workload objects.
+func inPolicyScope(row k8s.ImplimentedPolicies, obj agent_dto.ManagedObject) bool {
+	scope := objectScope(obj)
+	if scope != row.Scope && !(row.Scope == "" && scope == agent_dto.POLICY_SCOPE_WORKLOAD) {
+		return false
+	}
+	if scope == agent_dto.POLICY_SCOPE_CLUSTER {
+		return obj.Namespace == ""
+	}
+	// Cluster-scoped kinds such as TracingPolicy can also be deployed for a workload
+	return obj.Namespace == "" || obj.Namespace == row.Namespace
+}
+
+// objectScope returns the scope a managed object was deployed with
+func objectScope(obj agent_dto.ManagedObject) string {
+	if scope := obj.Labels[agent_consts.SCOPE_LABEL]; scope != "" {
+		return scope
+	}
+	return agent_dto.POLICY_SCOPE_WORKLOAD
+}
+
+// reapplyPolicy renders the version of the catalog policy an implemented policy was deployed with again and applies
+// it under the same id
+func reapplyPolicy(c k8s.Clusters, row *k8s.ImplimentedPolicies) error {
+	if row.PolicyID == "" {
+		return fmt.Errorf("implemented policy %s has no catalog policy", row.ID)
+	}
+	policy, err := persistance.GetPlicysById(row.PolicyID)
+	if err != nil {
+		return err
+	}
+	// The version it was deployed with, a missing policy is not upgraded on the way
+	template, version, err := policyTemplate(policy, row.PolicyVersion)
+	if err != nil {
+		return err
+	}
+
+	client := httpclient.NewClient(0)
+	base := "http://" + c.MasterIP + ":" + fmt.Sprintf("%d", c.AgentPort)
+
+	// Remove the stale rendered file, the objects in it are already gone
+	if row.PolicyFilePath != "" {
+		client.Post(base+agent_consts.DELETE_TETRAGON_POLICY, routes.PolicyPathRequest{Path: row.PolicyFilePath, ImplementedID: row.ID}, map[string]string{})
+	}
+
+	res, err := client.Post(base+agent_consts.POLICIES_DEPLOY_POLICY, agent_dto.DeployPolicy{
+		Namespace:     row.Namespace,
+		AppLabel:      row.AppLabel,
+		FilePath:      policy.PolicyFilePath,
+		Template:      template,
+		PolicyID:      policy.ID,
+		ImplementedID: row.ID,
+		Params:        row.Params,
+		Selector:      row.Selector,
+		Mode:          agentMode(policy, row.Mode),
+
+		Scope:              row.Scope,
+		Namespaces:         row.Namespaces,
+		ExcludedNamespaces: row.ExcludedNamespaces,
+		IncludeHost:        row.IncludeHost,
+	}, map[string]string{
+		"Content-Type": "application/json",
+	})
+	if err != nil {
+		return err
+	}
+
+	var deployed DeployedPolicyResponse
+	if err := json.Unmarshal(res.Body, &deployed); err != nil {
+		return err
+	}
+
+	row.PolicyFilePath = deployed.PolicyPath
+	row.PolicyVersion = version
+	row.UpdatedBy = "SNFOK:RECONCILER"
+	row.UpdatedAt = bun.NullTime{Time: time.Now()}
+	return nil
+}
+
+// adoptPolicy recreates the implemented policy record of labelled objects whose record is gone, e.g. after a database restore
+func adoptPolicy(c k8s.Clusters, implemented_id string, objects []agent_dto.ManagedObject) error {
+	row := k8s.ImplimentedPolicies{
+		ID:             implemented_id,
+		ClusterID:      c.ID,
+		PolicyID:       objects[0].PolicyID,
+		PolicyTitle:    objects[0].Name,
+		Scope:          objectScope(objects[0]),
+		Namespace:      objects[0].Namespace,
+		DriftStatus:    k8s.DriftStatusInSync,
+		DriftCheckedAt: bun.NullTime{Time: time.Now()},
+		AuditFields: k8s.AuditFields{
+			CreatedBy: "SNFOK:RECONCILER",
+			CreatedAt: time.Now(),
+		},
+	}
+
+	if row.PolicyID != "" {
+		if policy, err := persistance.GetPlicysById(row.PolicyID); err == nil {
+			row.PolicyTitle = policy.PolicyTitle
+			row.Description = policy.Description
+		} else {
+			row.PolicyID = ""
+		}
+	}
+
+	return persistance.CreateImplimentedPolicy(row)
+}
+
+// GetDriftReports returns the last drift report of every cluster
+func GetDriftReports() (global_dto.Response[[]dto.DriftReport], int) {
+
+	records, err := persistance.GetDriftReports()
+	if err != nil {
+		return global_dto.Response[[]dto.DriftReport]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	reports := []dto.DriftReport{}
+	for _, record := range records {
+		orphaned := record.Orphaned
+		if orphaned == nil {
+			orphaned = []agent_dto.ManagedObject{}
+		}
+		reports = append(reports, dto.DriftReport{
+			ClusterID:   record.ClusterID,
+			ClusterName: record.ClusterName,
+			CheckedAt:   record.CheckedAt,
+			InSync:      record.InSync,
+			Missing:     record.Missing,
+			Reapplied:   record.Reapplied,
+			Adopted:     record.Adopted,
+			Orphaned:    orphaned,
+			Error:       record.Error,
+		})
+	}
+
+	return global_dto.Response[[]dto.DriftReport]{
+		Status:  "success",
+		Message: "",
+		Data:    &reports,
+		Meta: &global_dto.Meta{
+			Code: response.POLICY_DRIFT,
+		},
+	}, fiber.StatusOK
+}
+
+// ReconcileDriftNow runs the reconciler immediately instead of waiting for the next interval
+func ReconcileDriftNow() (global_dto.Response[[]dto.DriftReport], int) {
+
+	reports := ReconcileDrift()
+	if reports == nil {
+		reports = []dto.DriftReport{}
+	}
+
+	return global_dto.Response[[]dto.DriftReport]{
+		Status:  "success",
+		Message: "",
+		Data:    &reports,
+		Meta: &global_dto.Meta{
+			Code: response.POLICY_DRIFT,
+		},
+	}, fiber.StatusOK
+}
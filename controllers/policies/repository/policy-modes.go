@@ -0,0 +1,300 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/FearLessSaad/SNFOK/constants/agent_consts"
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/dto"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/persistance"
+	"github.com/FearLessSaad/SNFOK/db/models/k8s"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/httpclient"
+	"github.com/FearLessSaad/SNFOK/tooling/logger"
+	"github.com/gofiber/fiber"
+	"github.com/uptrace/bun"
+)
+
+// implementedMode returns the mode an implemented policy runs in. Policies deployed before modes were recorded
+// run in the mode of their catalog policy.
+func implementedMode(row k8s.ImplimentedPolicies, policy k8s.AllPolicies) string {
+	if row.Mode != "" {
+		return row.Mode
+	}
+	return policy.Mode
+}
+
+// agentMode returns the mode the agent renders a policy with. The template is already written in the mode of its
+// catalog policy, so its actions are only rewritten for the other mode.
+func agentMode(policy k8s.AllPolicies, mode string) string {
+	if mode == policy.Mode {
+		return ""
+	}
+	return mode
+}
+
+// policyVariant returns the policy that deploys policy in the other mode: the variant it declares, or a policy
+// that declares it as its variant
+func policyVariant(policy k8s.AllPolicies) (k8s.AllPolicies, bool, error) {
+	if policy.VariantID == "" {
+		return persistance.GetPolicyByVariant(policy.ID)
+	}
+	variant, err := persistance.GetPlicysById(policy.VariantID)
+	if err != nil {
+		return k8s.AllPolicies{}, false, err
+	}
+	return variant, true, nil
+}
+
+// checkVariant reports why variant cannot deploy policy in the other mode, if it cannot
+func checkVariant(policy k8s.AllPolicies, variant k8s.AllPolicies) error {
+	if variant.PolicyType != policy.PolicyType {
+		return fmt.Errorf("variant %q is a %s policy, not %s", variant.PolicyTitle, variant.PolicyType, policy.PolicyType)
+	}
+	if variant.Mode == policy.Mode {
+		return fmt.Errorf("variant %q is in %s mode as well", variant.PolicyTitle, variant.Mode)
+	}
+	return nil
+}
+
+// modePolicy returns the policy to deploy for mode and the mode the agent renders it with. A policy with a
+// variant in the mode is replaced by the variant, whose template is written for it. Otherwise the agent switches
+// KubeArmor rules between Audit and Block and drops the enforcing actions of Tetragon selectors; it never adds
+// any, so enforce mode is refused for a Tetragon policy without an enforce variant. The agent also refuses audit
+// mode for KubeArmor policies with Allow rules, see manifests.SetMode.
+func modePolicy(policy k8s.AllPolicies, mode string) (k8s.AllPolicies, string, error) {
+	if mode == "" || mode == policy.Mode {
+		return policy, "", nil
+	}
+
+	variant, found, err := policyVariant(policy)
+	if err != nil {
+		return k8s.AllPolicies{}, "", err
+	}
+	if found && variant.Mode == mode {
+		return variant, "", nil
+	}
+	if policy.PolicyType == k8s.PolicyTypeTetragon && mode == agent_dto.POLICY_MODE_ENFORCE {
+		return k8s.AllPolicies{}, "", fmt.Errorf("policy %q has no enforce variant", policy.PolicyTitle)
+	}
+	return policy, mode, nil
+}
+
+// ChangePolicyMode switches an implemented policy between audit and enforce mode. The agent renders the deployed
+// version again with the actions of the new mode, or the latest version of the variant written for the mode, and
+// replaces the objects in place, so the implemented policy keeps its id.
+func ChangePolicyMode(id string, data dto.PolicyModeRequest, uid string) (global_dto.Response[dto.PolicyModeResult], int) {
+	driftLock.Lock()
+	defer driftLock.Unlock()
+
+	row, policy, status := upgradeTarget(id)
+	if status != fiber.StatusOK {
+		return global_dto.Response[dto.PolicyModeResult]{
+			Status:  "error",
+			Message: message.POLICY_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_NOT_FOUND,
+			},
+		}, status
+	}
+
+	from := implementedMode(row, policy)
+	if from == data.Mode {
+		return global_dto.Response[dto.PolicyModeResult]{
+			Status:  "error",
+			Message: message.POLICY_MODE_UNCHANGED,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_MODE_UNCHANGED,
+			},
+		}, fiber.StatusConflict
+	}
+
+	target, mode, err := modePolicy(policy, data.Mode)
+	if err != nil {
+		return global_dto.Response[dto.PolicyModeResult]{
+			Status:  "error",
+			Message: message.POLICY_MODE_UNSUPPORTED,
+			Errors:  []any{err.Error()},
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_MODE_UNSUPPORTED,
+			},
+		}, fiber.StatusConflict
+	}
+
+	// The version it is deployed with; policies without a recorded version use the stored template or file
+	template, version, params := policy.Content, row.PolicyVersion, row.Params
+	if target.ID != policy.ID {
+		template, version, err = policyTemplate(target, 0)
+		params, _ = versionParams(target.Params, row.Params)
+	} else if row.PolicyVersion != 0 {
+		template, _, err = policyTemplate(policy, row.PolicyVersion)
+	}
+	if err != nil {
+		return global_dto.Response[dto.PolicyModeResult]{
+			Status:  "error",
+			Message: message.POLICY_VERSION_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_VERSION_NOT_FOUND,
+			},
+		}, fiber.StatusNotFound
+	}
+
+	c, err := implementedCluster(row)
+	if err != nil {
+		return global_dto.Response[dto.PolicyModeResult]{
+			Status:  "error",
+			Message: message.CLUSTER_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.CLUSTER_NOT_FOUND,
+			},
+		}, fiber.StatusNotFound
+	}
+
+	client := httpclient.NewClient(0)
+
+	// The upgrade endpoint restores the objects of the previous mode when the new mode fails to apply
+	res, err := client.Post("http://"+c.MasterIP+":"+fmt.Sprintf("%d", c.AgentPort)+agent_consts.POLICIES_UPGRADE, agent_dto.UpgradePolicy{
+		DeployPolicy: agent_dto.DeployPolicy{
+			Namespace:     row.Namespace,
+			AppLabel:      row.AppLabel,
+			FilePath:      target.PolicyFilePath,
+			Template:      template,
+			PolicyID:      target.ID,
+			ImplementedID: row.ID,
+			Params:        params,
+			Selector:      row.Selector,
+			Mode:          mode,
+
+			Scope:              row.Scope,
+			Namespaces:         row.Namespaces,
+			ExcludedNamespaces: row.ExcludedNamespaces,
+			IncludeHost:        row.IncludeHost,
+		},
+		PreviousPath: row.PolicyFilePath,
+	}, map[string]string{
+		"Content-Type": "application/json",
+	})
+	if err != nil {
+		logger.Log(logger.DEBUG, "HTTP Request Error", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[dto.PolicyModeResult]{
+			Status:  "error",
+			Message: message.POLICY_MODE_CHANGE_FAILED,
+			Errors:  []any{err.Error()},
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_MODE_CHANGE_FAILED,
+			},
+		}, fiber.StatusBadGateway
+	}
+
+	var deployed agent_dto.DeployPolicyResponse
+	if err := json.Unmarshal(res.Body, &deployed); err != nil {
+		logger.Log(logger.DEBUG, "Unmarshal Response", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[dto.PolicyModeResult]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	if target.ID != policy.ID {
+		// The variant has its own versions, the previous version of the policy is no rollback target for it
+		row.PolicyID = target.ID
+		row.PolicyTitle = target.PolicyTitle
+		row.Description = target.Description
+		row.PolicyVersion = version
+		row.PreviousVersion = 0
+		row.Params = params
+	}
+	row.Mode = data.Mode
+	row.PolicyFilePath = deployed.PolicyPath
+	row.ClusterID = c.ID
+	row.UpdatedBy = uid
+	row.UpdatedAt = bun.NullTime{Time: time.Now()}
+	if err := persistance.UpdateImplimentedPolicy(row); err != nil {
+		// The cluster runs the new mode already, see changePolicyVersion
+		logger.Log(logger.ERROR, "Failed to record the policy mode.", logger.Field{Key: "policy", Value: row.ID}, logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[dto.PolicyModeResult]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	persistance.CreatePolicyModeChange(k8s.PolicyModeChanges{
+		ImplementedID: row.ID,
+		FromMode:      from,
+		ToMode:        data.Mode,
+		FromPolicyID:  policy.ID,
+		ToPolicyID:    target.ID,
+		Reason:        data.Reason,
+		AuditFields: k8s.AuditFields{
+			CreatedBy: uid,
+			CreatedAt: time.Now(),
+		},
+	})
+
+	return global_dto.Response[dto.PolicyModeResult]{
+		Status:  "success",
+		Message: message.POLICY_MODE_CHANGED,
+		Data: &dto.PolicyModeResult{
+			Policy:   row,
+			FromMode: from,
+			ToMode:   data.Mode,
+			Objects:  deployed.Objects,
+		},
+		Meta: &global_dto.Meta{
+			Code: response.POLICY_MODE_CHANGED,
+		},
+	}, fiber.StatusOK
+}
+
+// GetPolicyModeChanges returns the mode history of an implemented policy, newest first
+func GetPolicyModeChanges(id string) (global_dto.Response[[]k8s.PolicyModeChanges], int) {
+	if _, err := persistance.GetImplimentedPolicyById(id); err != nil {
+		return global_dto.Response[[]k8s.PolicyModeChanges]{
+			Status:  "error",
+			Message: message.POLICY_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_NOT_FOUND,
+			},
+		}, fiber.StatusNotFound
+	}
+
+	changes, err := persistance.GetPolicyModeChanges(id)
+	if err != nil {
+		return global_dto.Response[[]k8s.PolicyModeChanges]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[[]k8s.PolicyModeChanges]{
+		Status:  "success",
+		Message: "",
+		Data:    &changes,
+		Meta: &global_dto.Meta{
+			Code: response.POLICY_MODE_CHANGES,
+		},
+	}, fiber.StatusOK
+}
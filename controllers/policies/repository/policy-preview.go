@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/FearLessSaad/SNFOK/constants/agent_consts"
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/dto"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/persistance"
+	"github.com/FearLessSaad/SNFOK/db/models/k8s"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/httpclient"
+	"github.com/FearLessSaad/SNFOK/tooling/logger"
+	"github.com/gofiber/fiber"
+
+	cluster "github.com/FearLessSaad/SNFOK/controllers/clusters/persistance"
+)
+
+// PreviewPolicy asks the agent to render the policy and apply it in dry-run mode. Nothing is applied or recorded.
+// A preview the API server would reject is still returned, with the errors reported per object.
+func PreviewPolicy(data dto.DeployPolicyRequest) (global_dto.Response[agent_dto.PolicyPreview], int) {
+
+	get_policy, err := persistance.GetPlicysById(data.PolicyID)
+	if err != nil {
+		return global_dto.Response[agent_dto.PolicyPreview]{
+			Status:  "error",
+			Message: message.POLICY_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_NOT_FOUND,
+			},
+		}, fiber.StatusNotFound
+	}
+
+	// A mode the template is not written in is deployed from its variant, or by switching its actions
+	get_policy, agent_mode, err := modePolicy(get_policy, data.Mode)
+	if err != nil {
+		return global_dto.Response[agent_dto.PolicyPreview]{
+			Status:  "error",
+			Message: message.POLICY_MODE_UNSUPPORTED,
+			Errors:  []any{err.Error()},
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_MODE_UNSUPPORTED,
+			},
+		}, fiber.StatusConflict
+	}
+
+	clusters, _ := cluster.GetAllClusters()
+	if len(clusters) == 0 {
+		return global_dto.Response[agent_dto.PolicyPreview]{
+			Status:  "error",
+			Message: message.NO_REGISTERED_CLUSTER_AVAILABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.NO_CLUSTER_AVAILABLE,
+			},
+		}, fiber.StatusNotFound
+	}
+	ip := clusters[0].MasterIP
+	port := clusters[0].AgentPort
+
+	template, _, err := policyTemplate(get_policy, 0)
+	if err != nil {
+		return global_dto.Response[agent_dto.PolicyPreview]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	client := httpclient.NewClient(0)
+
+	res, err := client.Post("http://"+ip+":"+fmt.Sprintf("%d", port)+agent_consts.POLICIES_PREVIEW, agent_dto.DeployPolicy{
+		Namespace: data.Namespace,
+		AppLabel:  data.AppLabel,
+		FilePath:  get_policy.PolicyFilePath,
+		Template:  template,
+		PolicyID:  get_policy.ID,
+		Params:    data.Params,
+		Selector:  data.Selector,
+		Mode:      agent_mode,
+
+		Scope:              data.Scope,
+		Namespaces:         data.Namespaces,
+		ExcludedNamespaces: data.ExcludedNamespaces,
+		IncludeHost:        data.IncludeHost,
+	}, map[string]string{
+		"Content-Type": "application/json",
+	})
+	if err != nil {
+		logger.Log(logger.DEBUG, "HTTP Request Error", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[agent_dto.PolicyPreview]{
+			Status:  "error",
+			Message: message.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			Errors:  []any{err.Error()},
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			},
+		}, fiber.StatusBadGateway
+	}
+
+	var preview agent_dto.PolicyPreview
+	if err := json.Unmarshal(res.Body, &preview); err != nil {
+		logger.Log(logger.DEBUG, "Unmarshal Response", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[agent_dto.PolicyPreview]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	if !preview.Valid {
+		return global_dto.Response[agent_dto.PolicyPreview]{
+			Status:  "error",
+			Message: message.POLICY_PREVIEW_INVALID,
+			Data:    &preview,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_PREVIEW,
+			},
+		}, fiber.StatusOK
+	}
+
+	return global_dto.Response[agent_dto.PolicyPreview]{
+		Status:  "success",
+		Message: "",
+		Data:    &preview,
+		Meta: &global_dto.Meta{
+			Code: response.POLICY_PREVIEW,
+		},
+	}, fiber.StatusOK
+}
+
+// GetPolicyParams returns the parameters the template of a catalog policy declares
+func GetPolicyParams(policy string) (global_dto.Response[[]agent_dto.PolicyParam], int) {
+
+	get_policy, err := persistance.GetPlicysById(policy)
+	if err != nil {
+		return global_dto.Response[[]agent_dto.PolicyParam]{
+			Status:  "error",
+			Message: message.POLICY_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_NOT_FOUND,
+			},
+		}, fiber.StatusNotFound
+	}
+
+	// Custom templates are stored here, their parameters were read when they were saved
+	if get_policy.Source == k8s.PolicySourceCustom {
+		params := get_policy.Params
+		if params == nil {
+			params = []agent_dto.PolicyParam{}
+		}
+		return global_dto.Response[[]agent_dto.PolicyParam]{
+			Status:  "success",
+			Message: "",
+			Data:    &params,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_PARAMS,
+			},
+		}, fiber.StatusOK
+	}
+
+	clusters, _ := cluster.GetAllClusters()
+	if len(clusters) == 0 {
+		return global_dto.Response[[]agent_dto.PolicyParam]{
+			Status:  "error",
+			Message: message.NO_REGISTERED_CLUSTER_AVAILABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.NO_CLUSTER_AVAILABLE,
+			},
+		}, fiber.StatusNotFound
+	}
+	ip := clusters[0].MasterIP
+	port := clusters[0].AgentPort
+
+	client := httpclient.NewClient(0)
+
+	res, err := client.Get("http://"+ip+":"+fmt.Sprintf("%d", port)+agent_consts.POLICIES_PARAMS+"?file="+url.QueryEscape(get_policy.PolicyFilePath), map[string]string{})
+	if err != nil {
+		logger.Log(logger.DEBUG, "HTTP Request Error", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[[]agent_dto.PolicyParam]{
+			Status:  "error",
+			Message: message.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			Errors:  []any{err.Error()},
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			},
+		}, fiber.StatusBadGateway
+	}
+
+	var params []agent_dto.PolicyParam
+	if err := json.Unmarshal(res.Body, &params); err != nil {
+		logger.Log(logger.DEBUG, "Unmarshal Response", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[[]agent_dto.PolicyParam]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[[]agent_dto.PolicyParam]{
+		Status:  "success",
+		Message: "",
+		Data:    &params,
+		Meta: &global_dto.Meta{
+			Code: response.POLICY_PARAMS,
+		},
+	}, fiber.StatusOK
+}
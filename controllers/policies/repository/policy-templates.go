@@ -0,0 +1,410 @@
+package repository
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/FearLessSaad/SNFOK/agent/tooling/manifests"
+	"github.com/FearLessSaad/SNFOK/agent/tooling/templates"
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/dto"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/persistance"
+	"github.com/FearLessSaad/SNFOK/db/models/k8s"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/gofiber/fiber"
+	"github.com/uptrace/bun"
+)
+
+// customTemplateKinds are the kinds a custom template can be written in
+var customTemplateKinds = map[string]bool{
+	"TracingPolicy":           true,
+	"TracingPolicyNamespaced": true,
+	"KubeArmorPolicy":         true,
+	"KubeArmorClusterPolicy":  true,
+}
+
+// Values a custom template is rendered with to check that it uses the built-in placeholders
+const (
+	placeholderPolicyID  = "snfokpolicyid"
+	placeholderNamespace = "snfok-namespace"
+	placeholderAppLabel  = "snfok-app-label"
+)
+
+func GetPolicyTemplates() (global_dto.Response[[]k8s.AllPolicies], int) {
+
+	policies, err := persistance.GetPoliciesBySource(k8s.PolicySourceCustom)
+	if err != nil {
+		return global_dto.Response[[]k8s.AllPolicies]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[[]k8s.AllPolicies]{
+		Status:  "success",
+		Message: "",
+		Data:    &policies,
+		Meta: &global_dto.Meta{
+			Code: response.POLICY_TEMPLATES,
+		},
+	}, fiber.StatusOK
+}
+
+func GetPolicyTemplate(id string) (global_dto.Response[k8s.AllPolicies], int) {
+
+	policy, err := persistance.GetPlicysById(id)
+	if err != nil || policy.Source != k8s.PolicySourceCustom {
+		return templateNotFound()
+	}
+
+	return global_dto.Response[k8s.AllPolicies]{
+		Status:  "success",
+		Message: "",
+		Data:    &policy,
+		Meta: &global_dto.Meta{
+			Code: response.POLICY_TEMPLATES,
+		},
+	}, fiber.StatusOK
+}
+
+// CreatePolicyTemplate validates a template uploaded by an author and adds it to the catalog
+func CreatePolicyTemplate(data dto.PolicyTemplateRequest, uid string) (global_dto.Response[k8s.AllPolicies], int) {
+
+	policy, errs := buildPolicyTemplate(data)
+	if len(errs) != 0 {
+		return templateInvalid(errs)
+	}
+
+	policy.CreatedBy = uid
+	policy.CreatedAt = time.Now()
+	if err := createPolicy(&policy, data.Content, uid); err != nil {
+		return global_dto.Response[k8s.AllPolicies]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.CREATION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[k8s.AllPolicies]{
+		Status:  "success",
+		Message: message.TEMPLATE_CREATED,
+		Data:    &policy,
+		Meta: &global_dto.Meta{
+			Code: response.TEMPLATE_CREATED,
+		},
+	}, fiber.StatusOK
+}
+
+// UpdatePolicyTemplate replaces the content and metadata of a custom template. Changed content becomes a new
+// version; policies deployed from an older version keep it until they are upgraded.
+func UpdatePolicyTemplate(id string, data dto.PolicyTemplateRequest, uid string) (global_dto.Response[k8s.AllPolicies], int) {
+
+	existing, err := persistance.GetPlicysById(id)
+	if err != nil || existing.Source != k8s.PolicySourceCustom {
+		return templateNotFound()
+	}
+
+	policy, errs := buildPolicyTemplate(data)
+	if len(errs) != 0 {
+		return templateInvalid(errs)
+	}
+
+	policy.ID = existing.ID
+	policy.AuditFields = existing.AuditFields
+	policy.UpdatedBy = uid
+	policy.UpdatedAt = bun.NullTime{Time: time.Now()}
+	version, err := nextPolicyVersion(&policy, data.Content, uid)
+	if err == nil {
+		err = persistance.UpdatePolicyWithVersion(policy, version)
+	}
+	if err != nil {
+		return global_dto.Response[k8s.AllPolicies]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[k8s.AllPolicies]{
+		Status:  "success",
+		Message: message.TEMPLATE_UPDATED,
+		Data:    &policy,
+		Meta: &global_dto.Meta{
+			Code: response.TEMPLATE_UPDATED,
+		},
+	}, fiber.StatusOK
+}
+
+// ClonePolicyTemplate copies a custom template, or the template file of a catalog policy, into a new custom
+// template that can be edited without touching the original
+func ClonePolicyTemplate(id string, data dto.ClonePolicyTemplateRequest, uid string) (global_dto.Response[k8s.AllPolicies], int) {
+
+	source, err := persistance.GetPlicysById(id)
+	if err != nil {
+		return global_dto.Response[k8s.AllPolicies]{
+			Status:  "error",
+			Message: message.POLICY_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_NOT_FOUND,
+			},
+		}, fiber.StatusNotFound
+	}
+
+	content := source.Content
+	if source.Source != k8s.PolicySourceCustom {
+		content, err = templates.ReadTemplate(source.PolicyFilePath)
+		if err != nil {
+			return global_dto.Response[k8s.AllPolicies]{
+				Status:  "error",
+				Message: message.POLICY_NOT_FOUND,
+				Errors:  []any{err.Error()},
+				Data:    nil,
+				Meta: &global_dto.Meta{
+					Code: response.POLICY_NOT_FOUND,
+				},
+			}, fiber.StatusNotFound
+		}
+	}
+
+	title := data.Title
+	if title == "" {
+		title = source.PolicyTitle + " (copy)"
+	}
+	// The variant of the original is written for the original, the copy only gets the one the request names
+	result, status := CreatePolicyTemplate(dto.PolicyTemplateRequest{
+		Title:       title,
+		Description: source.Description,
+		Tags:        source.Tags,
+		Mode:        source.Mode,
+		Content:     templates.RemoveCatalogVariant(content),
+		VariantID:   data.VariantID,
+	}, uid)
+	if status == fiber.StatusOK {
+		result.Message = message.TEMPLATE_CLONED
+		result.Meta.Code = response.TEMPLATE_CLONED
+	}
+	return result, status
+}
+
+// DeletePolicyTemplate removes a custom template that is neither deployed nor a member of a bundle
+func DeletePolicyTemplate(id string) (global_dto.Response[string], int) {
+
+	policy, err := persistance.GetPlicysById(id)
+	if err != nil || policy.Source != k8s.PolicySourceCustom {
+		return global_dto.Response[string]{
+			Status:  "error",
+			Message: message.TEMPLATE_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.TEMPLATE_NOT_FOUND,
+			},
+		}, fiber.StatusNotFound
+	}
+
+	in_use, err := persistance.PolicyInUse(id)
+	if err != nil {
+		return global_dto.Response[string]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+	if in_use {
+		return global_dto.Response[string]{
+			Status:  "error",
+			Message: message.TEMPLATE_IN_USE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.TEMPLATE_IN_USE,
+			},
+		}, fiber.StatusConflict
+	}
+
+	if err := persistance.DeletePolicyById(id); err != nil {
+		return global_dto.Response[string]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[string]{
+		Status:  "success",
+		Message: message.TEMPLATE_DELETED,
+		Data:    &id,
+		Meta: &global_dto.Meta{
+			Code: response.TEMPLATE_DELETED,
+		},
+	}, fiber.StatusOK
+}
+
+// buildPolicyTemplate validates a custom template and reads its catalog entry the way the catalog import reads
+// a template file. The title, description, tags, mode and variant of the request take precedence over the template.
+func buildPolicyTemplate(data dto.PolicyTemplateRequest) (k8s.AllPolicies, []any) {
+	if errs := validatePolicyTemplate(data.Content); len(errs) != 0 {
+		return k8s.AllPolicies{}, errs
+	}
+
+	policy, annotation, err := parseCatalogTemplate("", data.Content)
+	if err != nil {
+		return k8s.AllPolicies{}, []any{err.Error()}
+	}
+
+	policy.Source = k8s.PolicySourceCustom
+	policy.Content = data.Content
+	policy.PolicyTitle = data.Title
+	if data.Description != "" {
+		policy.Description = data.Description
+	}
+	for _, tag := range data.Tags {
+		if !slices.Contains(policy.Tags, tag) {
+			policy.Tags = append(policy.Tags, tag)
+		}
+	}
+	if data.Mode != "" {
+		policy.Mode = data.Mode
+	}
+
+	// The variant is given by id, or by the catalog template file the catalog block names
+	if data.VariantID != "" || annotation.Variant != "" {
+		var variant k8s.AllPolicies
+		found := false
+		if data.VariantID != "" {
+			variant, err = persistance.GetPlicysById(data.VariantID)
+			found = err == nil
+		} else {
+			variant, found, err = persistance.GetPolicyByFilePath(annotation.Variant)
+		}
+		if err != nil || !found {
+			return k8s.AllPolicies{}, []any{"variant policy not found"}
+		}
+		if err := checkVariant(policy, variant); err != nil {
+			return k8s.AllPolicies{}, []any{err.Error()}
+		}
+		policy.VariantID = variant.ID
+	}
+	return policy, nil
+}
+
+// validatePolicyTemplate renders a custom template with the default parameter values and checks that every object
+// is of an allowed kind and uses the built-in placeholders the agent relies on: {{.PolicyID}} in every name, so
+// deployments do not overwrite each other, and {{.Namespace}} and {{.AppLabel}} in namespaced objects, so a
+// workload deployment lands in its namespace and selects its pods
+func validatePolicyTemplate(content string) []any {
+	if _, err := templates.ParseParams(content); err != nil {
+		return []any{err.Error()}
+	}
+	if _, err := templates.ParseCatalogAnnotation(content); err != nil {
+		return []any{err.Error()}
+	}
+
+	rendered, err := templates.RenderTemplate("template", content, placeholderPolicyID, placeholderNamespace, placeholderAppLabel, nil)
+	if err != nil {
+		return []any{fmt.Sprintf("%s %v", rendered, err)}
+	}
+	objects, err := manifests.Decode([]byte(rendered))
+	if err != nil {
+		return []any{err.Error()}
+	}
+	if len(objects) == 0 {
+		return []any{"template has no objects"}
+	}
+
+	var errs []any
+	policy_type := ""
+	for _, obj := range objects {
+		kind := obj.GetKind()
+		if !customTemplateKinds[kind] {
+			errs = append(errs, fmt.Sprintf("kind %s is not allowed in a custom template", kind))
+			continue
+		}
+		_, namespaced, err := manifests.ResourceFor(obj)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if policy_type == "" {
+			policy_type = catalogKinds[kind]
+		} else if catalogKinds[kind] != policy_type {
+			errs = append(errs, fmt.Sprintf("%s %q is not a %s policy like the first object", kind, obj.GetName(), policy_type))
+		}
+
+		if !strings.Contains(obj.GetName(), placeholderPolicyID) {
+			errs = append(errs, fmt.Sprintf("name of %s %q does not contain {{.PolicyID}}", kind, obj.GetName()))
+		}
+		if !namespaced {
+			continue
+		}
+		if obj.GetNamespace() != placeholderNamespace {
+			errs = append(errs, fmt.Sprintf("namespace of %s %q is not {{.Namespace}}", kind, obj.GetName()))
+		}
+		if !containsValue(obj.Object["spec"], placeholderAppLabel) {
+			errs = append(errs, fmt.Sprintf("selector of %s %q does not use {{.AppLabel}}", kind, obj.GetName()))
+		}
+	}
+	return errs
+}
+
+// containsValue reports whether a string value anywhere in the decoded object equals want
+func containsValue(value interface{}, want string) bool {
+	switch value := value.(type) {
+	case string:
+		return value == want
+	case map[string]interface{}:
+		for _, nested := range value {
+			if containsValue(nested, want) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, nested := range value {
+			if containsValue(nested, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func templateNotFound() (global_dto.Response[k8s.AllPolicies], int) {
+	return global_dto.Response[k8s.AllPolicies]{
+		Status:  "error",
+		Message: message.TEMPLATE_NOT_FOUND,
+		Data:    nil,
+		Meta: &global_dto.Meta{
+			Code: response.TEMPLATE_NOT_FOUND,
+		},
+	}, fiber.StatusNotFound
+}
+
+func templateInvalid(errs []any) (global_dto.Response[k8s.AllPolicies], int) {
+	return global_dto.Response[k8s.AllPolicies]{
+		Status:  "error",
+		Message: message.TEMPLATE_INVALID,
+		Errors:  errs,
+		Data:    nil,
+		Meta: &global_dto.Meta{
+			Code: response.TEMPLATE_INVALID,
+		},
+	}, fiber.StatusUnprocessableEntity
+}
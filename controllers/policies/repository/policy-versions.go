@@ -0,0 +1,488 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/FearLessSaad/SNFOK/agent/tooling/templates"
+	"github.com/FearLessSaad/SNFOK/constants/agent_consts"
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/dto"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/persistance"
+	"github.com/FearLessSaad/SNFOK/db/models/k8s"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/httpclient"
+	"github.com/FearLessSaad/SNFOK/tooling/logger"
+	"github.com/gofiber/fiber"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/uptrace/bun"
+
+	cluster "github.com/FearLessSaad/SNFOK/controllers/clusters/persistance"
+)
+
+// nextPolicyVersion returns the record of the template content as the next version of the catalog policy, or nil
+// when the latest version has the same content, and sets the version of the policy to it
+func nextPolicyVersion(policy *k8s.AllPolicies, content string, actor string) (*k8s.PolicyVersions, error) {
+	latest, found, err := persistance.GetLatestPolicyVersion(policy.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	version := newPolicyVersion(*policy, latest.Version+1, content, actor)
+	if found && latest.ContentHash == version.ContentHash {
+		policy.Version = latest.Version
+		return nil, nil
+	}
+	policy.Version = version.Version
+	return &version, nil
+}
+
+// createPolicy stores a new catalog policy with its template content as version 1, in one transaction, so no
+// policy is left without a version
+func createPolicy(policy *k8s.AllPolicies, content string, actor string) error {
+	version := newPolicyVersion(*policy, 1, content, actor)
+	return persistance.CreatePolicyWithVersion(policy, &version)
+}
+
+// newPolicyVersion returns the record of a version of the policy with the template content
+func newPolicyVersion(policy k8s.AllPolicies, number int, content string, actor string) k8s.PolicyVersions {
+	sum := sha256.Sum256([]byte(content))
+	return k8s.PolicyVersions{
+		PolicyID:    policy.ID,
+		Version:     number,
+		ContentHash: hex.EncodeToString(sum[:]),
+		Content:     content,
+		Params:      policy.Params,
+		AuditFields: k8s.AuditFields{
+			CreatedBy: actor,
+			CreatedAt: time.Now(),
+		},
+	}
+}
+
+// policyTemplate returns the template content a policy is deployed with and its version: the given version, or
+// the latest one for 0. Policies without recorded versions return their stored content, which is empty for
+// catalog policies so the agent reads the template file, and version 0.
+func policyTemplate(policy k8s.AllPolicies, version int) (string, int, error) {
+	if version == 0 {
+		latest, found, err := persistance.GetLatestPolicyVersion(policy.ID)
+		if err != nil || !found {
+			return policy.Content, 0, err
+		}
+		return latest.Content, latest.Version, nil
+	}
+
+	policy_version, err := persistance.GetPolicyVersion(policy.ID, version)
+	if err != nil {
+		return "", 0, fmt.Errorf("version %d of policy %s is not recorded", version, policy.ID)
+	}
+	return policy_version.Content, policy_version.Version, nil
+}
+
+// versionParams keeps the parameter values a version declares and returns the names of the others
+func versionParams(declared []agent_dto.PolicyParam, values map[string]interface{}) (map[string]interface{}, []string) {
+	kept := make(map[string]interface{})
+	dropped := []string{}
+	for name, value := range values {
+		if slices.ContainsFunc(declared, func(param agent_dto.PolicyParam) bool { return param.Name == name }) {
+			kept[name] = value
+		} else {
+			dropped = append(dropped, name)
+		}
+	}
+	slices.Sort(dropped)
+	return kept, dropped
+}
+
+// GetPolicyVersions returns the version history of a catalog policy, newest first
+func GetPolicyVersions(policy_id string) (global_dto.Response[[]k8s.PolicyVersions], int) {
+
+	if _, err := persistance.GetPlicysById(policy_id); err != nil {
+		return global_dto.Response[[]k8s.PolicyVersions]{
+			Status:  "error",
+			Message: message.POLICY_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_NOT_FOUND,
+			},
+		}, fiber.StatusNotFound
+	}
+
+	versions, err := persistance.GetPolicyVersions(policy_id)
+	if err != nil {
+		return global_dto.Response[[]k8s.PolicyVersions]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[[]k8s.PolicyVersions]{
+		Status:  "success",
+		Message: "",
+		Data:    &versions,
+		Meta: &global_dto.Meta{
+			Code: response.POLICY_VERSIONS,
+		},
+	}, fiber.StatusOK
+}
+
+// GetPolicyUpgrades lists the implemented policies that can be upgraded to a newer version of their catalog policy
+func GetPolicyUpgrades() (global_dto.Response[[]dto.PolicyUpgrade], int) {
+
+	rows, err := persistance.GetOutdatedImplimentedPolicies()
+	if err != nil {
+		return global_dto.Response[[]dto.PolicyUpgrade]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	latest := make(map[string]int)
+	upgrades := []dto.PolicyUpgrade{}
+	for _, row := range rows {
+		if _, known := latest[row.PolicyID]; !known {
+			policy, err := persistance.GetPlicysById(row.PolicyID)
+			if err != nil {
+				continue
+			}
+			latest[row.PolicyID] = policy.Version
+		}
+		upgrades = append(upgrades, dto.PolicyUpgrade{
+			ImplementedID:  row.ID,
+			PolicyID:       row.PolicyID,
+			PolicyTitle:    row.PolicyTitle,
+			Scope:          row.Scope,
+			Namespace:      row.Namespace,
+			CurrentVersion: row.PolicyVersion,
+			LatestVersion:  latest[row.PolicyID],
+		})
+	}
+
+	return global_dto.Response[[]dto.PolicyUpgrade]{
+		Status:  "success",
+		Message: "",
+		Data:    &upgrades,
+		Meta: &global_dto.Meta{
+			Code: response.POLICY_UPGRADES,
+		},
+	}, fiber.StatusOK
+}
+
+// PreviewPolicyUpgrade renders the current and the target version of an implemented policy with its parameter
+// values and returns the difference. The scope and selector are set by the agent and left out of both.
+func PreviewPolicyUpgrade(id string, version int) (global_dto.Response[dto.PolicyUpgradePreview], int) {
+
+	row, policy, status := upgradeTarget(id)
+	if status != fiber.StatusOK {
+		return global_dto.Response[dto.PolicyUpgradePreview]{
+			Status:  "error",
+			Message: message.POLICY_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_NOT_FOUND,
+			},
+		}, status
+	}
+	if version == 0 {
+		version = policy.Version
+	}
+
+	target, err := persistance.GetPolicyVersion(policy.ID, version)
+	if err != nil {
+		return global_dto.Response[dto.PolicyUpgradePreview]{
+			Status:  "error",
+			Message: message.POLICY_VERSION_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_VERSION_NOT_FOUND,
+			},
+		}, fiber.StatusNotFound
+	}
+
+	preview := dto.PolicyUpgradePreview{
+		ImplementedID: row.ID,
+		FromVersion:   row.PolicyVersion,
+		ToVersion:     target.Version,
+	}
+
+	// The content of policies deployed before versions were recorded is unknown, the diff shows all of the new one
+	current := ""
+	if row.PolicyVersion != 0 {
+		if from, err := persistance.GetPolicyVersion(policy.ID, row.PolicyVersion); err == nil {
+			params, _ := versionParams(from.Params, row.Params)
+			current, err = renderPolicyVersion(from, row, params)
+			if err != nil {
+				preview.Errors = append(preview.Errors, fmt.Sprintf("version %d: %v", from.Version, err))
+			}
+		}
+	}
+
+	params, dropped := versionParams(target.Params, row.Params)
+	preview.DroppedParams = dropped
+	upgraded, err := renderPolicyVersion(target, row, params)
+	if err != nil {
+		preview.Errors = append(preview.Errors, fmt.Sprintf("version %d: %v", target.Version, err))
+	}
+
+	preview.Diff, _ = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(current),
+		B:        difflib.SplitLines(upgraded),
+		FromFile: fmt.Sprintf("version %d", row.PolicyVersion),
+		ToFile:   fmt.Sprintf("version %d", target.Version),
+		Context:  3,
+	})
+
+	return global_dto.Response[dto.PolicyUpgradePreview]{
+		Status:  "success",
+		Message: "",
+		Data:    &preview,
+		Meta: &global_dto.Meta{
+			Code: response.POLICY_UPGRADE_PREVIEW,
+		},
+	}, fiber.StatusOK
+}
+
+// renderPolicyVersion renders a version of a template with the parameter values of an implemented policy. The policy
+// id is fixed so both sides of a diff name their objects the same.
+func renderPolicyVersion(version k8s.PolicyVersions, row k8s.ImplimentedPolicies, params map[string]interface{}) (string, error) {
+	rendered, err := templates.RenderTemplate(fmt.Sprintf("version-%d", version.Version), version.Content, catalogPolicyID, row.Namespace, row.AppLabel, params)
+	if err != nil {
+		return "", fmt.Errorf("%s %v", rendered, err)
+	}
+	return rendered, nil
+}
+
+// UpgradeImplementedPolicy moves an implemented policy to a version of its catalog policy, the latest one when
+// version is 0. The template is rendered again with the original parameter values.
+func UpgradeImplementedPolicy(id string, version int, uid string) (global_dto.Response[dto.PolicyUpgradeResult], int) {
+	driftLock.Lock()
+	defer driftLock.Unlock()
+
+	row, policy, status := upgradeTarget(id)
+	if status != fiber.StatusOK {
+		return global_dto.Response[dto.PolicyUpgradeResult]{
+			Status:  "error",
+			Message: message.POLICY_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_NOT_FOUND,
+			},
+		}, status
+	}
+	if version == 0 {
+		version = policy.Version
+	}
+
+	result, status := changePolicyVersion(row, policy, version, uid)
+	if status == fiber.StatusOK {
+		result.Message = message.POLICY_UPGRADED
+		result.Meta.Code = response.POLICY_UPGRADED
+	}
+	return result, status
+}
+
+// RollbackImplementedPolicy moves an implemented policy back to the version it had before its last upgrade
+func RollbackImplementedPolicy(id string, uid string) (global_dto.Response[dto.PolicyUpgradeResult], int) {
+	driftLock.Lock()
+	defer driftLock.Unlock()
+
+	row, policy, status := upgradeTarget(id)
+	if status != fiber.StatusOK {
+		return global_dto.Response[dto.PolicyUpgradeResult]{
+			Status:  "error",
+			Message: message.POLICY_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_NOT_FOUND,
+			},
+		}, status
+	}
+	if row.PreviousVersion == 0 {
+		return global_dto.Response[dto.PolicyUpgradeResult]{
+			Status:  "error",
+			Message: message.NO_PREVIOUS_VERSION,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.NO_PREVIOUS_VERSION,
+			},
+		}, fiber.StatusConflict
+	}
+
+	result, status := changePolicyVersion(row, policy, row.PreviousVersion, uid)
+	if status == fiber.StatusOK {
+		result.Message = message.POLICY_ROLLED_BACK
+		result.Meta.Code = response.POLICY_ROLLED_BACK
+	}
+	return result, status
+}
+
+// upgradeTarget loads an implemented policy and the catalog policy it was deployed from
+func upgradeTarget(id string) (k8s.ImplimentedPolicies, k8s.AllPolicies, int) {
+	row, err := persistance.GetImplimentedPolicyById(id)
+	if err != nil || row.PolicyID == "" {
+		return k8s.ImplimentedPolicies{}, k8s.AllPolicies{}, fiber.StatusNotFound
+	}
+	policy, err := persistance.GetPlicysById(row.PolicyID)
+	if err != nil {
+		return k8s.ImplimentedPolicies{}, k8s.AllPolicies{}, fiber.StatusNotFound
+	}
+	return row, policy, fiber.StatusOK
+}
+
+// implementedCluster returns the cluster an implemented policy is deployed to
+func implementedCluster(row k8s.ImplimentedPolicies) (k8s.Clusters, error) {
+	if row.ClusterID != "" {
+		return cluster.GetClusterById(row.ClusterID)
+	}
+	// Policies deployed before clusters were recorded on the row all went to the first cluster
+	clusters, err := cluster.GetAllClusters()
+	if err != nil {
+		return k8s.Clusters{}, err
+	}
+	if len(clusters) == 0 {
+		return k8s.Clusters{}, fmt.Errorf("no cluster registered")
+	}
+	return clusters[0], nil
+}
+
+// changePolicyVersion has the agent replace the objects of an implemented policy with the objects of another
+// version. When that fails the agent restores the previous version and the record is left unchanged.
+func changePolicyVersion(row k8s.ImplimentedPolicies, policy k8s.AllPolicies, version int, uid string) (global_dto.Response[dto.PolicyUpgradeResult], int) {
+
+	if version == row.PolicyVersion {
+		return global_dto.Response[dto.PolicyUpgradeResult]{
+			Status:  "error",
+			Message: message.POLICY_UP_TO_DATE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_UP_TO_DATE,
+			},
+		}, fiber.StatusConflict
+	}
+
+	target, err := persistance.GetPolicyVersion(policy.ID, version)
+	if err != nil {
+		return global_dto.Response[dto.PolicyUpgradeResult]{
+			Status:  "error",
+			Message: message.POLICY_VERSION_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_VERSION_NOT_FOUND,
+			},
+		}, fiber.StatusNotFound
+	}
+
+	c, err := implementedCluster(row)
+	if err != nil {
+		return global_dto.Response[dto.PolicyUpgradeResult]{
+			Status:  "error",
+			Message: message.CLUSTER_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.CLUSTER_NOT_FOUND,
+			},
+		}, fiber.StatusNotFound
+	}
+
+	params, _ := versionParams(target.Params, row.Params)
+
+	client := httpclient.NewClient(0)
+
+	res, err := client.Post("http://"+c.MasterIP+":"+fmt.Sprintf("%d", c.AgentPort)+agent_consts.POLICIES_UPGRADE, agent_dto.UpgradePolicy{
+		DeployPolicy: agent_dto.DeployPolicy{
+			Namespace:     row.Namespace,
+			AppLabel:      row.AppLabel,
+			FilePath:      policy.PolicyFilePath,
+			Template:      target.Content,
+			PolicyID:      policy.ID,
+			ImplementedID: row.ID,
+			Params:        params,
+			Selector:      row.Selector,
+			Mode:          agentMode(policy, row.Mode),
+
+			Scope:              row.Scope,
+			Namespaces:         row.Namespaces,
+			ExcludedNamespaces: row.ExcludedNamespaces,
+			IncludeHost:        row.IncludeHost,
+		},
+		PreviousPath: row.PolicyFilePath,
+	}, map[string]string{
+		"Content-Type": "application/json",
+	})
+	if err != nil {
+		logger.Log(logger.DEBUG, "HTTP Request Error", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[dto.PolicyUpgradeResult]{
+			Status:  "error",
+			Message: message.POLICY_UPGRADE_FAILED,
+			Errors:  []any{err.Error()},
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_UPGRADE_FAILED,
+			},
+		}, fiber.StatusBadGateway
+	}
+
+	var deployed agent_dto.DeployPolicyResponse
+	if err := json.Unmarshal(res.Body, &deployed); err != nil {
+		logger.Log(logger.DEBUG, "Unmarshal Response", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[dto.PolicyUpgradeResult]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	result := dto.PolicyUpgradeResult{
+		FromVersion: row.PolicyVersion,
+		ToVersion:   target.Version,
+		Objects:     deployed.Objects,
+	}
+
+	row.PreviousVersion = row.PolicyVersion
+	row.PolicyVersion = target.Version
+	row.PolicyFilePath = deployed.PolicyPath
+	row.Params = params
+	row.ClusterID = c.ID
+	row.UpdatedBy = uid
+	row.UpdatedAt = bun.NullTime{Time: time.Now()}
+	if err := persistance.UpdateImplimentedPolicy(row); err != nil {
+		// The cluster runs the new version already. Its objects carry the implemented id, so the policy can
+		// still be deleted by id although the record points at the removed file of the previous version.
+		logger.Log(logger.ERROR, "Failed to record the upgraded policy.", logger.Field{Key: "policy", Value: row.ID}, logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[dto.PolicyUpgradeResult]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+	result.Policy = row
+
+	return global_dto.Response[dto.PolicyUpgradeResult]{
+		Status:  "success",
+		Message: "",
+		Data:    &result,
+		Meta: &global_dto.Meta{
+			Code: response.POLICY_UPGRADED,
+		},
+	}, fiber.StatusOK
+}
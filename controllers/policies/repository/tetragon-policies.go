@@ -9,6 +9,7 @@ import (
 	"github.com/FearLessSaad/SNFOK/constants/agent_consts"
 	"github.com/FearLessSaad/SNFOK/constants/message"
 	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/dto"
 	"github.com/FearLessSaad/SNFOK/controllers/policies/persistance"
 	"github.com/FearLessSaad/SNFOK/db/models/k8s"
 	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
@@ -16,27 +17,131 @@ import (
 	"github.com/FearLessSaad/SNFOK/tooling/httpclient"
 	"github.com/FearLessSaad/SNFOK/tooling/logger"
 	"github.com/gofiber/fiber"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
 
 	cluster "github.com/FearLessSaad/SNFOK/controllers/clusters/persistance"
 )
 
 type DeployedPolicyResponse struct {
-	PolicyPath string `json:"policy_path"`
+	PolicyPath string                    `json:"policy_path"`
+	Objects    []agent_dto.AppliedObject `json:"objects,omitempty"`
 }
 
-func DeployPolicy(namespace string, app_label string, policy string) (global_dto.Response[DeployedPolicyResponse], int) {
+func DeployPolicy(data dto.DeployPolicyRequest) (global_dto.Response[DeployedPolicyResponse], int) {
+	driftLock.Lock()
+	defer driftLock.Unlock()
+
+	get_policy, err := persistance.GetPlicysById(data.PolicyID)
+	if err != nil {
+		return global_dto.Response[DeployedPolicyResponse]{
+			Status:  "error",
+			Message: message.POLICY_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_NOT_FOUND,
+			},
+		}, fiber.StatusNotFound
+	}
+
+	// A mode the template is not written in is deployed from its variant, or by switching its actions
+	get_policy, agent_mode, err := modePolicy(get_policy, data.Mode)
+	if err != nil {
+		return global_dto.Response[DeployedPolicyResponse]{
+			Status:  "error",
+			Message: message.POLICY_MODE_UNSUPPORTED,
+			Errors:  []any{err.Error()},
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_MODE_UNSUPPORTED,
+			},
+		}, fiber.StatusConflict
+	}
 
-	get_policy, _ := persistance.GetPlicysById(policy)
 	get_Master, _ := cluster.GetAllClusters()
+	if len(get_Master) == 0 {
+		return global_dto.Response[DeployedPolicyResponse]{
+			Status:  "error",
+			Message: message.NO_REGISTERED_CLUSTER_AVAILABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.NO_CLUSTER_AVAILABLE,
+			},
+		}, fiber.StatusNotFound
+	}
 	ip := get_Master[0].MasterIP
 	port := get_Master[0].AgentPort
 
 	client := httpclient.NewClient(0)
 
+	health, err := client.Get("http://"+ip+":"+fmt.Sprintf("%d", port)+agent_consts.HEALTH_GET_INTO_PATH, map[string]string{})
+	if err != nil {
+		logger.Log(logger.DEBUG, "HTTP Request Error", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[DeployedPolicyResponse]{
+			Status:  "error",
+			Message: message.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			},
+		}, fiber.StatusBadGateway
+	}
+
+	var health_data agent_dto.HealthResponse
+	if err := json.Unmarshal(health.Body, &health_data); err != nil {
+		logger.Log(logger.DEBUG, "Unmarshal Response", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[DeployedPolicyResponse]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	if err := policyTypeSupported(health_data, get_policy.PolicyType, data.Scope); err != nil {
+		return global_dto.Response[DeployedPolicyResponse]{
+			Status:  "error",
+			Message: message.POLICY_TYPE_UNSUPPORTED,
+			Errors:  []any{err.Error()},
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POLICY_TYPE_UNSUPPORTED,
+			},
+		}, fiber.StatusUnprocessableEntity
+	}
+
+	template, version, err := policyTemplate(get_policy, 0)
+	if err != nil {
+		return global_dto.Response[DeployedPolicyResponse]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	// The id is generated up front so the agent can label the applied objects with it
+	implemented_id := uuid.NewString()
+
 	res, err := client.Post("http://"+ip+":"+fmt.Sprintf("%d", port)+agent_consts.POLICIES_DEPLOY_POLICY, agent_dto.DeployPolicy{
-		Namespace: namespace,
-		AppLabel:  app_label,
-		FilePath:  get_policy.PolicyFilePath,
+		Namespace:     data.Namespace,
+		AppLabel:      data.AppLabel,
+		FilePath:      get_policy.PolicyFilePath,
+		Template:      template,
+		PolicyID:      get_policy.ID,
+		ImplementedID: implemented_id,
+		Params:        data.Params,
+		Selector:      data.Selector,
+		Mode:          agent_mode,
+
+		Scope:              data.Scope,
+		Namespaces:         data.Namespaces,
+		ExcludedNamespaces: data.ExcludedNamespaces,
+		IncludeHost:        data.IncludeHost,
 	}, map[string]string{
 		"Content-Type": "application/json",
 	})
@@ -45,6 +150,7 @@ func DeployPolicy(namespace string, app_label string, policy string) (global_dto
 		return global_dto.Response[DeployedPolicyResponse]{
 			Status:  "error",
 			Message: message.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			Errors:  []any{err.Error()},
 			Data:    nil,
 			Meta: &global_dto.Meta{
 				Code: response.SNFOK_AGENT_IS_NOT_ACCESSABLE,
@@ -65,12 +171,30 @@ func DeployPolicy(namespace string, app_label string, policy string) (global_dto
 		}, fiber.StatusInternalServerError
 	}
 
+	mode := data.Mode
+	if mode == "" {
+		mode = get_policy.Mode
+	}
+
 	i_policy := k8s.ImplimentedPolicies{
-		PolicyTitle:    get_policy.PolicyTitle,
-		Description:    get_policy.Description,
-		AppLabel:       app_label,
-		Namespace:      namespace,
-		PolicyFilePath: res_data.PolicyPath,
+		ID:                 implemented_id,
+		ClusterID:          get_Master[0].ID,
+		PolicyID:           get_policy.ID,
+		PolicyTitle:        get_policy.PolicyTitle,
+		Description:        get_policy.Description,
+		AppLabel:           data.AppLabel,
+		Selector:           effectiveSelector(data),
+		Scope:              data.Scope,
+		Namespace:          data.Namespace,
+		Namespaces:         data.Namespaces,
+		ExcludedNamespaces: data.ExcludedNamespaces,
+		IncludeHost:        data.IncludeHost,
+		PolicyFilePath:     res_data.PolicyPath,
+		Params:             data.Params,
+		PolicyVersion:      version,
+		Mode:               mode,
+		DriftStatus:        k8s.DriftStatusInSync,
+		DriftCheckedAt:     bun.NullTime{Time: time.Now()},
 		AuditFields: k8s.AuditFields{
 			CreatedBy: "SNFOK:USER",
 			CreatedAt: time.Now(),
@@ -90,7 +214,70 @@ func DeployPolicy(namespace string, app_label string, policy string) (global_dto
 
 }
 
+// effectiveSelector returns the selector a workload policy is deployed with: the requested one, or the app label
+// selector of the templates. Namespace and cluster policies select every pod in their scope and have none.
+func effectiveSelector(data dto.DeployPolicyRequest) *agent_dto.LabelSelector {
+	if data.Scope != "" && data.Scope != agent_dto.POLICY_SCOPE_WORKLOAD {
+		return nil
+	}
+	if data.Selector != nil {
+		return data.Selector
+	}
+	return &agent_dto.LabelSelector{MatchLabels: map[string]string{"app": data.AppLabel}}
+}
+
+// policyTypeKinds lists the kinds a policy of each type can be written in
+var policyTypeKinds = map[string][]string{
+	k8s.PolicyTypeTetragon:  {"TracingPolicy", "TracingPolicyNamespaced"},
+	k8s.PolicyTypeKubeArmor: {"KubeArmorPolicy"},
+	k8s.PolicyTypeNetwork:   {}, // Built into Kubernetes, there is no CRD to check
+}
+
+// unsupportedPolicyTypes lists the policy types SNFOK knows but the agent cannot apply, with the reason
+var unsupportedPolicyTypes = map[string]string{
+	k8s.PolicyTypeCilium: "the SNFOK agent cannot apply CiliumNetworkPolicy objects",
+}
+
+// clusterScopeKinds lists the cluster-scoped kinds a policy of each type is converted to for cluster scope
+var clusterScopeKinds = map[string][]string{
+	k8s.PolicyTypeTetragon:  {"TracingPolicy"},
+	k8s.PolicyTypeKubeArmor: {"KubeArmorClusterPolicy"},
+}
+
+// policyTypeSupported checks the CRDs reported by the agent health check against the kinds of a policy type
+// and, for cluster scope, the kinds it is converted to
+func policyTypeSupported(health agent_dto.HealthResponse, policy_type string, scope string) error {
+	if reason, unsupported := unsupportedPolicyTypes[policy_type]; unsupported {
+		return fmt.Errorf("%s policies cannot be deployed: %s", policy_type, reason)
+	}
+	kinds, exists := policyTypeKinds[policy_type]
+	if !exists {
+		return fmt.Errorf("policy type %q is not known to SNFOK", policy_type)
+	}
+	if scope == agent_dto.POLICY_SCOPE_CLUSTER {
+		cluster_kinds, exists := clusterScopeKinds[policy_type]
+		if !exists {
+			return fmt.Errorf("%s policies cannot be deployed with %s scope", policy_type, scope)
+		}
+		kinds = append(append([]string{}, kinds...), cluster_kinds...)
+	}
+
+	installed := make(map[string]bool)
+	for _, crd := range health.CRDs {
+		installed[crd.Kind] = crd.Installed
+	}
+	for _, kind := range kinds {
+		if !installed[kind] {
+			return fmt.Errorf("cluster %s does not support %s policies: CRD %s is not installed", health.K8sInfo.ClusterName, policy_type, kind)
+		}
+	}
+	return nil
+}
+
 func DeletePolicy(id string) (global_dto.Response[[]string], int) {
+	driftLock.Lock()
+	defer driftLock.Unlock()
+
 	policy, _ := persistance.GetImplimentedPolicyById(id)
 	cluster, _ := cluster.GetAllClusters()
 
@@ -98,7 +285,7 @@ func DeletePolicy(id string) (global_dto.Response[[]string], int) {
 	port := cluster[0].AgentPort
 	client := httpclient.NewClient(0)
 
-	_, err := client.Post("http://"+ip+":"+fmt.Sprintf("%d", port)+agent_consts.DELETE_TETRAGON_POLICY, routes.PolicyPathRequest{Path: policy.PolicyFilePath}, map[string]string{})
+	_, err := client.Post("http://"+ip+":"+fmt.Sprintf("%d", port)+agent_consts.DELETE_TETRAGON_POLICY, routes.PolicyPathRequest{Path: policy.PolicyFilePath, ImplementedID: policy.ID}, map[string]string{})
 	if err != nil {
 		logger.Log(logger.DEBUG, "HTTP Request Error", logger.Field{Key: "error", Value: err.Error()})
 		return global_dto.Response[[]string]{
@@ -113,6 +300,14 @@ func DeletePolicy(id string) (global_dto.Response[[]string], int) {
 
 	err = persistance.DeleteImplimentedPolicyById(policy.ID)
 
+	// A member of a bundle can be removed on its own, the bundle record keeps track of it
+	if err == nil && policy.BundleID != "" {
+		if bundle, err := persistance.GetImplementedBundleById(policy.BundleID); err == nil {
+			markBundleMember(&bundle, policy.ID)
+			persistance.UpdateImplementedBundle(bundle)
+		}
+	}
+
 	if err != nil {
 		return global_dto.Response[[]string]{
 			Status:  "error",
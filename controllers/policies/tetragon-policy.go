@@ -3,7 +3,13 @@ package policies
 import (
 	"fmt"
 
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/policies/dto"
 	"github.com/FearLessSaad/SNFOK/controllers/policies/repository"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/security/validation"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -15,19 +21,117 @@ func DeployTetragonPolicy(router fiber.Router) {
 		if id == "" || namespace == "" || label == "" {
 			return c.Status(fiber.StatusBadRequest).JSON("")
 		}
-		res, status := repository.DeployPolicy(namespace, label, id)
+		res, status := repository.DeployPolicy(dto.DeployPolicyRequest{PolicyID: id, DeployTarget: dto.DeployTarget{Scope: agent_dto.POLICY_SCOPE_WORKLOAD, Namespace: namespace, AppLabel: label}})
 		fmt.Println(res)
 		return c.Status(status).JSON(res)
 	})
 
+	// Dry-run of a deployment: rendered YAML, API server errors and the running pods it would select
+	router.Get("/preview/:id/:namespace/:label/", func(c *fiber.Ctx) error {
+		id := c.AllParams()["id"]
+		namespace := c.AllParams()["namespace"]
+		label := c.AllParams()["label"]
+		if id == "" || namespace == "" || label == "" {
+			return c.Status(fiber.StatusBadRequest).JSON("")
+		}
+		res, status := repository.PreviewPolicy(dto.DeployPolicyRequest{PolicyID: id, DeployTarget: dto.DeployTarget{Scope: agent_dto.POLICY_SCOPE_WORKLOAD, Namespace: namespace, AppLabel: label}})
+		return c.Status(status).JSON(res)
+	})
+
+	// Deploys a policy with the values of its template parameters, for a workload, a namespace or the whole cluster
+	router.Post("/deploy", func(c *fiber.Ctx) error {
+		details, err := parseDeployPolicyRequest(c)
+		if details == nil {
+			return err
+		}
+		res, status := repository.DeployPolicy(*details)
+		return c.Status(status).JSON(res)
+	})
+
+	router.Post("/preview", func(c *fiber.Ctx) error {
+		details, err := parseDeployPolicyRequest(c)
+		if details == nil {
+			return err
+		}
+		res, status := repository.PreviewPolicy(*details)
+		return c.Status(status).JSON(res)
+	})
+
+	// Parameters declared by the template of a catalog policy, with their types and defaults
+	router.Get("/params/:id", func(c *fiber.Ctx) error {
+		res, status := repository.GetPolicyParams(c.Params("id"))
+		return c.Status(status).JSON(res)
+	})
+
 	router.Get("/get/all", func(c *fiber.Ctx) error {
 		namespaces, response := repository.GetAllImplimentedPolicies()
 		return c.Status(response).JSON(namespaces)
 	})
 
+	// Lists the SNFOK-managed objects that are actually present in the cluster
+	router.Get("/managed", func(c *fiber.Ctx) error {
+		managed, status := repository.GetManagedPolicies(c.Query("namespace"))
+		return c.Status(status).JSON(managed)
+	})
+
+	// Last drift report of every cluster, including objects no implemented policy accounts for
+	router.Get("/drift", func(c *fiber.Ctx) error {
+		reports, status := repository.GetDriftReports()
+		return c.Status(status).JSON(reports)
+	})
+
+	router.Post("/drift/reconcile", func(c *fiber.Ctx) error {
+		reports, status := repository.ReconcileDriftNow()
+		return c.Status(status).JSON(reports)
+	})
+
 	router.Get("/delete/:id", func(c *fiber.Ctx) error {
 		namespaces, response := repository.DeletePolicy(c.AllParams()["id"])
 		return c.Status(response).JSON(namespaces)
 	})
 
 }
+
+// parseDeployPolicyRequest reads and validates a deploy request. When it returns nil the error response is already sent.
+func parseDeployPolicyRequest(c *fiber.Ctx) (*dto.DeployPolicyRequest, error) {
+	details := new(dto.DeployPolicyRequest)
+	if err := c.BodyParser(details); err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(global_dto.Response[string]{
+			Status:  "error",
+			Message: message.INVALID_REQUEST_PAYLOAD,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.INVALID_REQUEST_PAYLOAD,
+			},
+		})
+	}
+	if details.Scope == "" {
+		details.Scope = agent_dto.POLICY_SCOPE_WORKLOAD
+	}
+	errs := append(validation.ValidateStruct(details), validateDeployTarget(&details.DeployTarget)...)
+	if len(errs) > 0 {
+		return nil, validationFailed(c, errs)
+	}
+	return details, nil
+}
+
+// validateDeployTarget checks the fields that depend on the scope of a deploy request
+func validateDeployTarget(details *dto.DeployTarget) []validation.ValidationError {
+	var errs []validation.ValidationError
+	if details.Scope == agent_dto.POLICY_SCOPE_WORKLOAD && details.AppLabel == "" && details.Selector == nil {
+		errs = append(errs, validation.ValidationError{Field: "app_label", Error: "app_label or selector is required for WORKLOAD scope"})
+	}
+	if details.Scope != agent_dto.POLICY_SCOPE_WORKLOAD && details.Selector != nil {
+		errs = append(errs, validation.ValidationError{Field: "selector", Error: "selector is only allowed for WORKLOAD scope"})
+	}
+	if details.Scope != agent_dto.POLICY_SCOPE_CLUSTER && (len(details.Namespaces) != 0 || len(details.ExcludedNamespaces) != 0) {
+		errs = append(errs, validation.ValidationError{Field: "namespaces", Error: "namespaces and excluded_namespaces are only allowed for CLUSTER scope"})
+	}
+	if details.Scope != agent_dto.POLICY_SCOPE_CLUSTER && details.IncludeHost {
+		errs = append(errs, validation.ValidationError{Field: "include_host", Error: "include_host is only allowed for CLUSTER scope"})
+	}
+	if details.Scope == agent_dto.POLICY_SCOPE_CLUSTER {
+		details.Namespace = ""
+	}
+	return errs
+}
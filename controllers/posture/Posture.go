@@ -0,0 +1,10 @@
+package posture
+
+import "github.com/gofiber/fiber/v2"
+
+func PostureController(router fiber.Router) {
+	WorkloadScan(router)
+	RBACAnalysis(router)
+	NetworkCoverage(router)
+	ImageInventory(router)
+}
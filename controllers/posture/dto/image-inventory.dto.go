@@ -0,0 +1,11 @@
+package dto
+
+// ImageInventoryQuery filters the image inventory. Image matches any part of the image reference,
+// e.g. a repository name during CVE response.
+type ImageInventoryQuery struct {
+	Image      string `query:"image"`
+	Registry   string `query:"registry"`
+	Namespace  string `query:"namespace"`
+	Mutable    bool   `query:"mutable"`    // Only images with a mutable tag
+	Disallowed bool   `query:"disallowed"` // Only images from registries outside the allowlist
+}
@@ -0,0 +1,25 @@
+package dto
+
+// NamespaceCoverage is the share of a namespace's pods whose ingress and egress are isolated by NetworkPolicies.
+// Host network pods are not counted, NetworkPolicies do not apply to them.
+type NamespaceCoverage struct {
+	Namespace          string  `json:"namespace"`
+	Pods               int     `json:"pods"`
+	IngressCovered     int     `json:"ingress_covered"`
+	EgressCovered      int     `json:"egress_covered"`
+	IngressCoverage    float64 `json:"ingress_coverage"` // Percentage, 0-100
+	EgressCoverage     float64 `json:"egress_coverage"`  // Percentage, 0-100
+	DefaultDenyIngress bool    `json:"default_deny_ingress"`
+	DefaultDenyEgress  bool    `json:"default_deny_egress"`
+	Policies           int     `json:"policies"`
+}
+
+// UncoveredWorkload is a workload with at least one pod whose ingress or egress is not isolated
+type UncoveredWorkload struct {
+	Namespace       string   `json:"namespace"`
+	Kind            string   `json:"kind"`
+	Name            string   `json:"name"`
+	Pods            []string `json:"pods"`
+	IngressIsolated bool     `json:"ingress_isolated"`
+	EgressIsolated  bool     `json:"egress_isolated"`
+}
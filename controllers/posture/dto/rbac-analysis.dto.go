@@ -0,0 +1,20 @@
+package dto
+
+import "github.com/FearLessSaad/SNFOK/shared/agent_dto"
+
+// WhoCanQuery asks which subjects may perform a verb on a resource
+type WhoCanQuery struct {
+	Verb      string `query:"verb" validate:"required"`
+	Resource  string `query:"resource" validate:"required"`
+	Group     string `query:"group"`
+	Namespace string `query:"namespace"`
+}
+
+// OverPrivilegedPod is a running pod whose ServiceAccount has risky RBAC grants
+type OverPrivilegedPod struct {
+	Namespace      string               `json:"namespace"`
+	Pod            string               `json:"pod"`
+	ServiceAccount string               `json:"service_account"`
+	Severity       string               `json:"severity"`
+	Risks          []agent_dto.RBACRisk `json:"risks"`
+}
@@ -0,0 +1,16 @@
+package dto
+
+import "github.com/FearLessSaad/SNFOK/db/models/k8s"
+
+// PostureFindingsQuery filters the findings of a scan. The latest scan is used when ScanID is empty.
+type PostureFindingsQuery struct {
+	ScanID    string `query:"scan_id" validate:"omitempty,uuid"`
+	Namespace string `query:"namespace"`
+	Severity  string `query:"severity" validate:"omitempty,oneof=CRITICAL HIGH MEDIUM LOW"`
+}
+
+// PostureFindingsResponse holds a scan and its (filtered) findings
+type PostureFindingsResponse struct {
+	Scan     k8s.PostureScans      `json:"scan"`
+	Findings []k8s.PostureFindings `json:"findings"`
+}
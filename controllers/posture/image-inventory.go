@@ -0,0 +1,30 @@
+package posture
+
+import (
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/posture/dto"
+	"github.com/FearLessSaad/SNFOK/controllers/posture/repository"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/gofiber/fiber/v2"
+)
+
+func ImageInventory(router fiber.Router) {
+
+	router.Get("/images", func(c *fiber.Ctx) error {
+		query := new(dto.ImageInventoryQuery)
+		if err := c.QueryParser(query); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(global_dto.Response[string]{
+				Status:  "error",
+				Message: message.INVALID_REQUEST_PAYLOAD,
+				Data:    nil,
+				Meta: &global_dto.Meta{
+					Code: response.INVALID_REQUEST_PAYLOAD,
+				},
+			})
+		}
+
+		response, status := repository.GetImageInventory(*query)
+		return c.Status(status).JSON(response)
+	})
+}
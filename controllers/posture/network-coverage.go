@@ -0,0 +1,19 @@
+package posture
+
+import (
+	"github.com/FearLessSaad/SNFOK/controllers/posture/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+func NetworkCoverage(router fiber.Router) {
+
+	router.Get("/network/coverage", func(c *fiber.Ctx) error {
+		response, status := repository.GetNetworkCoverage()
+		return c.Status(status).JSON(response)
+	})
+
+	router.Get("/network/uncovered", func(c *fiber.Ctx) error {
+		response, status := repository.GetUncoveredWorkloads(c.Query("namespace"))
+		return c.Status(status).JSON(response)
+	})
+}
@@ -0,0 +1,109 @@
+package persistance
+
+import (
+	"context"
+
+	"github.com/FearLessSaad/SNFOK/db"
+	"github.com/FearLessSaad/SNFOK/db/models/k8s"
+	"github.com/FearLessSaad/SNFOK/tooling/logger"
+	"github.com/uptrace/bun"
+)
+
+func GetAllPostureScans(cluster_id string) ([]k8s.PostureScans, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	scans := new([]k8s.PostureScans)
+	err := conn.NewSelect().Model(scans).Where("cluster_id = ?", cluster_id).Order("scanned_at DESC").Scan(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.posture_scans'.", logger.Field{Key: "error", Value: err.Error()})
+		return []k8s.PostureScans{}, err
+	}
+
+	return *scans, nil
+}
+
+func GetPostureScanById(id string) (k8s.PostureScans, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	scan := new(k8s.PostureScans)
+	err := conn.NewSelect().Model(scan).Where("id = ?", id).Limit(1).Scan(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.posture_scans'.", logger.Field{Key: "error", Value: err.Error()})
+		return k8s.PostureScans{}, err
+	}
+
+	return *scan, nil
+}
+
+func GetLatestPostureScan(cluster_id string) (k8s.PostureScans, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	scan := new(k8s.PostureScans)
+	err := conn.NewSelect().Model(scan).Where("cluster_id = ?", cluster_id).Order("scanned_at DESC").Limit(1).Scan(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.posture_scans'.", logger.Field{Key: "error", Value: err.Error()})
+		return k8s.PostureScans{}, err
+	}
+
+	return *scan, nil
+}
+
+// GetPostureFindings returns the findings of a scan, optionally filtered by namespace and severity
+func GetPostureFindings(scan_id string, namespace string, severity string) ([]k8s.PostureFindings, error) {
+
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	findings := new([]k8s.PostureFindings)
+	query := conn.NewSelect().Model(findings).Where("scan_id = ?", scan_id)
+	if namespace != "" {
+		query = query.Where("namespace = ?", namespace)
+	}
+	if severity != "" {
+		query = query.Where("severity = ?", severity)
+	}
+	err := query.Order("namespace ASC", "kind ASC", "name ASC").Scan(ctx)
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute select query on 'k8s.posture_findings'.", logger.Field{Key: "error", Value: err.Error()})
+		return []k8s.PostureFindings{}, err
+	}
+
+	return *findings, nil
+}
+
+// CreatePostureScan stores a scan together with its findings in a single transaction
+func CreatePostureScan(scan *k8s.PostureScans, findings []k8s.PostureFindings) error {
+	conn := db.GetDB()
+	ctx := context.Background()
+
+	err := conn.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewInsert().Model(scan).Returning("id").Exec(ctx); err != nil {
+			return err
+		}
+		if len(findings) == 0 {
+			return nil
+		}
+		for i := range findings {
+			findings[i].ScanID = scan.ID
+		}
+		_, err := tx.NewInsert().Model(&findings).Exec(ctx)
+		return err
+	})
+
+	if err != nil {
+		logger.Log(logger.ERROR, "Failed to execute insert query on 'k8s.posture_scans'.", logger.Field{Key: "error", Value: err.Error()})
+		return err
+	}
+
+	return nil
+}
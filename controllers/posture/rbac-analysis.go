@@ -0,0 +1,56 @@
+package posture
+
+import (
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/posture/dto"
+	"github.com/FearLessSaad/SNFOK/controllers/posture/repository"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/security/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+func RBACAnalysis(router fiber.Router) {
+
+	router.Get("/rbac/analysis", func(c *fiber.Ctx) error {
+		response, status := repository.GetRBACAnalysis()
+		return c.Status(status).JSON(response)
+	})
+
+	router.Get("/rbac/who-can", func(c *fiber.Ctx) error {
+		query := new(dto.WhoCanQuery)
+		if err := c.QueryParser(query); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(global_dto.Response[string]{
+				Status:  "error",
+				Message: message.INVALID_REQUEST_PAYLOAD,
+				Data:    nil,
+				Meta: &global_dto.Meta{
+					Code: response.INVALID_REQUEST_PAYLOAD,
+				},
+			})
+		}
+		if errs := validation.ValidateStruct(query); len(errs) > 0 {
+			errors := make([]any, len(errs))
+			for i, err := range errs {
+				errors[i] = err
+			}
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(global_dto.Response[string]{
+				Status:  "error",
+				Message: message.FAILED_DATA_VALIDATION,
+				Errors:  errors,
+				Data:    nil,
+				Meta: &global_dto.Meta{
+					Code: response.FAILED_DATA_VALIDATION,
+				},
+			})
+		}
+
+		response, status := repository.WhoCan(*query)
+		return c.Status(status).JSON(response)
+	})
+
+	router.Get("/rbac/overprivileged-pods", func(c *fiber.Ctx) error {
+		response, status := repository.GetOverPrivilegedPods()
+		return c.Status(status).JSON(response)
+	})
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/FearLessSaad/SNFOK/constants/agent_consts"
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/posture/dto"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/httpclient"
+	"github.com/FearLessSaad/SNFOK/tooling/logger"
+	"github.com/gofiber/fiber"
+
+	cluster "github.com/FearLessSaad/SNFOK/controllers/clusters/persistance"
+)
+
+// GetImageInventory returns the images running in the cluster, flagged against the registry
+// allowlist in $ALLOWED_IMAGE_REGISTRIES, and filtered by the query
+func GetImageInventory(query dto.ImageInventoryQuery) (global_dto.Response[agent_dto.ImageInventory], int) {
+
+	clusters, _ := cluster.GetAllClusters()
+	if len(clusters) == 0 {
+		return global_dto.Response[agent_dto.ImageInventory]{
+			Status:  "error",
+			Message: message.NO_REGISTERED_CLUSTER_AVAILABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.NO_CLUSTER_AVAILABLE,
+			},
+		}, fiber.StatusNotFound
+	}
+	ip := clusters[0].MasterIP
+	port := clusters[0].AgentPort
+
+	path := agent_consts.POSTURE_IMAGE_INVENTORY
+	if allowed := os.Getenv("ALLOWED_IMAGE_REGISTRIES"); allowed != "" {
+		path += "?allowed_registries=" + url.QueryEscape(allowed)
+	}
+
+	client := httpclient.NewClient(0)
+
+	res, err := client.Get("http://"+ip+":"+fmt.Sprintf("%d", port)+path, map[string]string{})
+	if err != nil {
+		logger.Log(logger.DEBUG, "HTTP Request Error", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[agent_dto.ImageInventory]{
+			Status:  "error",
+			Message: message.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			},
+		}, fiber.StatusBadGateway
+	}
+
+	var res_data agent_dto.ImageInventory
+	if err := json.Unmarshal(res.Body, &res_data); err != nil {
+		logger.Log(logger.DEBUG, "Unmarshal Response", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[agent_dto.ImageInventory]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	images := []agent_dto.ImageInventoryItem{}
+	for _, image := range res_data.Images {
+		if query.Image != "" && !strings.Contains(image.Image, query.Image) && !strings.Contains(image.Repository, query.Image) {
+			continue
+		}
+		if query.Registry != "" && image.Registry != query.Registry {
+			continue
+		}
+		if query.Mutable && !image.MutableTag {
+			continue
+		}
+		if query.Disallowed && image.AllowedRegistry {
+			continue
+		}
+		if query.Namespace != "" {
+			var pods []agent_dto.ImagePod
+			for _, pod := range image.Pods {
+				if pod.Namespace == query.Namespace {
+					pods = append(pods, pod)
+				}
+			}
+			if len(pods) == 0 {
+				continue
+			}
+			var workloads []agent_dto.ImageWorkload
+			for _, workload := range image.Workloads {
+				if workload.Namespace == query.Namespace {
+					workloads = append(workloads, workload)
+				}
+			}
+			image.Pods, image.Workloads = pods, workloads
+		}
+		images = append(images, image)
+	}
+	res_data.Images = images
+
+	return global_dto.Response[agent_dto.ImageInventory]{
+		Status:  "success",
+		Message: "",
+		Data:    &res_data,
+		Meta: &global_dto.Meta{
+			TotalCount: int64(len(images)),
+			Code:       response.IMAGE_INVENTORY,
+		},
+	}, fiber.StatusOK
+}
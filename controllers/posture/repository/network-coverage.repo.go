@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/FearLessSaad/SNFOK/constants/agent_consts"
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/posture/dto"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/httpclient"
+	"github.com/FearLessSaad/SNFOK/tooling/logger"
+	"github.com/gofiber/fiber"
+
+	cluster "github.com/FearLessSaad/SNFOK/controllers/clusters/persistance"
+)
+
+// getNetworkCoverage reads the per pod NetworkPolicy evaluation from the agent
+func getNetworkCoverage() (agent_dto.NetworkCoverage, global_dto.Response[string], int) {
+
+	clusters, _ := cluster.GetAllClusters()
+	if len(clusters) == 0 {
+		return agent_dto.NetworkCoverage{}, global_dto.Response[string]{
+			Status:  "error",
+			Message: message.NO_REGISTERED_CLUSTER_AVAILABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.NO_CLUSTER_AVAILABLE,
+			},
+		}, fiber.StatusNotFound
+	}
+	ip := clusters[0].MasterIP
+	port := clusters[0].AgentPort
+
+	client := httpclient.NewClient(0)
+
+	res, err := client.Get("http://"+ip+":"+fmt.Sprintf("%d", port)+agent_consts.POSTURE_NETWORK_COVERAGE, map[string]string{})
+	if err != nil {
+		logger.Log(logger.DEBUG, "HTTP Request Error", logger.Field{Key: "error", Value: err.Error()})
+		return agent_dto.NetworkCoverage{}, global_dto.Response[string]{
+			Status:  "error",
+			Message: message.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			},
+		}, fiber.StatusBadGateway
+	}
+
+	var res_data agent_dto.NetworkCoverage
+	if err := json.Unmarshal(res.Body, &res_data); err != nil {
+		logger.Log(logger.DEBUG, "Unmarshal Response", logger.Field{Key: "error", Value: err.Error()})
+		return agent_dto.NetworkCoverage{}, global_dto.Response[string]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return res_data, global_dto.Response[string]{}, fiber.StatusOK
+}
+
+// GetNetworkCoverage returns the ingress and egress coverage percentage of every namespace
+func GetNetworkCoverage() (global_dto.Response[[]dto.NamespaceCoverage], int) {
+
+	coverage, failure, status := getNetworkCoverage()
+	if status != fiber.StatusOK {
+		return global_dto.Response[[]dto.NamespaceCoverage]{
+			Status:  failure.Status,
+			Message: failure.Message,
+			Data:    nil,
+			Meta:    failure.Meta,
+		}, status
+	}
+
+	namespaces := []dto.NamespaceCoverage{}
+	index := make(map[string]int)
+	for _, namespace := range coverage.Namespaces {
+		index[namespace.Namespace] = len(namespaces)
+		namespaces = append(namespaces, dto.NamespaceCoverage{
+			Namespace:          namespace.Namespace,
+			DefaultDenyIngress: namespace.DefaultDenyIngress,
+			DefaultDenyEgress:  namespace.DefaultDenyEgress,
+			Policies:           len(namespace.Policies),
+		})
+	}
+
+	for _, pod := range coverage.Pods {
+		i, exists := index[pod.Namespace]
+		if !exists || pod.HostNetwork {
+			continue
+		}
+		namespaces[i].Pods++
+		if pod.IngressIsolated {
+			namespaces[i].IngressCovered++
+		}
+		if pod.EgressIsolated {
+			namespaces[i].EgressCovered++
+		}
+	}
+
+	for i := range namespaces {
+		namespaces[i].IngressCoverage = percentage(namespaces[i].IngressCovered, namespaces[i].Pods)
+		namespaces[i].EgressCoverage = percentage(namespaces[i].EgressCovered, namespaces[i].Pods)
+	}
+
+	return global_dto.Response[[]dto.NamespaceCoverage]{
+		Status:  "success",
+		Message: "",
+		Data:    &namespaces,
+		Meta: &global_dto.Meta{
+			Code: response.NETWORK_COVERAGE,
+		},
+	}, fiber.StatusOK
+}
+
+// GetUncoveredWorkloads lists the workloads with pods whose ingress or egress is not isolated,
+// optionally limited to one namespace
+func GetUncoveredWorkloads(namespace string) (global_dto.Response[[]dto.UncoveredWorkload], int) {
+
+	coverage, failure, status := getNetworkCoverage()
+	if status != fiber.StatusOK {
+		return global_dto.Response[[]dto.UncoveredWorkload]{
+			Status:  failure.Status,
+			Message: failure.Message,
+			Data:    nil,
+			Meta:    failure.Meta,
+		}, status
+	}
+
+	workloads := []dto.UncoveredWorkload{}
+	index := make(map[string]int)
+	for _, pod := range coverage.Pods {
+		if pod.HostNetwork || (pod.IngressIsolated && pod.EgressIsolated) {
+			continue
+		}
+		if namespace != "" && pod.Namespace != namespace {
+			continue
+		}
+
+		key := pod.Namespace + "/" + pod.WorkloadKind + "/" + pod.WorkloadName
+		i, exists := index[key]
+		if !exists {
+			i = len(workloads)
+			index[key] = i
+			workloads = append(workloads, dto.UncoveredWorkload{
+				Namespace:       pod.Namespace,
+				Kind:            pod.WorkloadKind,
+				Name:            pod.WorkloadName,
+				Pods:            []string{},
+				IngressIsolated: true,
+				EgressIsolated:  true,
+			})
+		}
+		// A workload is only isolated in a direction if all of its pods are
+		workloads[i].Pods = append(workloads[i].Pods, pod.Pod)
+		workloads[i].IngressIsolated = workloads[i].IngressIsolated && pod.IngressIsolated
+		workloads[i].EgressIsolated = workloads[i].EgressIsolated && pod.EgressIsolated
+	}
+
+	return global_dto.Response[[]dto.UncoveredWorkload]{
+		Status:  "success",
+		Message: "",
+		Data:    &workloads,
+		Meta: &global_dto.Meta{
+			TotalCount: int64(len(workloads)),
+			Code:       response.NETWORK_COVERAGE,
+		},
+	}, fiber.StatusOK
+}
+
+// percentage returns part of total as a percentage rounded to two decimals, 0 when total is 0
+func percentage(part int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(total)*10000) / 100
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/FearLessSaad/SNFOK/constants/agent_consts"
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/posture/dto"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/httpclient"
+	"github.com/FearLessSaad/SNFOK/tooling/logger"
+	"github.com/gofiber/fiber"
+
+	cluster "github.com/FearLessSaad/SNFOK/controllers/clusters/persistance"
+)
+
+// GetRBACAnalysis returns the risky roles, grants and ServiceAccount privileges reported by the agent
+func GetRBACAnalysis() (global_dto.Response[agent_dto.RBACAnalysis], int) {
+
+	clusters, _ := cluster.GetAllClusters()
+	if len(clusters) == 0 {
+		return global_dto.Response[agent_dto.RBACAnalysis]{
+			Status:  "error",
+			Message: message.NO_REGISTERED_CLUSTER_AVAILABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.NO_CLUSTER_AVAILABLE,
+			},
+		}, fiber.StatusNotFound
+	}
+	ip := clusters[0].MasterIP
+	port := clusters[0].AgentPort
+
+	client := httpclient.NewClient(0)
+
+	res, err := client.Get("http://"+ip+":"+fmt.Sprintf("%d", port)+agent_consts.POSTURE_RBAC_ANALYSIS, map[string]string{})
+	if err != nil {
+		logger.Log(logger.DEBUG, "HTTP Request Error", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[agent_dto.RBACAnalysis]{
+			Status:  "error",
+			Message: message.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			},
+		}, fiber.StatusBadGateway
+	}
+
+	var res_data agent_dto.RBACAnalysis
+	if err := json.Unmarshal(res.Body, &res_data); err != nil {
+		logger.Log(logger.DEBUG, "Unmarshal Response", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[agent_dto.RBACAnalysis]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[agent_dto.RBACAnalysis]{
+		Status:  "success",
+		Message: "",
+		Data:    &res_data,
+		Meta: &global_dto.Meta{
+			Code: response.RBAC_ANALYSIS,
+		},
+	}, fiber.StatusOK
+}
+
+// WhoCan returns the grants that allow the verb on the resource
+func WhoCan(query dto.WhoCanQuery) (global_dto.Response[[]agent_dto.RBACGrant], int) {
+
+	clusters, _ := cluster.GetAllClusters()
+	if len(clusters) == 0 {
+		return global_dto.Response[[]agent_dto.RBACGrant]{
+			Status:  "error",
+			Message: message.NO_REGISTERED_CLUSTER_AVAILABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.NO_CLUSTER_AVAILABLE,
+			},
+		}, fiber.StatusNotFound
+	}
+	ip := clusters[0].MasterIP
+	port := clusters[0].AgentPort
+
+	params := url.Values{}
+	params.Set("verb", query.Verb)
+	params.Set("resource", query.Resource)
+	params.Set("group", query.Group)
+	params.Set("namespace", query.Namespace)
+
+	client := httpclient.NewClient(0)
+
+	res, err := client.Get("http://"+ip+":"+fmt.Sprintf("%d", port)+agent_consts.POSTURE_RBAC_WHO_CAN+"?"+params.Encode(), map[string]string{})
+	if err != nil {
+		logger.Log(logger.DEBUG, "HTTP Request Error", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[[]agent_dto.RBACGrant]{
+			Status:  "error",
+			Message: message.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			},
+		}, fiber.StatusBadGateway
+	}
+
+	var res_data []agent_dto.RBACGrant
+	if err := json.Unmarshal(res.Body, &res_data); err != nil {
+		logger.Log(logger.DEBUG, "Unmarshal Response", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[[]agent_dto.RBACGrant]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[[]agent_dto.RBACGrant]{
+		Status:  "success",
+		Message: "",
+		Data:    &res_data,
+		Meta: &global_dto.Meta{
+			TotalCount: int64(len(res_data)),
+			Code:       response.RBAC_ANALYSIS,
+		},
+	}, fiber.StatusOK
+}
+
+// GetOverPrivilegedPods lists the running pods whose ServiceAccount has at least one risky grant
+func GetOverPrivilegedPods() (global_dto.Response[[]dto.OverPrivilegedPod], int) {
+
+	analysis, status := GetRBACAnalysis()
+	if analysis.Data == nil {
+		return global_dto.Response[[]dto.OverPrivilegedPod]{
+			Status:  analysis.Status,
+			Message: analysis.Message,
+			Errors:  analysis.Errors,
+			Data:    nil,
+			Meta:    analysis.Meta,
+		}, status
+	}
+
+	pods := []dto.OverPrivilegedPod{}
+	for _, account := range analysis.Data.ServiceAccounts {
+		if len(account.Risks) == 0 {
+			continue
+		}
+		for _, pod := range account.Pods {
+			pods = append(pods, dto.OverPrivilegedPod{
+				Namespace:      account.Namespace,
+				Pod:            pod,
+				ServiceAccount: account.Name,
+				Severity:       account.Severity,
+				Risks:          account.Risks,
+			})
+		}
+	}
+
+	return global_dto.Response[[]dto.OverPrivilegedPod]{
+		Status:  "success",
+		Message: "",
+		Data:    &pods,
+		Meta: &global_dto.Meta{
+			TotalCount: int64(len(pods)),
+			Code:       response.RBAC_ANALYSIS,
+		},
+	}, fiber.StatusOK
+}
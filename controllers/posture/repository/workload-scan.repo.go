@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/FearLessSaad/SNFOK/constants/agent_consts"
+	"github.com/FearLessSaad/SNFOK/constants/message"
+	"github.com/FearLessSaad/SNFOK/constants/response"
+	"github.com/FearLessSaad/SNFOK/controllers/posture/dto"
+	"github.com/FearLessSaad/SNFOK/controllers/posture/persistance"
+	"github.com/FearLessSaad/SNFOK/db/models/k8s"
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/global_dto"
+	"github.com/FearLessSaad/SNFOK/tooling/httpclient"
+	"github.com/FearLessSaad/SNFOK/tooling/logger"
+	"github.com/gofiber/fiber"
+
+	cluster "github.com/FearLessSaad/SNFOK/controllers/clusters/persistance"
+)
+
+// RunWorkloadScan asks the agent to scan every workload and stores the findings for the cluster
+func RunWorkloadScan(uid string) (global_dto.Response[k8s.PostureScans], int) {
+
+	clusters, _ := cluster.GetAllClusters()
+	if len(clusters) == 0 {
+		return global_dto.Response[k8s.PostureScans]{
+			Status:  "error",
+			Message: message.NO_REGISTERED_CLUSTER_AVAILABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.NO_CLUSTER_AVAILABLE,
+			},
+		}, fiber.StatusNotFound
+	}
+	ip := clusters[0].MasterIP
+	port := clusters[0].AgentPort
+
+	client := httpclient.NewClient(0)
+
+	res, err := client.Get("http://"+ip+":"+fmt.Sprintf("%d", port)+agent_consts.POSTURE_SCAN_WORKLOADS, map[string]string{})
+	if err != nil {
+		logger.Log(logger.DEBUG, "HTTP Request Error", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[k8s.PostureScans]{
+			Status:  "error",
+			Message: message.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.SNFOK_AGENT_IS_NOT_ACCESSABLE,
+			},
+		}, fiber.StatusBadGateway
+	}
+
+	var res_data agent_dto.PostureScan
+	if err := json.Unmarshal(res.Body, &res_data); err != nil {
+		logger.Log(logger.DEBUG, "Unmarshal Response", logger.Field{Key: "error", Value: err.Error()})
+		return global_dto.Response[k8s.PostureScans]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	audit := k8s.AuditFields{
+		CreatedBy: uid,
+		CreatedAt: time.Now(),
+	}
+	scan := k8s.PostureScans{
+		ClusterID:   clusters[0].ID,
+		ScannedAt:   res_data.ScannedAt,
+		Workloads:   res_data.Workloads,
+		Findings:    len(res_data.Findings),
+		AuditFields: audit,
+	}
+
+	findings := []k8s.PostureFindings{}
+	for _, finding := range res_data.Findings {
+		switch finding.Severity {
+		case agent_dto.SEVERITY_CRITICAL:
+			scan.Critical++
+		case agent_dto.SEVERITY_HIGH:
+			scan.High++
+		case agent_dto.SEVERITY_MEDIUM:
+			scan.Medium++
+		case agent_dto.SEVERITY_LOW:
+			scan.Low++
+		}
+		findings = append(findings, k8s.PostureFindings{
+			ClusterID:   clusters[0].ID,
+			CheckID:     finding.Check,
+			Severity:    finding.Severity,
+			Title:       finding.Title,
+			Kind:        finding.Kind,
+			Namespace:   finding.Namespace,
+			Name:        finding.Name,
+			Container:   finding.Container,
+			Message:     finding.Message,
+			Remediation: finding.Remediation,
+			AuditFields: audit,
+		})
+	}
+
+	if err := persistance.CreatePostureScan(&scan, findings); err != nil {
+		return global_dto.Response[k8s.PostureScans]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.CREATION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[k8s.PostureScans]{
+		Status:  "success",
+		Message: message.POSTURE_SCAN_COMPLETED,
+		Data:    &scan,
+		Meta: &global_dto.Meta{
+			Code: response.POSTURE_SCAN_COMPLETED,
+		},
+	}, fiber.StatusOK
+}
+
+// GetPostureScans lists the stored scans of the cluster, newest first
+func GetPostureScans() (global_dto.Response[[]k8s.PostureScans], int) {
+
+	clusters, _ := cluster.GetAllClusters()
+	if len(clusters) == 0 {
+		return global_dto.Response[[]k8s.PostureScans]{
+			Status:  "error",
+			Message: message.NO_REGISTERED_CLUSTER_AVAILABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.NO_CLUSTER_AVAILABLE,
+			},
+		}, fiber.StatusNotFound
+	}
+
+	scans, err := persistance.GetAllPostureScans(clusters[0].ID)
+	if err != nil {
+		return global_dto.Response[[]k8s.PostureScans]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[[]k8s.PostureScans]{
+		Status:  "success",
+		Message: "",
+		Data:    &scans,
+		Meta: &global_dto.Meta{
+			Code: response.POSTURE_SCANS,
+		},
+	}, fiber.StatusOK
+}
+
+// GetPostureFindings returns the findings of a scan, the latest one by default, filtered by namespace and severity
+func GetPostureFindings(query dto.PostureFindingsQuery) (global_dto.Response[dto.PostureFindingsResponse], int) {
+
+	clusters, _ := cluster.GetAllClusters()
+	if len(clusters) == 0 {
+		return global_dto.Response[dto.PostureFindingsResponse]{
+			Status:  "error",
+			Message: message.NO_REGISTERED_CLUSTER_AVAILABLE,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.NO_CLUSTER_AVAILABLE,
+			},
+		}, fiber.StatusNotFound
+	}
+
+	var scan k8s.PostureScans
+	var err error
+	if query.ScanID != "" {
+		scan, err = persistance.GetPostureScanById(query.ScanID)
+	} else {
+		scan, err = persistance.GetLatestPostureScan(clusters[0].ID)
+	}
+	if err != nil {
+		return global_dto.Response[dto.PostureFindingsResponse]{
+			Status:  "error",
+			Message: message.POSTURE_SCAN_NOT_FOUND,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.POSTURE_SCAN_NOT_FOUND,
+			},
+		}, fiber.StatusNotFound
+	}
+
+	findings, err := persistance.GetPostureFindings(scan.ID, query.Namespace, query.Severity)
+	if err != nil {
+		return global_dto.Response[dto.PostureFindingsResponse]{
+			Status:  "error",
+			Message: message.SOMETING_WRONG,
+			Data:    nil,
+			Meta: &global_dto.Meta{
+				Code: response.EXECUTION_ERROR,
+			},
+		}, fiber.StatusInternalServerError
+	}
+
+	return global_dto.Response[dto.PostureFindingsResponse]{
+		Status:  "success",
+		Message: "",
+		Data: &dto.PostureFindingsResponse{
+			Scan:     scan,
+			Findings: findings,
+		},
+		Meta: &global_dto.Meta{
+			TotalCount: int64(len(findings)),
+			Code:       response.POSTURE_FINDINGS,
+		},
+	}, fiber.StatusOK
+}
@@ -20,5 +20,14 @@ func InitializeCluster() {
 	utils.InitializeTable(ctx, conn, k8s.AlertsTableName, (*k8s.Alerts)(nil))
 	utils.InitializeTable(ctx, conn, k8s.ImplimentedPoliciesTableName, (*k8s.ImplimentedPolicies)(nil))
 	utils.InitializeTable(ctx, conn, k8s.AllPoliciesTableName, (*k8s.AllPolicies)(nil))
+	utils.InitializeTable(ctx, conn, k8s.PolicyVersionsTableName, (*k8s.PolicyVersions)(nil))
+	utils.InitializeTable(ctx, conn, k8s.PolicyModeChangesTableName, (*k8s.PolicyModeChanges)(nil))
+	utils.InitializeTable(ctx, conn, k8s.DriftReportsTableName, (*k8s.DriftReports)(nil))
+	utils.InitializeTable(ctx, conn, k8s.PolicyBundlesTableName, (*k8s.PolicyBundles)(nil))
+	utils.InitializeTable(ctx, conn, k8s.ImplementedBundlesTableName, (*k8s.ImplementedBundles)(nil))
+	utils.InitializeTable(ctx, conn, k8s.PodIsolationsTableName, (*k8s.PodIsolations)(nil))
+	utils.InitializeTable(ctx, conn, k8s.ForensicBundlesTableName, (*k8s.ForensicBundles)(nil))
+	utils.InitializeTable(ctx, conn, k8s.PostureScansTableName, (*k8s.PostureScans)(nil))
+	utils.InitializeTable(ctx, conn, k8s.PostureFindingsTableName, (*k8s.PostureFindings)(nil))
 	logger.Log(logger.INFO, "The 'k8s' schema initialized successfully!")
 }
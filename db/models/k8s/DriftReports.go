@@ -0,0 +1,26 @@
+package k8s
+
+import (
+	"time"
+
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"github.com/uptrace/bun"
+)
+
+// DriftReports holds the outcome of the last drift reconciliation of every cluster. Orphaned objects have no
+// implemented policy row to mark, so they are only recorded here.
+type DriftReports struct {
+	bun.BaseModel `bun:"table:k8s.drift_reports,alias:d"`
+
+	ClusterID   string `bun:",pk,type:uuid"`
+	ClusterName string
+	CheckedAt   time.Time
+	InSync      int
+	Missing     int
+	Reapplied   int
+	Adopted     int
+	Orphaned    []agent_dto.ManagedObject `bun:",type:jsonb"` // SNFOK-managed objects no implemented policy accounts for
+	Error       string
+}
+
+const DriftReportsTableName = "k8s.drift_reports"
@@ -0,0 +1,29 @@
+package k8s
+
+import (
+	"time"
+
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"github.com/uptrace/bun"
+)
+
+type ForensicBundles struct {
+	bun.BaseModel `bun:"table:k8s.forensic_bundles,alias:h"`
+
+	ID          string `bun:",pk,type:uuid,default:gen_random_uuid()"`
+	ClusterID   string `bun:",type:uuid"`
+	AlertID     string `bun:",type:uuid"`
+	Namespace   string
+	Pod         string
+	PodUID      string
+	FileName    string
+	Size        int64
+	SHA256      string `bun:"sha256"` // Hash of the whole archive, the manifest holds the hash of every file
+	CollectedAt time.Time
+	Manifest    agent_dto.ForensicManifest `bun:",type:jsonb"`
+	Bundle      []byte                     `bun:",type:bytea" json:"-"`
+
+	AuditFields
+}
+
+const ForensicBundlesTableName = "k8s.forensic_bundles"
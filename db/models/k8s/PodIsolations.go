@@ -0,0 +1,31 @@
+package k8s
+
+import (
+	"github.com/uptrace/bun"
+)
+
+type IsolationStatus string
+
+const (
+	IsolationStatusIsolated IsolationStatus = "ISOLATED"
+	IsolationStatusReleased IsolationStatus = "RELEASED"
+)
+
+type PodIsolations struct {
+	bun.BaseModel `bun:"table:k8s.pod_isolations,alias:h"`
+
+	ID          string `bun:",pk,type:uuid,default:gen_random_uuid()"`
+	ClusterID   string `bun:",type:uuid"`
+	IsolationID string // Value of the quarantine label set by the agent
+	Namespace   string
+	Pod         string
+	PolicyName  string
+	Reason      string
+	Status      IsolationStatus `bun:",type:varchar(20),notnull,default:'ISOLATED'"`
+	ExpiresAt   bun.NullTime    `bun:",nullzero"`
+	ReleasedAt  bun.NullTime    `bun:",nullzero"`
+
+	AuditFields
+}
+
+const PodIsolationsTableName = "k8s.pod_isolations"
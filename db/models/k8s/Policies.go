@@ -1,24 +1,63 @@
 package k8s
 
 import (
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
 	"github.com/uptrace/bun"
 )
 
+// DriftStatus is the reconciled state of an implemented policy. Objects in a cluster that no implemented policy
+// accounts for have no row to mark; they are listed as orphaned in k8s.drift_reports.
+type DriftStatus string
+
+const (
+	DriftStatusUnknown DriftStatus = "UNKNOWN" // Not reconciled yet, or applied before objects were labelled
+	DriftStatusInSync  DriftStatus = "IN_SYNC"
+	DriftStatusMissing DriftStatus = "MISSING_IN_CLUSTER"
+)
+
 type ImplimentedPolicies struct {
 	bun.BaseModel `bun:"table:k8s.implimented_policies,alias:h"`
 
-	ID             string `bun:",pk,type:uuid,default:gen_random_uuid()"`
-	PolicyTitle    string
-	Description    string
-	AppLabel       string
-	Namespace      string
-	PolicyFilePath string
+	ID                 string `bun:",pk,type:uuid,default:gen_random_uuid()"`
+	ClusterID          string `bun:",type:uuid,nullzero"`
+	PolicyID           string `bun:",type:uuid,nullzero"` // Catalog policy it was deployed from
+	BundleID           string `bun:",type:uuid,nullzero"` // Implemented bundle it was deployed with, if any
+	PolicyTitle        string
+	Description        string
+	AppLabel           string
+	Selector           *agent_dto.LabelSelector `bun:",type:jsonb"`                                  // Pods the policy applies to, for workload scope
+	Scope              string                   `bun:",type:varchar(20),notnull,default:'WORKLOAD'"` // One of the agent_dto.POLICY_SCOPE_* values
+	Namespace          string                   // Empty for cluster scope
+	Namespaces         []string                 `bun:",type:jsonb"`            // Cluster scope: namespaces the policy is limited to
+	ExcludedNamespaces []string                 `bun:",type:jsonb"`            // Cluster scope: namespaces the policy skips
+	IncludeHost        bool                     `bun:",notnull,default:false"` // Cluster scope: TracingPolicies may apply to host processes
+	PolicyFilePath     string
+	Params             map[string]interface{} `bun:",type:jsonb"`        // Template parameter values it was deployed with
+	PolicyVersion      int                    `bun:",notnull,default:0"` // Catalog policy version it was rendered from, 0 when not recorded
+	PreviousVersion    int                    `bun:",notnull,default:0"` // Version before the last upgrade, the rollback target
+	Mode               string                 `bun:",type:varchar(20)"`  // One of the agent_dto.POLICY_MODE_* values, empty for the mode of the catalog policy
+	DriftStatus        DriftStatus            `bun:",type:varchar(30),notnull,default:'UNKNOWN'"`
+	DriftCheckedAt     bun.NullTime           `bun:",nullzero"`
 
 	AuditFields
 }
 
 const ImplimentedPoliciesTableName = "k8s.implimented_policies"
 
+// Policy types of the catalog, named after the engine that enforces them
+const (
+	PolicyTypeTetragon  = "TETRAGON"
+	PolicyTypeKubeArmor = "KUBEARMOR"
+	PolicyTypeCilium    = "CILIUM"
+	PolicyTypeNetwork   = "NETWORKPOLICY" // Kubernetes NetworkPolicy, enforced by the CNI
+)
+
+// Sources of a catalog policy
+const (
+	PolicySourceCatalog = "CATALOG" // Imported from a template file in POLICIES_TEMPLATES_DIR
+	PolicySourceCustom  = "CUSTOM"  // Uploaded through the API, the template is stored in Content
+)
+
 type AllPolicies struct {
 	bun.BaseModel `bun:"table:k8s.all_policies,alias:h"`
 
@@ -26,7 +65,19 @@ type AllPolicies struct {
 	PolicyTitle    string
 	Description    string
 	PolicyType     string
-	PolicyFilePath string
+	PolicyFilePath string                  // Empty for custom templates
+	Source         string                  `bun:",type:varchar(20),notnull,default:'CATALOG'"` // One of the PolicySource* values
+	Content        string                  `bun:",type:text"`                                  // Template of a custom policy
+	ContentHash    string                  `bun:",unique,nullzero"`                            // SHA-256 of the template, set by the catalog import
+	Kind           string                  // Kind of the first object in the template
+	Name           string                  // metadata.name of the first object in the template
+	Tags           []string                `bun:",type:jsonb"`
+	Framework      string                  // Compliance framework of the template directory, e.g. MITRE or PCI-DSS
+	Mode           string                  `bun:",type:varchar(20)"` // One of the agent_dto.POLICY_MODE_* values
+	Params         []agent_dto.PolicyParam `bun:",type:jsonb"`       // Parameters declared by the template
+	ImportedAt     bun.NullTime            `bun:",nullzero"`
+	Version        int                     `bun:",notnull,default:0"`  // Latest version in k8s.policy_versions
+	VariantID      string                  `bun:",type:uuid,nullzero"` // Policy that deploys this one in the other mode
 
 	AuditFields
 }
@@ -0,0 +1,64 @@
+package k8s
+
+import (
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"github.com/uptrace/bun"
+)
+
+type BundleStatus string
+
+const (
+	BundleStatusDeployed       BundleStatus = "DEPLOYED"
+	BundleStatusFailed         BundleStatus = "FAILED"          // A member failed to apply and every applied member was rolled back, or a member failed to be recorded and was removed
+	BundleStatusRollbackFailed BundleStatus = "ROLLBACK_FAILED" // A member failed and some applied members could not be removed
+)
+
+// BundleMemberRemoved is the status of a member that was deleted on its own after the bundle was deployed
+const BundleMemberRemoved = "REMOVED"
+
+// PolicyBundles is a named set of catalog policies that is deployed and removed as a unit
+type PolicyBundles struct {
+	bun.BaseModel `bun:"table:k8s.policy_bundles,alias:h"`
+
+	ID          string `bun:",pk,type:uuid,default:gen_random_uuid()"`
+	Name        string `bun:",unique,notnull"`
+	Description string
+	PolicyIDs   []string `bun:",type:jsonb"` // Catalog policies, in the order they are applied
+
+	AuditFields
+}
+
+const PolicyBundlesTableName = "k8s.policy_bundles"
+
+// BundleMember is the outcome of one policy of a deployed bundle
+type BundleMember struct {
+	PolicyID      string `json:"policy_id"`
+	PolicyTitle   string `json:"policy_title"`
+	ImplementedID string `json:"implemented_id"`
+	Status        string `json:"status"` // One of the agent_dto.BUNDLE_MEMBER_* values or BundleMemberRemoved
+	Error         string `json:"error,omitempty"`
+}
+
+// ImplementedBundles records a deployment of a bundle, with the outcome of every member.
+// The members that are applied have an implemented policy of their own linked through BundleID.
+type ImplementedBundles struct {
+	bun.BaseModel `bun:"table:k8s.implemented_bundles,alias:h"`
+
+	ID                 string `bun:",pk,type:uuid,default:gen_random_uuid()"`
+	ClusterID          string `bun:",type:uuid,nullzero"`
+	BundleID           string `bun:",type:uuid,nullzero"` // Catalog bundle, empty once it is deleted
+	BundleName         string
+	Scope              string                   `bun:",type:varchar(20),notnull,default:'WORKLOAD'"`
+	Namespace          string                   // Empty for cluster scope
+	AppLabel           string                   // Workload scope only
+	Selector           *agent_dto.LabelSelector `bun:",type:jsonb"`
+	Namespaces         []string                 `bun:",type:jsonb"`
+	ExcludedNamespaces []string                 `bun:",type:jsonb"`
+	IncludeHost        bool                     `bun:",notnull,default:false"`
+	Status             BundleStatus             `bun:",type:varchar(20),notnull"`
+	Members            []BundleMember           `bun:",type:jsonb"`
+
+	AuditFields
+}
+
+const ImplementedBundlesTableName = "k8s.implemented_bundles"
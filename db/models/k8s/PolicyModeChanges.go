@@ -0,0 +1,20 @@
+package k8s
+
+import "github.com/uptrace/bun"
+
+// PolicyModeChanges records every switch of an implemented policy between audit and enforce mode
+type PolicyModeChanges struct {
+	bun.BaseModel `bun:"table:k8s.policy_mode_changes,alias:m"`
+
+	ID            string `bun:",pk,type:uuid,default:gen_random_uuid()"`
+	ImplementedID string `bun:",type:uuid,notnull"`
+	FromMode      string `bun:",type:varchar(20)"` // One of the agent_dto.POLICY_MODE_* values
+	ToMode        string `bun:",type:varchar(20),notnull"`
+	FromPolicyID  string `bun:",type:uuid,nullzero"` // Catalog policy before the change
+	ToPolicyID    string `bun:",type:uuid,nullzero"` // Catalog policy after the change, its variant when the mode needed one
+	Reason        string
+
+	AuditFields
+}
+
+const PolicyModeChangesTableName = "k8s.policy_mode_changes"
@@ -0,0 +1,23 @@
+package k8s
+
+import (
+	"github.com/FearLessSaad/SNFOK/shared/agent_dto"
+	"github.com/uptrace/bun"
+)
+
+// PolicyVersions keeps every template content a catalog policy had, so implemented policies can be rendered
+// again from the version they were deployed with
+type PolicyVersions struct {
+	bun.BaseModel `bun:"table:k8s.policy_versions,alias:v"`
+
+	ID          string                  `bun:",pk,type:uuid,default:gen_random_uuid()"`
+	PolicyID    string                  `bun:",type:uuid,notnull,unique:policy_version"`
+	Version     int                     `bun:",notnull,unique:policy_version"` // Starts at 1 and counts up per policy
+	ContentHash string                  // SHA-256 of Content
+	Content     string                  `bun:",type:text"`
+	Params      []agent_dto.PolicyParam `bun:",type:jsonb"` // Parameters declared by this version
+
+	AuditFields
+}
+
+const PolicyVersionsTableName = "k8s.policy_versions"
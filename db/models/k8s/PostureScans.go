@@ -0,0 +1,46 @@
+package k8s
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+type PostureScans struct {
+	bun.BaseModel `bun:"table:k8s.posture_scans,alias:h"`
+
+	ID        string `bun:",pk,type:uuid,default:gen_random_uuid()"`
+	ClusterID string `bun:",type:uuid"`
+	ScannedAt time.Time
+	Workloads int
+	Findings  int
+	Critical  int
+	High      int
+	Medium    int
+	Low       int
+
+	AuditFields
+}
+
+const PostureScansTableName = "k8s.posture_scans"
+
+type PostureFindings struct {
+	bun.BaseModel `bun:"table:k8s.posture_findings,alias:h"`
+
+	ID          string `bun:",pk,type:uuid,default:gen_random_uuid()"`
+	ScanID      string `bun:",type:uuid"`
+	ClusterID   string `bun:",type:uuid"`
+	CheckID     string
+	Severity    string `bun:",type:varchar(20)"`
+	Title       string
+	Kind        string
+	Namespace   string
+	Name        string
+	Container   string
+	Message     string
+	Remediation string
+
+	AuditFields
+}
+
+const PostureFindingsTableName = "k8s.posture_findings"
@@ -22,6 +22,11 @@ type Clusters struct {
 	AgentPort   int
 	Description string
 
+	// Drift reconciliation: re-apply implemented policies missing in the cluster,
+	// adopt SNFOK-labelled objects that have no implemented policy record
+	ReapplyMissing bool `bun:",notnull,default:false"`
+	AdoptOrphans   bool `bun:",notnull,default:false"`
+
 	AuditFields
 }
 
@@ -4,18 +4,22 @@ import (
 	"github.com/FearLessSaad/SNFOK/tooling/logger"
 	"context"
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/uptrace/bun"
 )
 
-func CheckTableExists(ctx context.Context, db *bun.DB, tableName string) (bool, error) {
-	schema := "public"
-	name := tableName
+// splitTableName splits a table name into its schema, public by default, and name
+func splitTableName(tableName string) (string, string) {
 	if parts := strings.Split(tableName, "."); len(parts) == 2 {
-		schema = parts[0]
-		name = parts[1]
+		return parts[0], parts[1]
 	}
+	return "public", tableName
+}
+
+func CheckTableExists(ctx context.Context, db *bun.DB, tableName string) (bool, error) {
+	schema, name := splitTableName(tableName)
 
 	// Query information_schema.tables
 	count, err := db.NewSelect().
@@ -51,6 +55,10 @@ func InitializeTable(ctx context.Context, conn *bun.DB, tableName string, model
 	}
 	if exists {
 		logger.Log(logger.INFO, "Table '"+tableName+"' already exists.")
+		if err := addMissingColumns(ctx, conn, tableName, model); err != nil {
+			logger.Log(logger.ERROR, "Failed to add new columns to '"+tableName+"' table.", logger.Field{Key: logger.ERROR_MESSAGE, Value: err.Error()})
+			panic(err)
+		}
 		return
 	}
 
@@ -63,3 +71,49 @@ func InitializeTable(ctx context.Context, conn *bun.DB, tableName string, model
 	logger.Log(logger.ERROR, "Failed to create '"+tableName+"' table.", logger.Field{Key: logger.ERROR_MESSAGE, Value: err.Error()})
 	panic(err)
 }
+
+// addMissingColumns adds the columns of the model that an existing table does not have yet,
+// so new model fields are available without recreating the table.
+// NOT NULL is only kept for columns with a default, since existing rows have no value for them.
+// Only a plain unique option becomes a column constraint; unique:<group> spans several columns and is left out.
+func addMissingColumns(ctx context.Context, conn *bun.DB, tableName string, model interface{}) error {
+	schema, name := splitTableName(tableName)
+
+	var columns []string
+	err := conn.NewSelect().
+		Table("information_schema.columns").
+		Column("column_name").
+		Where("table_schema = ?", schema).
+		Where("table_name = ?", name).
+		Scan(ctx, &columns)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for _, column := range columns {
+		existing[column] = true
+	}
+
+	table := conn.Table(reflect.TypeOf(model))
+	for _, field := range table.Fields {
+		if existing[field.Name] {
+			continue
+		}
+
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s", tableName, field.SQLName, field.CreateTableSQLType)
+		if group, found := field.Tag.Option("unique"); found && group == "" {
+			query += " UNIQUE"
+		}
+		if field.SQLDefault != "" {
+			query += " DEFAULT " + field.SQLDefault
+			if field.NotNull {
+				query += " NOT NULL"
+			}
+		}
+
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
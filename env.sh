@@ -7,6 +7,9 @@ export DB_SSL_MODE="disable"
 export SERVER_PORT="8989"
 export LOG_FILE="app.log"
 export COOKIE_DOMAIN="localhost"
+export DRIFT_RECONCILE_INTERVAL="5m"
+export CATALOG_IMPORT_INTERVAL="1h"
+export ALLOWED_IMAGE_REGISTRIES="" # Comma separated, e.g. "ghcr.io/my-org,*.dkr.ecr.us-east-1.amazonaws.com"
 
 export POLICIES_TEMPLATES_DIR="/Users/xaadiii/Desktop/SNFOK/agent/policies"
 export APPLIED_POLICIES_DIR="/Users/xaadiii/Desktop/SNFOK/agent/tmp"
\ No newline at end of file
@@ -18,6 +18,8 @@ import (
 	"github.com/FearLessSaad/SNFOK/controllers/clusters"
 	"github.com/FearLessSaad/SNFOK/controllers/kubernetes"
 	"github.com/FearLessSaad/SNFOK/controllers/policies"
+	policy_repository "github.com/FearLessSaad/SNFOK/controllers/policies/repository"
+	"github.com/FearLessSaad/SNFOK/controllers/posture"
 	"github.com/FearLessSaad/SNFOK/db/initializer"
 	"github.com/FearLessSaad/SNFOK/middlewares"
 	"github.com/FearLessSaad/SNFOK/tooling"
@@ -38,6 +40,12 @@ func main() {
 	// Do All Other Application Related Code Below
 	initializer.InitializeDatabase()
 
+	// Reconcile implemented policies with the policies present in the clusters
+	go policy_repository.RunDriftReconciler(policy_repository.DriftInterval())
+
+	// Keep the policy catalog in sync with the templates directory
+	go policy_repository.RunCatalogImporter(policy_repository.CatalogImportInterval())
+
 	// Encrypt Cookies
 	app.Use(encryptcookie.New(encryptcookie.Config{
 		Key: "eqnVqTihpmg5ico1TCccc2JrvHyWbbpHiuVlOi/5Gp4=",
@@ -74,6 +82,7 @@ func main() {
 	clusters.ClusterController(app.Group(api + "/clusters"))
 	kubernetes.KubernetesController(app.Group(api + "/kubernetes"))
 	policies.PoliciesController(app.Group(api + "/policies"))
+	posture.PostureController(app.Group(api + "/posture"))
 	// -----------------------------------------------
 
 	// Channel to receive OS signals
@@ -11,7 +11,32 @@ type Health_System struct {
 	Message string `json:"message"`
 }
 
+// Health_CRD reports whether the CRD of a policy kind is served by the API server
+type Health_CRD struct {
+	Kind      string `json:"kind"`
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Installed bool   `json:"installed"`
+}
+
+// Health_Engine reports the DaemonSet of a runtime security engine.
+// Installed is false when no DaemonSet of the engine exists in the cluster.
+type Health_Engine struct {
+	Engine    string `json:"engine"`
+	Installed bool   `json:"installed"`
+	Namespace string `json:"namespace,omitempty"`
+	DaemonSet string `json:"daemonset,omitempty"`
+	Desired   int32  `json:"desired"`
+	Ready     int32  `json:"ready"`
+	Available int32  `json:"available"`
+	Healthy   bool   `json:"healthy"`
+	Image     string `json:"image,omitempty"`
+	Version   string `json:"version,omitempty"`
+}
+
 type HealthResponse struct {
-	K8sInfo    Health_K8s    `json:"k8s"`
-	SystemInfo Health_System `json:"system"`
+	K8sInfo    Health_K8s      `json:"k8s"`
+	SystemInfo Health_System   `json:"system"`
+	CRDs       []Health_CRD    `json:"crds"`
+	Engines    []Health_Engine `json:"engines"`
 }
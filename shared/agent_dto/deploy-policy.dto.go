@@ -1,7 +1,76 @@
 package agent_dto
 
+import "time"
+
+// Scopes a policy can be deployed with
+const (
+	POLICY_SCOPE_WORKLOAD  = "WORKLOAD"  // Pods matching the app label or selector in one namespace
+	POLICY_SCOPE_NAMESPACE = "NAMESPACE" // Every pod in one namespace
+	POLICY_SCOPE_CLUSTER   = "CLUSTER"   // Every namespace, optionally limited by Namespaces and ExcludedNamespaces
+)
+
+// Modes of a policy: AUDIT only reports, ENFORCE blocks or kills
+const (
+	POLICY_MODE_AUDIT   = "AUDIT"
+	POLICY_MODE_ENFORCE = "ENFORCE"
+)
+
 type DeployPolicy struct {
-	AppLabel  string `json:"app_label"`
-	Namespace string `json:"namespace"`
-	FilePath  string `json:"file_path"`
+	AppLabel      string                 `json:"app_label"`
+	Namespace     string                 `json:"namespace"`
+	FilePath      string                 `json:"file_path"`
+	Template      string                 `json:"template,omitempty"` // Content of a template stored by the server; FilePath is not read when set
+	PolicyID      string                 `json:"policy_id"`
+	ImplementedID string                 `json:"implemented_id"`
+	Params        map[string]interface{} `json:"params,omitempty"`   // Values of the parameters declared by the template
+	Selector      *LabelSelector         `json:"selector,omitempty"` // Replaces the pod selector of the template; defaults to app=<AppLabel>
+	Mode          string                 `json:"mode,omitempty"`     // One of the POLICY_MODE_* values; empty deploys the actions of the template as they are
+
+	Scope              string   `json:"scope,omitempty"`               // One of the POLICY_SCOPE_* values; empty means POLICY_SCOPE_WORKLOAD
+	Namespaces         []string `json:"namespaces,omitempty"`          // Cluster scope only: namespaces the policy is limited to
+	ExcludedNamespaces []string `json:"excluded_namespaces,omitempty"` // Cluster scope only: namespaces the policy skips
+	IncludeHost        bool     `json:"include_host,omitempty"`        // Cluster scope only: required for kinds that also apply to host processes, see manifests.SetClusterScope
+}
+
+// LabelSelector selects the pods a policy applies to, in the shape of a Kubernetes label selector
+type LabelSelector struct {
+	MatchLabels      map[string]string          `json:"matchLabels,omitempty"`
+	MatchExpressions []LabelSelectorRequirement `json:"matchExpressions,omitempty"`
+}
+
+// LabelSelectorRequirement is a selector expression, e.g. tier In (frontend, backend) or team NotIn (qa)
+type LabelSelectorRequirement struct {
+	Key      string   `json:"key"`
+	Operator string   `json:"operator"` // In, NotIn, Exists or DoesNotExist
+	Values   []string `json:"values,omitempty"`
+}
+
+// AppliedObject identifies a Kubernetes object created or updated by the agent
+type AppliedObject struct {
+	Kind            string `json:"kind"`
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace,omitempty"`
+	UID             string `json:"uid"`
+	ResourceVersion string `json:"resource_version"`
+}
+
+// DeployPolicyResponse is returned by the agent after a policy is applied
+type DeployPolicyResponse struct {
+	PolicyPath string          `json:"policy_path"`
+	Objects    []AppliedObject `json:"objects,omitempty"`
+}
+
+// ManagedObject is a policy object in the cluster that is managed by SNFOK.
+// PolicyID and ImplementedID are empty for objects that were labelled without them.
+type ManagedObject struct {
+	Kind          string            `json:"kind"`
+	APIVersion    string            `json:"api_version"`
+	Name          string            `json:"name"`
+	Namespace     string            `json:"namespace,omitempty"`
+	UID           string            `json:"uid"`
+	PolicyID      string            `json:"policy_id,omitempty"`
+	ImplementedID string            `json:"implemented_id,omitempty"`
+	Status        string            `json:"status"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	Created       time.Time         `json:"created"`
 }
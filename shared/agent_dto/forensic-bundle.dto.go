@@ -0,0 +1,26 @@
+package agent_dto
+
+import "time"
+
+// FORENSIC_MANIFEST_FILE is the name of the manifest inside every forensic bundle
+const FORENSIC_MANIFEST_FILE = "manifest.json"
+
+// ForensicFile is a single file of a forensic bundle with its SHA-256 hash
+type ForensicFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ForensicManifest describes the contents of a forensic bundle.
+// Items that could not be collected are listed in Errors instead of failing the whole bundle.
+type ForensicManifest struct {
+	Cluster     string         `json:"cluster"`
+	Namespace   string         `json:"namespace"`
+	Pod         string         `json:"pod"`
+	PodUID      string         `json:"pod_uid"`
+	NodeName    string         `json:"node_name"`
+	CollectedAt time.Time      `json:"collected_at"`
+	Files       []ForensicFile `json:"files"`
+	Errors      []string       `json:"errors,omitempty"`
+}
@@ -0,0 +1,41 @@
+package agent_dto
+
+import "time"
+
+// ImageWorkload is a workload running an image
+type ImageWorkload struct {
+	Namespace string `json:"namespace"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+}
+
+// ImagePod is a pod container running an image
+type ImagePod struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	Node      string `json:"node"`
+	Digest    string `json:"digest,omitempty"`
+}
+
+// ImageInventoryItem is a unique image reference found in the cluster
+type ImageInventoryItem struct {
+	Image           string          `json:"image"` // Reference as written in the pod spec
+	Registry        string          `json:"registry"`
+	Repository      string          `json:"repository"`
+	Tag             string          `json:"tag,omitempty"`
+	Digests         []string        `json:"digests"` // Resolved digests; more than one means the tag moved between pulls
+	PullPolicies    []string        `json:"pull_policies"`
+	MutableTag      bool            `json:"mutable_tag"`
+	AllowedRegistry bool            `json:"allowed_registry"`
+	Workloads       []ImageWorkload `json:"workloads"`
+	Pods            []ImagePod      `json:"pods"`
+}
+
+// ImageInventory lists every image running in the cluster
+type ImageInventory struct {
+	Cluster           string               `json:"cluster"`
+	CollectedAt       time.Time            `json:"collected_at"`
+	AllowedRegistries []string             `json:"allowed_registries"` // Empty when every registry is allowed
+	Images            []ImageInventoryItem `json:"images"`
+}
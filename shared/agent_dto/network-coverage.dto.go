@@ -0,0 +1,31 @@
+package agent_dto
+
+import "time"
+
+// PodNetworkCoverage reports how NetworkPolicies isolate a single pod
+type PodNetworkCoverage struct {
+	Namespace       string   `json:"namespace"`
+	Pod             string   `json:"pod"`
+	WorkloadKind    string   `json:"workload_kind"`
+	WorkloadName    string   `json:"workload_name"`
+	HostNetwork     bool     `json:"host_network"` // NetworkPolicies do not apply to host network pods
+	IngressIsolated bool     `json:"ingress_isolated"`
+	EgressIsolated  bool     `json:"egress_isolated"`
+	Policies        []string `json:"policies"` // Names of the policies selecting the pod
+}
+
+// NamespaceNetworkCoverage summarises the NetworkPolicies of a namespace
+type NamespaceNetworkCoverage struct {
+	Namespace          string   `json:"namespace"`
+	Policies           []string `json:"policies"`
+	DefaultDenyIngress bool     `json:"default_deny_ingress"`
+	DefaultDenyEgress  bool     `json:"default_deny_egress"`
+}
+
+// NetworkCoverage is the result of evaluating every NetworkPolicy against the live pods
+type NetworkCoverage struct {
+	Cluster     string                     `json:"cluster"`
+	EvaluatedAt time.Time                  `json:"evaluated_at"`
+	Namespaces  []NamespaceNetworkCoverage `json:"namespaces"`
+	Pods        []PodNetworkCoverage       `json:"pods"`
+}
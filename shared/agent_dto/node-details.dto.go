@@ -0,0 +1,64 @@
+package agent_dto
+
+import "time"
+
+// Runtime security engines whose agents are reported per node
+const (
+	SECURITY_ENGINE_TETRAGON  = "tetragon"
+	SECURITY_ENGINE_KUBEARMOR = "kubearmor"
+)
+
+// NodeDetails holds the inventory of a node, control-plane nodes included
+type NodeDetails struct {
+	Name             string                 `json:"name"`
+	Hostname         string                 `json:"hostname"`
+	InternalIP       string                 `json:"internal_ip"`
+	ExternalIP       string                 `json:"external_ip"`
+	Roles            []string               `json:"roles"`
+	ControlPlane     bool                   `json:"control_plane"`
+	Unschedulable    bool                   `json:"unschedulable"`
+	Ready            bool                   `json:"ready"`
+	Created          time.Time              `json:"created"`
+	Capacity         map[string]string      `json:"capacity"`
+	Allocatable      map[string]string      `json:"allocatable"`
+	KernelVersion    string                 `json:"kernel_version"`
+	OSImage          string                 `json:"os_image"`
+	OperatingSystem  string                 `json:"operating_system"`
+	Architecture     string                 `json:"architecture"`
+	ContainerRuntime string                 `json:"container_runtime"`
+	KubeletVersion   string                 `json:"kubelet_version"`
+	Conditions       []NodeCondition        `json:"conditions"`
+	Taints           []NodeTaint            `json:"taints"`
+	Labels           map[string]string      `json:"labels"`
+	SecurityEngines  []SecurityEngineStatus `json:"security_engines"`
+}
+
+// NodeCondition is a condition reported by the kubelet, e.g. Ready or MemoryPressure
+type NodeCondition struct {
+	Type               string    `json:"type"`
+	Status             string    `json:"status"`
+	Reason             string    `json:"reason"`
+	Message            string    `json:"message"`
+	LastTransitionTime time.Time `json:"last_transition_time"`
+}
+
+// NodeTaint holds a taint of a node
+type NodeTaint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Effect string `json:"effect"`
+}
+
+// SecurityEngineStatus reports the agent pod of a runtime security engine on a node.
+// Installed is false when no pod of the engine is scheduled on the node.
+type SecurityEngineStatus struct {
+	Engine    string `json:"engine"`
+	Installed bool   `json:"installed"`
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	Phase     string `json:"phase,omitempty"`
+	Running   bool   `json:"running"`
+	Ready     bool   `json:"ready"`
+	Image     string `json:"image,omitempty"`
+	Version   string `json:"version,omitempty"`
+}
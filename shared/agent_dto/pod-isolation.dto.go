@@ -0,0 +1,26 @@
+package agent_dto
+
+import "time"
+
+// IsolatePod is the request to cut a single pod off the network
+type IsolatePod struct {
+	Namespace  string `json:"namespace"`
+	Pod        string `json:"pod"`
+	TTLSeconds int64  `json:"ttl_seconds,omitempty"` // Release automatically after this many seconds, 0 keeps it isolated
+}
+
+// ReleasePod is the request to lift an isolation
+type ReleasePod struct {
+	Namespace string `json:"namespace"`
+	ID        string `json:"id"`
+}
+
+// IsolatedPod describes a pod isolated by the agent
+type IsolatedPod struct {
+	ID         string     `json:"id"`
+	Namespace  string     `json:"namespace"`
+	Pod        string     `json:"pod"`
+	PolicyName string     `json:"policy_name"`
+	IsolatedAt time.Time  `json:"isolated_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
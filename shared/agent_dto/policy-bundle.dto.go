@@ -0,0 +1,30 @@
+package agent_dto
+
+// Status of a member of a bundle deployment
+const (
+	BUNDLE_MEMBER_APPLIED     = "APPLIED"
+	BUNDLE_MEMBER_FAILED      = "FAILED"
+	BUNDLE_MEMBER_ROLLED_BACK = "ROLLED_BACK" // Applied, then removed because another member failed
+	BUNDLE_MEMBER_SKIPPED     = "SKIPPED"     // Not applied because another member failed first
+)
+
+// DeployBundle deploys several policies as one unit: either every member is applied or none is
+type DeployBundle struct {
+	Members []DeployPolicy `json:"members"`
+}
+
+// BundleMemberResult is the outcome of one member of a bundle deployment
+type BundleMemberResult struct {
+	PolicyID      string          `json:"policy_id"`
+	ImplementedID string          `json:"implemented_id"`
+	Status        string          `json:"status"`
+	Error         string          `json:"error,omitempty"`
+	PolicyPath    string          `json:"policy_path,omitempty"`
+	Objects       []AppliedObject `json:"objects,omitempty"`
+}
+
+// DeployBundleResponse reports whether the bundle was applied, with the outcome of every member in order
+type DeployBundleResponse struct {
+	Deployed bool                 `json:"deployed"`
+	Members  []BundleMemberResult `json:"members"`
+}
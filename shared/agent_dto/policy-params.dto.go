@@ -0,0 +1,26 @@
+package agent_dto
+
+// Types of the parameters a policy template can declare
+const (
+	PARAM_STRING        = "string"
+	PARAM_INT           = "int"
+	PARAM_BOOL          = "bool"
+	PARAM_PORT          = "port"
+	PARAM_PORT_LIST     = "port_list"
+	PARAM_CIDR          = "cidr"
+	PARAM_CIDR_LIST     = "cidr_list"
+	PARAM_PATH          = "path"
+	PARAM_PATH_LIST     = "path_list"
+	PARAM_BINARY        = "binary"
+	PARAM_BINARY_LIST   = "binary_list"
+	PARAM_SIGNAL_ACTION = "signal_action"
+)
+
+// PolicyParam is a parameter declared in the header of a policy template.
+// A parameter without a default must be given a value when the policy is deployed.
+type PolicyParam struct {
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	Description string      `json:"description,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+}
@@ -0,0 +1,27 @@
+package agent_dto
+
+// PreviewPod is a running pod the selector of a previewed policy object matches
+type PreviewPod struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	NodeName  string `json:"node_name"`
+}
+
+// PreviewObject is the dry-run result of one object of a previewed policy
+type PreviewObject struct {
+	Kind        string       `json:"kind"`
+	Name        string       `json:"name"`
+	Namespace   string       `json:"namespace,omitempty"`
+	Valid       bool         `json:"valid"`
+	Error       string       `json:"error,omitempty"` // Schema or admission error reported by the API server
+	MatchedPods []PreviewPod `json:"matched_pods"`
+}
+
+// PolicyPreview is the rendered policy and the outcome of applying it in dry-run mode.
+// Error is set when the policy cannot be rendered at all, e.g. because of invalid parameters or an invalid selector.
+type PolicyPreview struct {
+	RenderedYAML string          `json:"rendered_yaml"`
+	Valid        bool            `json:"valid"`
+	Error        string          `json:"error,omitempty"`
+	Objects      []PreviewObject `json:"objects"`
+}
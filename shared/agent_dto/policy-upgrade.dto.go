@@ -0,0 +1,8 @@
+package agent_dto
+
+// UpgradePolicy replaces the objects of an applied policy with the objects rendered from another version of its
+// template. The agent answers with a DeployPolicyResponse for the new rendered file.
+type UpgradePolicy struct {
+	DeployPolicy
+	PreviousPath string `json:"previous_path"` // Rendered file of the version that is replaced
+}
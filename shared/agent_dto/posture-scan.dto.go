@@ -0,0 +1,32 @@
+package agent_dto
+
+import "time"
+
+// Finding severities, from most to least severe
+const (
+	SEVERITY_CRITICAL = "CRITICAL"
+	SEVERITY_HIGH     = "HIGH"
+	SEVERITY_MEDIUM   = "MEDIUM"
+	SEVERITY_LOW      = "LOW"
+)
+
+// PostureFinding is a single misconfiguration found in a workload's pod template
+type PostureFinding struct {
+	Check       string `json:"check"` // Stable identifier of the check, e.g. PRIVILEGED_CONTAINER
+	Severity    string `json:"severity"`
+	Title       string `json:"title"`
+	Kind        string `json:"kind"`
+	Namespace   string `json:"namespace"`
+	Name        string `json:"name"`
+	Container   string `json:"container,omitempty"` // Empty for pod level findings
+	Message     string `json:"message"`
+	Remediation string `json:"remediation"`
+}
+
+// PostureScan is the result of scanning every workload in the cluster
+type PostureScan struct {
+	Cluster   string           `json:"cluster"`
+	ScannedAt time.Time        `json:"scanned_at"`
+	Workloads int              `json:"workloads"`
+	Findings  []PostureFinding `json:"findings"`
+}
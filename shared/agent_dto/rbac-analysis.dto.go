@@ -0,0 +1,57 @@
+package agent_dto
+
+import "time"
+
+// RBACSubject is a user, group or ServiceAccount a role is bound to
+type RBACSubject struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// RBACRisk is a risky permission granted by a rule of a role
+type RBACRisk struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+}
+
+// RBACRole is a Role or ClusterRole with the risks found in its rules
+type RBACRole struct {
+	Kind      string     `json:"kind"`
+	Namespace string     `json:"namespace,omitempty"`
+	Name      string     `json:"name"`
+	Builtin   bool       `json:"builtin"` // Default role maintained by Kubernetes itself
+	Risks     []RBACRisk `json:"risks"`
+}
+
+// RBACGrant is a role granted to a subject through a binding.
+// Namespace is empty when the grant applies to the whole cluster.
+type RBACGrant struct {
+	Subject     RBACSubject `json:"subject"`
+	BindingKind string      `json:"binding_kind"`
+	BindingName string      `json:"binding_name"`
+	RoleKind    string      `json:"role_kind"`
+	RoleName    string      `json:"role_name"`
+	Namespace   string      `json:"namespace,omitempty"`
+}
+
+// ServiceAccountPrivileges maps a ServiceAccount used by running pods to the roles granted to it
+type ServiceAccountPrivileges struct {
+	Namespace string      `json:"namespace"`
+	Name      string      `json:"name"`
+	Pods      []string    `json:"pods"`
+	Severity  string      `json:"severity,omitempty"` // Highest severity of its risks, empty when none
+	Grants    []RBACGrant `json:"grants"`
+	Risks     []RBACRisk  `json:"risks"`
+}
+
+// RBACAnalysis is the result of analysing every role and binding in the cluster
+type RBACAnalysis struct {
+	Cluster         string                     `json:"cluster"`
+	AnalyzedAt      time.Time                  `json:"analyzed_at"`
+	Roles           []RBACRole                 `json:"roles"`
+	Grants          []RBACGrant                `json:"grants"`
+	ServiceAccounts []ServiceAccountPrivileges `json:"service_accounts"`
+}
@@ -0,0 +1,24 @@
+package agent_dto
+
+// Resource event types streamed by the agent's watch endpoint
+const (
+	RESOURCE_EVENT_ADDED    = "ADDED"
+	RESOURCE_EVENT_MODIFIED = "MODIFIED"
+	RESOURCE_EVENT_DELETED  = "DELETED"
+	RESOURCE_EVENT_EXPIRED  = "EXPIRED" // The requested resource version is too old, re-read the snapshot
+	RESOURCE_EVENT_ERROR    = "ERROR"
+)
+
+// ResourceEvent is a single add/update/delete of a watched resource
+type ResourceEvent struct {
+	Type            string           `json:"type"`
+	Kind            string           `json:"kind"`
+	Namespace       string           `json:"namespace,omitempty"`
+	Name            string           `json:"name,omitempty"`
+	ResourceVersion string           `json:"resource_version,omitempty"`
+	Pod             *PodInfo         `json:"pod,omitempty"`
+	Deployment      *DeploymentInfo  `json:"deployment,omitempty"`
+	StatefulSet     *StatefulSetInfo `json:"stateful_set,omitempty"`
+	DaemonSet       *DaemonSetInfo   `json:"daemon_set,omitempty"`
+	Message         string           `json:"message,omitempty"`
+}
@@ -2,6 +2,7 @@ package httpclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -143,3 +144,31 @@ func (c *Client) Delete(url string, headers map[string]string) (*Response, error
 		Headers:    resp.Header,
 	}, nil
 }
+
+// Stream sends a GET request and returns the response with its body left open for incremental reads.
+// The client timeout is not applied to streams; cancel ctx or close the body to end the stream.
+func (c *Client) Stream(ctx context.Context, url string, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GET request: %w", err)
+	}
+
+	// Add custom headers
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	streamClient := &http.Client{Transport: c.httpClient.Transport}
+	resp, err := streamClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send GET request: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("GET request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return resp, nil
+}